@@ -2,20 +2,27 @@ package chain
 
 import (
 	"context"
-	"fil-assistant/lib"
+	"encoding/json"
 	"github.com/ethereum/go-ethereum/rpc"
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/lotus/chain/types"
 	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
 	"golang.org/x/xerrors"
+	"strings"
 )
 
 type msgSendSpec struct {
 	MaxFee 	abi.TokenAmount
 }
 
+// MinRBF is the lowest premium the mpool accepts to replace a message with premium, i.e. 25% more.
+func MinRBF(premium abi.TokenAmount) abi.TokenAmount {
+	minPremium := types.BigDiv(types.BigMul(premium, types.NewInt(125)), types.NewInt(100))
+	return types.BigAdd(minPremium, types.NewInt(1))
+}
+
 type MsigTransaction struct {
 	ID     int64
 	To     address.Address
@@ -34,6 +41,51 @@ type MsgLookup struct {
 	Height    abi.ChainEpoch
 }
 
+// ActorState is the state of an actor as returned by StateReadState, State is decoded by the caller into the state
+// type of the actor code.
+type ActorState struct {
+	Balance types.BigInt
+	Code    cid.Cid
+	State   json.RawMessage
+}
+
+type MsigVesting struct {
+	InitialBalance abi.TokenAmount
+	StartEpoch     abi.ChainEpoch
+	UnlockDuration abi.ChainEpoch
+}
+
+// MinerInfo is the part of StateMinerInfo the assistants use, NewWorker is undefined when no worker change is
+// pending.
+type MinerInfo struct {
+	Owner             address.Address
+	Worker            address.Address
+	NewWorker         address.Address
+	ControlAddresses  []address.Address
+	WorkerChangeEpoch abi.ChainEpoch
+	PeerId            *peer.ID
+	Multiaddrs        [][]byte
+	SectorSize        abi.SectorSize
+}
+
+// MarketBalance is the collateral of a storage provider or client in the storage market actor, Locked is the part
+// of Escrow held by deals.
+type MarketBalance struct {
+	Escrow abi.TokenAmount
+	Locked abi.TokenAmount
+}
+
+type PowerClaim struct {
+	RawBytePower    abi.StoragePower
+	QualityAdjPower abi.StoragePower
+}
+
+type MinerPower struct {
+	MinerPower  PowerClaim
+	TotalPower  PowerClaim
+	HasMinPower bool
+}
+
 type LotusClient struct {
 	client 				*rpc.Client
 }
@@ -69,31 +121,23 @@ func (l *LotusClient) EstimateMessageGas(ctx context.Context, maxFee abi.TokenAm
 	}
 }
 
-func (l *LotusClient) SendMsg(ctx context.Context, pk []byte, msg *types.Message, signer lib.Signer) (cid.Cid, error) {
-	mb, err := msg.ToStorageBlock()
-	if err != nil {
-		return cid.Undef, xerrors.Errorf("SendMsg ToStorageBlock error: %w", err)
-	}
-
-	sig, err := signer.Sign(pk, mb.Cid().Bytes())
+func (l *LotusClient) PushMsg(ctx context.Context, signedMsg *types.SignedMessage) (cid.Cid, error) {
+	var c cid.Cid
+	err := l.client.CallContext(ctx, &c, "Filecoin.MpoolPush", signedMsg)
 	if err != nil {
-		return cid.Undef, xerrors.Errorf("SendMsg Sign error: %w", err)
-	}
-
-	signedMsg := &types.SignedMessage{
-		Message:	*msg,
-		Signature: 	crypto.Signature{
-			Type: signer.Type(),
-			Data: sig,
-		},
+		return cid.Undef, xerrors.Errorf("PushMsg Call error: %w", err)
+	} else {
+		return c, nil
 	}
+}
 
-	var c cid.Cid
-	err = l.client.CallContext(ctx, &c, "Filecoin.MpoolPush", signedMsg)
+func (l *LotusClient) PendingMessages(ctx context.Context) ([]*types.SignedMessage, error) {
+	var msgs []*types.SignedMessage
+	err := l.client.CallContext(ctx, &msgs, "Filecoin.MpoolPending", types.EmptyTSK)
 	if err != nil {
-		return cid.Undef, xerrors.Errorf("SendMsg Call error: %w", err)
+		return nil, xerrors.Errorf("PendingMessages error: %w", err)
 	} else {
-		return c, nil
+		return msgs, nil
 	}
 }
 
@@ -117,11 +161,21 @@ func (l *LotusClient) LookupMessage(ctx context.Context, c cid.Cid) (*types.Mess
 	}
 }
 
-func (l *LotusClient) WaitMessage(ctx context.Context, c cid.Cid, confidence uint64) ([]byte, error) {
+// WaitMsgLookup waits for the message like WaitMessage, but leaves checking the receipt to the caller.
+func (l *LotusClient) WaitMsgLookup(ctx context.Context, c cid.Cid, confidence uint64) (*MsgLookup, error) {
 	wait := new(MsgLookup)
 	err := l.client.CallContext(ctx, wait, "Filecoin.StateWaitMsg", c, confidence)
 	if err != nil {
 		return nil, xerrors.Errorf("WaitMessage for %s error: %w", c.String(), err)
+	} else {
+		return wait, nil
+	}
+}
+
+func (l *LotusClient) WaitMessage(ctx context.Context, c cid.Cid, confidence uint64) ([]byte, error) {
+	wait, err := l.WaitMsgLookup(ctx, c, confidence)
+	if err != nil {
+		return nil, err
 	} else if wait.Receipt.ExitCode != 0 {
 		return nil, xerrors.Errorf("WaitMessage executed, exit code %d", wait.Receipt.ExitCode)
 	} else {
@@ -162,13 +216,108 @@ func (l *LotusClient) GetPendingMsigTrxs(ctx context.Context, msigAddr address.A
 func (l *LotusClient) StateGetActorCode(ctx context.Context, actor address.Address) (cid.Cid, error) {
 	var act types.Actor
 	err := l.client.CallContext(ctx, &act, "Filecoin.StateGetActor", actor, types.EmptyTSK)
-	if err != nil {
+	if err != nil && strings.Contains(err.Error(), ErrActorNotFound.Error()) {
+		// the node only returns the text of its error, which ends with the message of Lotus's ErrActorNotFound
+		return cid.Cid{}, xerrors.Errorf("StateGetActorCode %s: %w", actor, ErrActorNotFound)
+	} else if err != nil {
 		return cid.Cid{}, xerrors.Errorf("StateGetActorCode error: %w", err)
 	} else {
 		return act.Code, nil
 	}
 }
 
+func (l *LotusClient) ReadState(ctx context.Context, actor address.Address) (*ActorState, error) {
+	st := new(ActorState)
+	err := l.client.CallContext(ctx, st, "Filecoin.StateReadState", actor, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("ReadState error: %w", err)
+	} else {
+		return st, nil
+	}
+}
+
+func (l *LotusClient) AccountKey(ctx context.Context, addr address.Address) (address.Address, error) {
+	var key address.Address
+	err := l.client.CallContext(ctx, &key, "Filecoin.StateAccountKey", addr, types.EmptyTSK)
+	if err != nil {
+		return address.Undef, xerrors.Errorf("AccountKey error: %w", err)
+	} else {
+		return key, nil
+	}
+}
+
+func (l *LotusClient) GetMsigAvailableBalance(ctx context.Context, msigAddr address.Address) (types.BigInt, error) {
+	var bal types.BigInt
+	err := l.client.CallContext(ctx, &bal, "Filecoin.MsigGetAvailableBalance", msigAddr, types.EmptyTSK)
+	if err != nil {
+		return types.EmptyInt, xerrors.Errorf("GetMsigAvailableBalance error: %w", err)
+	} else {
+		return bal, nil
+	}
+}
+
+func (l *LotusClient) GetMsigVesting(ctx context.Context, msigAddr address.Address) (*MsigVesting, error) {
+	vesting := new(MsigVesting)
+	err := l.client.CallContext(ctx, vesting, "Filecoin.MsigGetVestingSchedule", msigAddr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("GetMsigVesting error: %w", err)
+	} else {
+		return vesting, nil
+	}
+}
+
+func (l *LotusClient) GetMinerInfo(ctx context.Context, minerID address.Address) (*MinerInfo, error) {
+	info := new(MinerInfo)
+	err := l.client.CallContext(ctx, info, "Filecoin.StateMinerInfo", minerID, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("GetMinerInfo error: %w", err)
+	} else {
+		return info, nil
+	}
+}
+
+func (l *LotusClient) GetMinerPower(ctx context.Context, minerID address.Address) (*MinerPower, error) {
+	power := new(MinerPower)
+	err := l.client.CallContext(ctx, power, "Filecoin.StateMinerPower", minerID, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("GetMinerPower error: %w", err)
+	} else {
+		return power, nil
+	}
+}
+
+func (l *LotusClient) GetMarketBalance(ctx context.Context, addr address.Address) (*MarketBalance, error) {
+	bal := new(MarketBalance)
+	err := l.client.CallContext(ctx, bal, "Filecoin.StateMarketBalance", addr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("GetMarketBalance error: %w", err)
+	} else {
+		return bal, nil
+	}
+}
+
+// GetVerifiedClientStatus returns the DataCap left to a verified client, nil when addr is not one.
+func (l *LotusClient) GetVerifiedClientStatus(ctx context.Context, addr address.Address) (*abi.StoragePower, error) {
+	var dcap *abi.StoragePower
+	err := l.client.CallContext(ctx, &dcap, "Filecoin.StateVerifiedClientStatus", addr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("GetVerifiedClientStatus error: %w", err)
+	} else {
+		return dcap, nil
+	}
+}
+
+// GetVerifierStatus returns the allowance left to a verifier, nil when addr is not one.
+func (l *LotusClient) GetVerifierStatus(ctx context.Context, addr address.Address) (*abi.StoragePower, error) {
+	var allowance *abi.StoragePower
+	err := l.client.CallContext(ctx, &allowance, "Filecoin.StateVerifierStatus", addr, types.EmptyTSK)
+	if err != nil {
+		return nil, xerrors.Errorf("GetVerifierStatus error: %w", err)
+	} else {
+		return allowance, nil
+	}
+}
+
 func (l *LotusClient) Close() {
 	l.client.Close()
 }
\ No newline at end of file
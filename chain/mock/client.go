@@ -0,0 +1,196 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fil-assistant/chain"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// client is a chain.Node calling the node in memory. Params and results still go through JSON, so the values seen
+// by the Handler are the same as over HTTP.
+type client struct {
+	n *Node
+}
+
+// Client returns a chain.Node backed by n without serving it.
+func (n *Node) Client() chain.Node {
+	return &client{n: n}
+}
+
+func (c *client) call(result interface{}, name string, args ...interface{}) error {
+	params := make([]json.RawMessage, 0, len(args))
+	for _, arg := range args {
+		val, err := json.Marshal(arg)
+		if err != nil {
+			return err
+		}
+		params = append(params, val)
+	}
+
+	m, found := methods[name]
+	if !found {
+		return xerrors.Errorf("method %s is not supported by the mock node", name)
+	}
+	res, err := m(c.n, params)
+	if err != nil {
+		return err
+	}
+	val, err := json.Marshal(res)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(val, result)
+}
+
+func (c *client) GetNonce(ctx context.Context, addr address.Address) (uint64, error) {
+	var nonce uint64
+	err := c.call(&nonce, "Filecoin.MpoolGetNonce", addr)
+	return nonce, err
+}
+
+func (c *client) EstimateMessageGas(ctx context.Context, maxFee abi.TokenAmount, msg *types.Message) (*types.Message, error) {
+	newMsg := new(types.Message)
+	err := c.call(newMsg, "Filecoin.GasEstimateMessageGas", msg, map[string]abi.TokenAmount{"MaxFee": maxFee}, types.EmptyTSK)
+	return newMsg, err
+}
+
+func (c *client) PushMsg(ctx context.Context, signedMsg *types.SignedMessage) (cid.Cid, error) {
+	var res cid.Cid
+	err := c.call(&res, "Filecoin.MpoolPush", signedMsg)
+	return res, err
+}
+
+func (c *client) PendingMessages(ctx context.Context) ([]*types.SignedMessage, error) {
+	var msgs []*types.SignedMessage
+	err := c.call(&msgs, "Filecoin.MpoolPending", types.EmptyTSK)
+	return msgs, err
+}
+
+func (c *client) GetBalance(ctx context.Context, addr address.Address) (types.BigInt, error) {
+	var bal types.BigInt
+	err := c.call(&bal, "Filecoin.WalletBalance", addr)
+	return bal, err
+}
+
+func (c *client) LookupMessage(ctx context.Context, mc cid.Cid) (*types.Message, error) {
+	msg := new(types.Message)
+	err := c.call(msg, "Filecoin.ChainGetMessage", mc)
+	return msg, err
+}
+
+func (c *client) WaitMsgLookup(ctx context.Context, mc cid.Cid, confidence uint64) (*chain.MsgLookup, error) {
+	wait := new(chain.MsgLookup)
+	if err := c.call(wait, "Filecoin.StateWaitMsg", mc, confidence); err != nil {
+		return nil, err
+	}
+	return wait, nil
+}
+
+func (c *client) WaitMessage(ctx context.Context, mc cid.Cid, confidence uint64) ([]byte, error) {
+	wait, err := c.WaitMsgLookup(ctx, mc, confidence)
+	if err != nil {
+		return nil, err
+	} else if wait.Receipt.ExitCode != 0 {
+		return nil, xerrors.Errorf("WaitMessage executed, exit code %d", wait.Receipt.ExitCode)
+	}
+	return wait.Receipt.Return, nil
+}
+
+func (c *client) LookupID(ctx context.Context, addr address.Address) (address.Address, error) {
+	var id address.Address
+	err := c.call(&id, "Filecoin.StateLookupID", addr, types.EmptyTSK)
+	return id, err
+}
+
+func (c *client) GetMinerAvailableBalance(ctx context.Context, minerID address.Address) (types.BigInt, error) {
+	var bal types.BigInt
+	err := c.call(&bal, "Filecoin.StateMinerAvailableBalance", minerID, types.EmptyTSK)
+	return bal, err
+}
+
+func (c *client) GetPendingMsigTrxs(ctx context.Context, msigAddr address.Address) ([]chain.MsigTransaction, error) {
+	var trxs []chain.MsigTransaction
+	err := c.call(&trxs, "Filecoin.MsigGetPending", msigAddr, types.EmptyTSK)
+	return trxs, err
+}
+
+func (c *client) StateGetActorCode(ctx context.Context, actor address.Address) (cid.Cid, error) {
+	var act types.Actor
+	err := c.call(&act, "Filecoin.StateGetActor", actor, types.EmptyTSK)
+	return act.Code, err
+}
+
+func (c *client) ReadState(ctx context.Context, actor address.Address) (*chain.ActorState, error) {
+	st := new(chain.ActorState)
+	if err := c.call(st, "Filecoin.StateReadState", actor, types.EmptyTSK); err != nil {
+		return nil, err
+	}
+	return st, nil
+}
+
+func (c *client) AccountKey(ctx context.Context, addr address.Address) (address.Address, error) {
+	var key address.Address
+	err := c.call(&key, "Filecoin.StateAccountKey", addr, types.EmptyTSK)
+	return key, err
+}
+
+func (c *client) GetMsigAvailableBalance(ctx context.Context, msigAddr address.Address) (types.BigInt, error) {
+	var bal types.BigInt
+	err := c.call(&bal, "Filecoin.MsigGetAvailableBalance", msigAddr, types.EmptyTSK)
+	return bal, err
+}
+
+func (c *client) GetMsigVesting(ctx context.Context, msigAddr address.Address) (*chain.MsigVesting, error) {
+	vesting := new(chain.MsigVesting)
+	if err := c.call(vesting, "Filecoin.MsigGetVestingSchedule", msigAddr, types.EmptyTSK); err != nil {
+		return nil, err
+	}
+	return vesting, nil
+}
+
+func (c *client) GetMinerInfo(ctx context.Context, minerID address.Address) (*chain.MinerInfo, error) {
+	info := new(chain.MinerInfo)
+	if err := c.call(info, "Filecoin.StateMinerInfo", minerID, types.EmptyTSK); err != nil {
+		return nil, err
+	}
+	return info, nil
+}
+
+func (c *client) GetMinerPower(ctx context.Context, minerID address.Address) (*chain.MinerPower, error) {
+	power := new(chain.MinerPower)
+	if err := c.call(power, "Filecoin.StateMinerPower", minerID, types.EmptyTSK); err != nil {
+		return nil, err
+	}
+	return power, nil
+}
+
+func (c *client) GetMarketBalance(ctx context.Context, addr address.Address) (*chain.MarketBalance, error) {
+	bal := new(chain.MarketBalance)
+	if err := c.call(bal, "Filecoin.StateMarketBalance", addr, types.EmptyTSK); err != nil {
+		return nil, err
+	}
+	return bal, nil
+}
+
+func (c *client) GetVerifiedClientStatus(ctx context.Context, addr address.Address) (*abi.StoragePower, error) {
+	var dcap *abi.StoragePower
+	if err := c.call(&dcap, "Filecoin.StateVerifiedClientStatus", addr, types.EmptyTSK); err != nil {
+		return nil, err
+	}
+	return dcap, nil
+}
+
+func (c *client) GetVerifierStatus(ctx context.Context, addr address.Address) (*abi.StoragePower, error) {
+	var allowance *abi.StoragePower
+	if err := c.call(&allowance, "Filecoin.StateVerifierStatus", addr, types.EmptyTSK); err != nil {
+		return nil, err
+	}
+	return allowance, nil
+}
+
+func (c *client) Close() {}
@@ -0,0 +1,716 @@
+package mock
+
+import (
+	"bytes"
+	"fil-assistant/chain"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	init_ "github.com/filecoin-project/specs-actors/v6/actors/builtin/init"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/power"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/verifreg"
+	"github.com/ipfs/go-cid"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/minio/blake2b-simd"
+	cbg "github.com/whyrusleeping/cbor-gen"
+)
+
+// Gas values returned by GasEstimateMessageGas.
+const (
+	GasLimit   = 1000000
+	GasPremium = 100000
+	BaseFee    = 100
+)
+
+// defaultThreshold applies to multisigs the state does not describe, so that their proposals stay pending.
+const defaultThreshold = 2
+
+// execute applies msg to the state: value transfers, multisig creation through the init actor, miner creation through
+// the power actor, the propose/approve/cancel flow of multisigs, whose approved transactions are executed in turn, the
+// signers, threshold and vesting of multisigs, the owner, worker, peer info and funds of scripted miners, the storage
+// market collateral and DataCap grants of verifiers. Any other method succeeds without changing the state.
+func (n *Node) execute(msg *types.Message, c cid.Cid) types.MessageReceipt {
+	if n.balance(msg.From).LessThan(msg.Value) {
+		return types.MessageReceipt{ExitCode: exitcode.SysErrInsufficientFunds}
+	}
+	if to := n.resolve(msg.To); to.Protocol() == address.ID && !n.exists(to) {
+		return types.MessageReceipt{ExitCode: exitcode.SysErrInvalidReceiver}
+	}
+	n.transfer(msg.From, msg.To, msg.Value)
+
+	code, _ := n.code(n.resolve(msg.To))
+	var (
+		ret  cbg.CBORMarshaler
+		exit exitcode.ExitCode
+	)
+	switch {
+	case msg.To == builtin.InitActorAddr && msg.Method == builtin.MethodsInit.Exec:
+		ret, exit = n.exec(msg, c)
+	case msg.To == builtin.StoragePowerActorAddr && msg.Method == builtin.MethodsPower.CreateMiner:
+		ret, exit = n.createMiner(msg, c)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.Propose:
+		ret, exit = n.propose(msg, c)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.Approve:
+		ret, exit = n.approve(msg, c)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.Cancel:
+		exit = n.cancel(msg)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.AddSigner:
+		exit = n.addSigner(msg)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.RemoveSigner:
+		exit = n.removeSigner(msg)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.SwapSigner:
+		exit = n.swapSigner(msg)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.ChangeNumApprovalsThreshold:
+		exit = n.changeThreshold(msg)
+	case code == builtin.MultisigActorCodeID && msg.Method == builtin.MethodsMultisig.LockBalance:
+		exit = n.lockBalance(msg)
+	case code == builtin.StorageMinerActorCodeID && msg.Method == builtin.MethodsMiner.ChangeOwnerAddress:
+		exit = n.changeOwner(msg)
+	case code == builtin.StorageMinerActorCodeID && msg.Method == builtin.MethodsMiner.ChangeWorkerAddress:
+		exit = n.changeWorker(msg)
+	case code == builtin.StorageMinerActorCodeID && msg.Method == builtin.MethodsMiner.ConfirmUpdateWorkerKey:
+		exit = n.confirmWorker(msg)
+	case code == builtin.StorageMinerActorCodeID && msg.Method == builtin.MethodsMiner.ChangePeerID:
+		exit = n.changePeerID(msg)
+	case code == builtin.StorageMinerActorCodeID && msg.Method == builtin.MethodsMiner.ChangeMultiaddrs:
+		exit = n.changeMultiaddrs(msg)
+	case code == builtin.StorageMinerActorCodeID && msg.Method == builtin.MethodsMiner.WithdrawBalance:
+		exit = n.withdraw(msg)
+	case code == builtin.StorageMinerActorCodeID && msg.Method == builtin.MethodsMiner.RepayDebt:
+		exit = n.repayDebt(msg)
+	case code == builtin.StorageMarketActorCodeID && msg.Method == builtin.MethodsMarket.AddBalance:
+		exit = n.addBalance(msg)
+	case code == builtin.StorageMarketActorCodeID && msg.Method == builtin.MethodsMarket.WithdrawBalance:
+		exit = n.withdrawBalance(msg)
+	case code == builtin.VerifiedRegistryActorCodeID && msg.Method == builtin.MethodsVerifiedRegistry.AddVerifiedClient:
+		exit = n.addVerifiedClient(msg)
+	}
+	if exit != exitcode.Ok {
+		n.transfer(msg.To, msg.From, msg.Value)
+		return types.MessageReceipt{ExitCode: exit}
+	}
+
+	rcpt := types.MessageReceipt{GasUsed: GasLimit / 2}
+	if ret != nil {
+		enc, err := actors.SerializeParams(ret)
+		if err != nil {
+			return types.MessageReceipt{ExitCode: exitcode.ErrSerialization}
+		}
+		rcpt.Return = enc
+	}
+	return rcpt
+}
+
+func (n *Node) exists(id address.Address) bool {
+	_, found := n.code(id)
+	return found || n.robust(id) != id
+}
+
+// code returns the actor code of id, scripted miners are miners even when Actors does not list them.
+func (n *Node) code(id address.Address) (cid.Cid, bool) {
+	if code, found := n.state.Actors[id.String()]; found {
+		return code, true
+	}
+	if _, found := n.state.Miners[id.String()]; found {
+		return builtin.StorageMinerActorCodeID, true
+	}
+	return cid.Undef, false
+}
+
+func (n *Node) transfer(from, to address.Address, value types.BigInt) {
+	if value.IsZero() {
+		return
+	}
+	n.state.Balances[n.resolve(from).String()] = types.BigSub(n.balance(from), value)
+	n.account(to)
+	n.state.Balances[n.resolve(to).String()] = types.BigAdd(n.balance(to), value)
+}
+
+// account creates the account actor of a key address on its first transfer, like the chain does.
+func (n *Node) account(addr address.Address) {
+	if p := addr.Protocol(); p != address.SECP256K1 && p != address.BLS {
+		return
+	}
+	if _, found := n.state.IDs[addr.String()]; found {
+		return
+	}
+
+	id, err := address.NewIDAddress(n.nextID)
+	if err != nil {
+		return
+	}
+	n.nextID++
+
+	n.state.IDs[addr.String()] = id
+	n.state.Actors[id.String()] = builtin.AccountActorCodeID
+	if bal, found := n.state.Balances[addr.String()]; found {
+		delete(n.state.Balances, addr.String())
+		n.state.Balances[id.String()] = bal
+	}
+}
+
+func (n *Node) exec(msg *types.Message, c cid.Cid) (cbg.CBORMarshaler, exitcode.ExitCode) {
+	params := new(init_.ExecParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return nil, exitcode.ErrSerialization
+	}
+
+	robust, err := address.NewActorAddress(c.Bytes())
+	if err != nil {
+		return nil, exitcode.ErrIllegalState
+	}
+	id, err := address.NewIDAddress(n.nextID)
+	if err != nil {
+		return nil, exitcode.ErrIllegalState
+	}
+	n.nextID++
+
+	n.state.IDs[robust.String()] = id
+	n.state.Actors[id.String()] = params.CodeCID
+	n.transfer(builtin.InitActorAddr, id, msg.Value)
+
+	if params.CodeCID == builtin.MultisigActorCodeID {
+		cp := new(multisig.ConstructorParams)
+		if err = cp.UnmarshalCBOR(bytes.NewReader(params.ConstructorParams)); err != nil {
+			return nil, exitcode.ErrSerialization
+		}
+		signers := make([]address.Address, 0, len(cp.Signers))
+		for _, signer := range cp.Signers {
+			n.account(signer)
+			signers = append(signers, n.resolve(signer))
+		}
+		st := &MsigState{Signers: signers, Threshold: cp.NumApprovalsThreshold, InitialBalance: types.NewInt(0)}
+		if cp.UnlockDuration != 0 {
+			st.InitialBalance = msg.Value
+			st.StartEpoch = cp.StartEpoch
+			st.UnlockDuration = cp.UnlockDuration
+		}
+		n.state.Msigs[id.String()] = st
+	}
+	return &init_.ExecReturn{IDAddress: id, RobustAddress: robust}, exitcode.Ok
+}
+
+// createMiner creates a scripted miner without power or funds, the value sent goes to its balance.
+func (n *Node) createMiner(msg *types.Message, c cid.Cid) (cbg.CBORMarshaler, exitcode.ExitCode) {
+	params := new(power.CreateMinerParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return nil, exitcode.ErrSerialization
+	}
+	ssize, err := params.WindowPoStProofType.SectorSize()
+	if err != nil {
+		return nil, exitcode.ErrIllegalArgument
+	}
+	pid := peer.ID(params.Peer)
+	if !n.exists(n.resolve(params.Owner)) || !n.exists(n.resolve(params.Worker)) {
+		return nil, exitcode.ErrIllegalArgument
+	}
+
+	robust, err := address.NewActorAddress(c.Bytes())
+	if err != nil {
+		return nil, exitcode.ErrIllegalState
+	}
+	id, err := address.NewIDAddress(n.nextID)
+	if err != nil {
+		return nil, exitcode.ErrIllegalState
+	}
+	n.nextID++
+
+	n.state.IDs[robust.String()] = id
+	n.state.Actors[id.String()] = builtin.StorageMinerActorCodeID
+	n.transfer(builtin.StoragePowerActorAddr, id, msg.Value)
+
+	addrs := make([][]byte, 0, len(params.Multiaddrs))
+	for _, addr := range params.Multiaddrs {
+		addrs = append(addrs, addr)
+	}
+	n.state.Miners[id.String()] = &MinerState{
+		Info: chain.MinerInfo{
+			Owner:      n.resolve(params.Owner),
+			Worker:     n.resolve(params.Worker),
+			PeerId:     &pid,
+			Multiaddrs: addrs,
+			SectorSize: ssize,
+		},
+	}
+	return &power.CreateMinerReturn{IDAddress: id, RobustAddress: robust}, exitcode.Ok
+}
+
+func (n *Node) threshold(msig address.Address) uint64 {
+	if st, found := n.state.Msigs[msig.String()]; found {
+		return st.Threshold
+	}
+	return defaultThreshold
+}
+
+func (n *Node) propose(msg *types.Message, c cid.Cid) (cbg.CBORMarshaler, exitcode.ExitCode) {
+	params := new(multisig.ProposeParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return nil, exitcode.ErrSerialization
+	}
+
+	msig := n.resolve(msg.To)
+	txnID := n.nextTxn[msig]
+	n.nextTxn[msig]++
+
+	trx := chain.MsigTransaction{
+		ID:       txnID,
+		To:       params.To,
+		Value:    params.Value,
+		Method:   params.Method,
+		Params:   params.Params,
+		Approved: []address.Address{n.resolve(msg.From)},
+	}
+	if n.threshold(msig) <= 1 {
+		rcpt := n.executeTxn(msig, &trx, c)
+		return &multisig.ProposeReturn{TxnID: multisig.TxnID(txnID), Applied: true, Code: rcpt.ExitCode,
+			Ret: rcpt.Return}, exitcode.Ok
+	}
+	n.state.Pending[msig.String()] = append(n.state.Pending[msig.String()], trx)
+	return &multisig.ProposeReturn{TxnID: multisig.TxnID(txnID)}, exitcode.Ok
+}
+
+func (n *Node) approve(msg *types.Message, c cid.Cid) (cbg.CBORMarshaler, exitcode.ExitCode) {
+	params := new(multisig.TxnIDParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return nil, exitcode.ErrSerialization
+	}
+
+	msig := n.resolve(msg.To)
+	pending := n.state.Pending[msig.String()]
+	for i := range pending {
+		if pending[i].ID != int64(params.ID) {
+			continue
+		}
+		if !hashMatches(&pending[i], params.ProposalHash) {
+			return nil, exitcode.ErrIllegalArgument
+		}
+		approver := n.resolve(msg.From)
+		for _, a := range pending[i].Approved {
+			if a == approver {
+				return nil, exitcode.ErrForbidden
+			}
+		}
+		pending[i].Approved = append(pending[i].Approved, approver)
+		if uint64(len(pending[i].Approved)) < n.threshold(msig) {
+			return &multisig.ApproveReturn{}, exitcode.Ok
+		}
+
+		trx := pending[i]
+		n.state.Pending[msig.String()] = append(pending[:i:i], pending[i+1:]...)
+		rcpt := n.executeTxn(msig, &trx, c)
+		return &multisig.ApproveReturn{Applied: true, Code: rcpt.ExitCode, Ret: rcpt.Return}, exitcode.Ok
+	}
+	return nil, exitcode.ErrNotFound
+}
+
+// executeTxn sends an approved transaction from the multisig, its failure does not fail the approval.
+func (n *Node) executeTxn(msig address.Address, trx *chain.MsigTransaction, c cid.Cid) types.MessageReceipt {
+	return n.execute(&types.Message{
+		From:   msig,
+		To:     trx.To,
+		Value:  trx.Value,
+		Method: trx.Method,
+		Params: trx.Params,
+	}, c)
+}
+
+func (n *Node) cancel(msg *types.Message) exitcode.ExitCode {
+	params := new(multisig.TxnIDParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+
+	msig := n.resolve(msg.To)
+	pending := n.state.Pending[msig.String()]
+	for i := range pending {
+		if pending[i].ID == int64(params.ID) {
+			if !hashMatches(&pending[i], params.ProposalHash) {
+				return exitcode.ErrIllegalArgument
+			}
+			if len(pending[i].Approved) == 0 || pending[i].Approved[0] != n.resolve(msg.From) {
+				return exitcode.ErrForbidden
+			}
+			n.state.Pending[msig.String()] = append(pending[:i:i], pending[i+1:]...)
+			return exitcode.Ok
+		}
+	}
+	return exitcode.ErrNotFound
+}
+
+// self returns the state of the multisig msg is sent to, the methods changing it can only be called by the multisig
+// itself, through an approved proposal.
+func (n *Node) self(msg *types.Message) (*MsigState, exitcode.ExitCode) {
+	msig := n.resolve(msg.To)
+	if n.resolve(msg.From) != msig {
+		return nil, exitcode.ErrForbidden
+	}
+	st, found := n.state.Msigs[msig.String()]
+	if !found {
+		return nil, exitcode.ErrNotFound
+	}
+	return st, exitcode.Ok
+}
+
+func (n *Node) signerIndex(st *MsigState, signer address.Address) int {
+	for i, s := range st.Signers {
+		if n.resolve(s) == n.resolve(signer) {
+			return i
+		}
+	}
+	return -1
+}
+
+func (n *Node) addSigner(msg *types.Message) exitcode.ExitCode {
+	params := new(multisig.AddSignerParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, exit := n.self(msg)
+	if exit != exitcode.Ok {
+		return exit
+	}
+	if n.signerIndex(st, params.Signer) >= 0 {
+		return exitcode.ErrForbidden
+	}
+	n.account(params.Signer)
+	st.Signers = append(st.Signers, n.resolve(params.Signer))
+	if params.Increase {
+		st.Threshold++
+	}
+	return exitcode.Ok
+}
+
+func (n *Node) removeSigner(msg *types.Message) exitcode.ExitCode {
+	params := new(multisig.RemoveSignerParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, exit := n.self(msg)
+	if exit != exitcode.Ok {
+		return exit
+	}
+	i := n.signerIndex(st, params.Signer)
+	if i < 0 || len(st.Signers) == 1 {
+		return exitcode.ErrForbidden
+	}
+	threshold := st.Threshold
+	if params.Decrease {
+		threshold--
+	}
+	if threshold < 1 || threshold > uint64(len(st.Signers)-1) {
+		return exitcode.ErrIllegalArgument
+	}
+	st.Signers = append(st.Signers[:i:i], st.Signers[i+1:]...)
+	st.Threshold = threshold
+	return exitcode.Ok
+}
+
+func (n *Node) swapSigner(msg *types.Message) exitcode.ExitCode {
+	params := new(multisig.SwapSignerParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, exit := n.self(msg)
+	if exit != exitcode.Ok {
+		return exit
+	}
+	i := n.signerIndex(st, params.From)
+	if i < 0 || n.signerIndex(st, params.To) >= 0 {
+		return exitcode.ErrForbidden
+	}
+	n.account(params.To)
+	st.Signers[i] = n.resolve(params.To)
+	return exitcode.Ok
+}
+
+func (n *Node) changeThreshold(msg *types.Message) exitcode.ExitCode {
+	params := new(multisig.ChangeNumApprovalsThresholdParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, exit := n.self(msg)
+	if exit != exitcode.Ok {
+		return exit
+	}
+	if params.NewThreshold < 1 || params.NewThreshold > uint64(len(st.Signers)) {
+		return exitcode.ErrIllegalArgument
+	}
+	st.Threshold = params.NewThreshold
+	return exitcode.Ok
+}
+
+// lockBalance locks part of the balance of a multisig which has no vesting yet, like the actor does.
+func (n *Node) lockBalance(msg *types.Message) exitcode.ExitCode {
+	params := new(multisig.LockBalanceParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, exit := n.self(msg)
+	if exit != exitcode.Ok {
+		return exit
+	}
+	if params.UnlockDuration <= 0 || params.Amount.LessThan(big.Zero()) {
+		return exitcode.ErrIllegalArgument
+	}
+	if st.UnlockDuration != 0 {
+		return exitcode.ErrForbidden
+	}
+	st.InitialBalance = params.Amount
+	st.StartEpoch = params.StartEpoch
+	st.UnlockDuration = params.UnlockDuration
+	return exitcode.Ok
+}
+
+// hashMatches checks the proposal hash of an approval or a cancellation like the actor does, when one is given.
+func hashMatches(trx *chain.MsigTransaction, hash []byte) bool {
+	if hash == nil {
+		return true
+	} else if len(trx.Approved) == 0 {
+		// the hash covers the proposer's approval, the actor aborts without it
+		return false
+	}
+	expected, err := multisig.ComputeProposalHash(&multisig.Transaction{
+		To:       trx.To,
+		Value:    trx.Value,
+		Method:   trx.Method,
+		Params:   trx.Params,
+		Approved: trx.Approved,
+	}, blake2b.Sum256)
+	return err == nil && bytes.Equal(expected, hash)
+}
+
+// minerCaller returns the state of the miner msg is sent to, nil when it is not scripted, checking that the sender is
+// its owner, worker or a control address.
+func (n *Node) minerCaller(msg *types.Message) (*MinerState, exitcode.ExitCode) {
+	st, found := n.state.Miners[n.resolve(msg.To).String()]
+	if !found {
+		return nil, exitcode.Ok
+	}
+	from := n.resolve(msg.From)
+	for _, addr := range append([]address.Address{st.Info.Owner, st.Info.Worker}, st.Info.ControlAddresses...) {
+		if n.resolve(addr) == from {
+			return st, exitcode.Ok
+		}
+	}
+	return nil, exitcode.ErrForbidden
+}
+
+func (n *Node) changePeerID(msg *types.Message) exitcode.ExitCode {
+	params := new(miner.ChangePeerIDParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	if len(params.NewID) > miner.MaxPeerIDLength {
+		return exitcode.ErrIllegalArgument
+	}
+	st, exit := n.minerCaller(msg)
+	if st != nil {
+		pid := peer.ID(params.NewID)
+		st.Info.PeerId = &pid
+	}
+	return exit
+}
+
+func (n *Node) changeMultiaddrs(msg *types.Message) exitcode.ExitCode {
+	params := new(miner.ChangeMultiaddrsParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, exit := n.minerCaller(msg)
+	if st != nil {
+		st.Info.Multiaddrs = make([][]byte, 0, len(params.NewMultiaddrs))
+		for _, maddr := range params.NewMultiaddrs {
+			st.Info.Multiaddrs = append(st.Info.Multiaddrs, maddr)
+		}
+	}
+	return exit
+}
+
+// payDebt burns the fee debt of st from the unlocked balance of the miner, as far as it goes.
+func (n *Node) payDebt(id address.Address, st *MinerState) {
+	unlocked := big.Sub(n.balance(id), st.locked())
+	paid := big.Max(big.Min(orZero(st.FeeDebt), unlocked), big.Zero())
+	st.FeeDebt = big.Sub(orZero(st.FeeDebt), paid)
+	n.state.Balances[id.String()] = big.Sub(n.balance(id), paid)
+}
+
+// withdraw pays the fee debt first and fails when it can not, like the miner actor, then sends the owner what is
+// available of the requested amount.
+func (n *Node) withdraw(msg *types.Message) exitcode.ExitCode {
+	params := new(miner.WithdrawBalanceParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	id := n.resolve(msg.To)
+	st, found := n.state.Miners[id.String()]
+	if !found {
+		return exitcode.Ok
+	}
+	if n.resolve(msg.From) != n.resolve(st.Info.Owner) {
+		return exitcode.ErrForbidden
+	}
+
+	n.payDebt(id, st)
+	if debt := orZero(st.FeeDebt); !debt.IsZero() {
+		return exitcode.ErrInsufficientFunds
+	}
+	avail := big.Sub(n.balance(id), st.locked())
+	n.transfer(id, st.Info.Owner, big.Max(big.Min(avail, params.AmountRequested), big.Zero()))
+	return exitcode.Ok
+}
+
+func (n *Node) repayDebt(msg *types.Message) exitcode.ExitCode {
+	st, exit := n.minerCaller(msg)
+	if st != nil {
+		n.payDebt(n.resolve(msg.To), st)
+	}
+	return exit
+}
+
+// changeOwner nominates a new owner when called by the owner, the nominee takes over by calling it with its own
+// address.
+func (n *Node) changeOwner(msg *types.Message) exitcode.ExitCode {
+	var newOwner address.Address
+	if err := newOwner.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, found := n.state.Miners[n.resolve(msg.To).String()]
+	if !found {
+		return exitcode.Ok
+	}
+	from, nominee := n.resolve(msg.From), n.resolve(newOwner)
+	switch {
+	case from == n.resolve(st.Info.Owner):
+		st.PendingOwner = nominee
+		if nominee == from {
+			st.PendingOwner = address.Undef
+		}
+	case st.PendingOwner != address.Undef && from == st.PendingOwner && nominee == st.PendingOwner:
+		st.Info.Owner = nominee
+		st.PendingOwner = address.Undef
+	default:
+		return exitcode.ErrForbidden
+	}
+	return exitcode.Ok
+}
+
+// changeWorker replaces the control addresses at once and schedules the new worker after miner.WorkerKeyChangeDelay.
+func (n *Node) changeWorker(msg *types.Message) exitcode.ExitCode {
+	params := new(miner.ChangeWorkerAddressParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	st, found := n.state.Miners[n.resolve(msg.To).String()]
+	if !found {
+		return exitcode.Ok
+	}
+	if n.resolve(msg.From) != n.resolve(st.Info.Owner) {
+		return exitcode.ErrForbidden
+	}
+
+	st.Info.ControlAddresses = make([]address.Address, 0, len(params.NewControlAddrs))
+	for _, addr := range params.NewControlAddrs {
+		st.Info.ControlAddresses = append(st.Info.ControlAddresses, n.resolve(addr))
+	}
+	if worker := n.resolve(params.NewWorker); worker != n.resolve(st.Info.Worker) {
+		st.Info.NewWorker = worker
+		st.Info.WorkerChangeEpoch = n.state.Height + miner.WorkerKeyChangeDelay
+	}
+	return exitcode.Ok
+}
+
+// confirmWorker makes the scheduled worker effective once its epoch is reached, before that it does nothing.
+func (n *Node) confirmWorker(msg *types.Message) exitcode.ExitCode {
+	st, found := n.state.Miners[n.resolve(msg.To).String()]
+	if !found {
+		return exitcode.Ok
+	}
+	if n.resolve(msg.From) != n.resolve(st.Info.Owner) {
+		return exitcode.ErrForbidden
+	}
+	if st.Info.NewWorker != address.Undef && n.state.Height >= st.Info.WorkerChangeEpoch {
+		st.Info.Worker = st.Info.NewWorker
+		st.Info.NewWorker = address.Undef
+		st.Info.WorkerChangeEpoch = -1
+	}
+	return exitcode.Ok
+}
+
+// market returns the storage market collateral of addr, zero when it has none.
+func (n *Node) market(addr address.Address) *chain.MarketBalance {
+	if bal, found := n.state.Market[n.resolve(addr).String()]; found {
+		return &chain.MarketBalance{Escrow: orZero(bal.Escrow), Locked: orZero(bal.Locked)}
+	}
+	return &chain.MarketBalance{Escrow: big.Zero(), Locked: big.Zero()}
+}
+
+func (n *Node) addBalance(msg *types.Message) exitcode.ExitCode {
+	var addr address.Address
+	if err := addr.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	bal := n.market(addr)
+	bal.Escrow = big.Add(bal.Escrow, msg.Value)
+	n.state.Market[n.resolve(addr).String()] = bal
+	return exitcode.Ok
+}
+
+// withdrawBalance lets the owner or the worker of a scripted miner withdraw its collateral to the owner, and any
+// other address withdraw its own. The v6 market actor returns nothing, the amount is only returned from v7 on.
+func (n *Node) withdrawBalance(msg *types.Message) exitcode.ExitCode {
+	params := new(market.WithdrawBalanceParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	id, from := n.resolve(params.ProviderOrClientAddress), n.resolve(msg.From)
+	recipient := id
+	if st, found := n.state.Miners[id.String()]; found {
+		if from != n.resolve(st.Info.Owner) && from != n.resolve(st.Info.Worker) {
+			return exitcode.ErrForbidden
+		}
+		recipient = st.Info.Owner
+	} else if from != id {
+		return exitcode.ErrForbidden
+	}
+
+	bal := n.market(id)
+	amount := big.Max(big.Min(params.Amount, big.Sub(bal.Escrow, bal.Locked)), big.Zero())
+	bal.Escrow = big.Sub(bal.Escrow, amount)
+	n.state.Market[id.String()] = bal
+	n.transfer(builtin.StorageMarketActorAddr, recipient, amount)
+	return exitcode.Ok
+}
+
+func (n *Node) addVerifiedClient(msg *types.Message) exitcode.ExitCode {
+	params := new(verifreg.AddVerifiedClientParams)
+	if err := params.UnmarshalCBOR(bytes.NewReader(msg.Params)); err != nil {
+		return exitcode.ErrSerialization
+	}
+	if params.Allowance.LessThan(verifreg.MinVerifiedDealSize) {
+		return exitcode.ErrIllegalArgument
+	}
+
+	verifier := n.resolve(msg.From).String()
+	allowance, found := n.state.Verifiers[verifier]
+	if !found {
+		return exitcode.ErrNotFound
+	}
+	if allowance.LessThan(params.Allowance) {
+		return exitcode.ErrIllegalArgument
+	}
+	n.account(params.Address)
+	client := n.resolve(params.Address).String()
+	if _, found = n.state.Verifiers[client]; found {
+		return exitcode.ErrIllegalArgument
+	}
+
+	n.state.Verifiers[verifier] = big.Sub(allowance, params.Allowance)
+	n.state.VerifiedClients[client] = big.Add(orZero(n.state.VerifiedClients[client]), params.Allowance)
+	return exitcode.Ok
+}
@@ -0,0 +1,390 @@
+package mock
+
+import (
+	"context"
+	"encoding/json"
+	"fil-assistant/chain"
+	"fil-assistant/lib"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// MsigState is the part of a multisig the node needs to decide whether a transaction is applied and how much of its
+// balance is locked.
+type MsigState struct {
+	Signers        []address.Address
+	Threshold      uint64
+	InitialBalance types.BigInt
+	StartEpoch     abi.ChainEpoch
+	UnlockDuration abi.ChainEpoch
+}
+
+// MinerState is the scriptable state of a storage miner. Unless MinerAvailable scripts it, its available balance is
+// the balance less the locked funds and the fee debt. PendingOwner is the owner nominated by the current one.
+type MinerState struct {
+	Info              chain.MinerInfo
+	PendingOwner      address.Address
+	Power             chain.PowerClaim
+	PreCommitDeposits types.BigInt
+	LockedFunds       types.BigInt
+	InitialPledge     types.BigInt
+	FeeDebt           types.BigInt
+}
+
+// locked sums the funds the miner can not withdraw, besides its fee debt.
+func (st *MinerState) locked() types.BigInt {
+	return big.Sum(orZero(st.LockedFunds), orZero(st.PreCommitDeposits), orZero(st.InitialPledge))
+}
+
+// State is the scriptable chain state of a Node. Maps are keyed by address strings, balances and actors by the ID
+// address whenever IDs knows one.
+type State struct {
+	// Height is the current epoch, it only moves when set.
+	Height         abi.ChainEpoch
+	Balances       map[string]types.BigInt
+	IDs            map[string]address.Address
+	Actors         map[string]cid.Cid
+	MinerAvailable map[string]types.BigInt
+	Miners         map[string]*MinerState
+	Market         map[string]*chain.MarketBalance
+	// Verifiers holds the allowance left to each verifier, VerifiedClients the DataCap left to each client.
+	Verifiers       map[string]types.BigInt
+	VerifiedClients map[string]types.BigInt
+	Msigs           map[string]*MsigState
+	Pending         map[string][]chain.MsigTransaction
+}
+
+// NewState returns a state knowing only the singleton actors.
+func NewState() *State {
+	return &State{
+		Balances: make(map[string]types.BigInt),
+		IDs:      make(map[string]address.Address),
+		Actors: map[string]cid.Cid{
+			builtin.SystemActorAddr.String():           builtin.SystemActorCodeID,
+			builtin.InitActorAddr.String():             builtin.InitActorCodeID,
+			builtin.RewardActorAddr.String():           builtin.RewardActorCodeID,
+			builtin.CronActorAddr.String():             builtin.CronActorCodeID,
+			builtin.StoragePowerActorAddr.String():     builtin.StoragePowerActorCodeID,
+			builtin.StorageMarketActorAddr.String():    builtin.StorageMarketActorCodeID,
+			builtin.VerifiedRegistryActorAddr.String(): builtin.VerifiedRegistryActorCodeID,
+		},
+		MinerAvailable:  make(map[string]types.BigInt),
+		Miners:          make(map[string]*MinerState),
+		Market:          make(map[string]*chain.MarketBalance),
+		Verifiers:       make(map[string]types.BigInt),
+		VerifiedClients: make(map[string]types.BigInt),
+		Msigs:           make(map[string]*MsigState),
+		Pending:         make(map[string][]chain.MsigTransaction),
+	}
+}
+
+// LoadState reads a State from a JSON file, missing maps are left empty.
+func LoadState(path string) (*State, error) {
+	val, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	st := NewState()
+	if err = json.Unmarshal(val, st); err != nil {
+		return nil, xerrors.Errorf("invalid state %s: %w", path, err)
+	}
+	return st, nil
+}
+
+// Node is an in-process fake of the Lotus JSON-RPC API, serving the methods LotusClient calls. Pushed messages have
+// their signature and nonce checked and are executed at once, so StateWaitMsg never blocks.
+type Node struct {
+	// Exec overrides the execution of a pushed message when it returns true, e.g. to make a method fail.
+	Exec func(msg *types.Message) (types.MessageReceipt, bool)
+	// Reject fails the push of a message when it returns an error, e.g. to make the mpool refuse it.
+	Reject func(smsg *types.SignedMessage) error
+	// Stall keeps pushed messages in the mpool until Mine is called, so that they can be replaced. Once it is unset,
+	// a replacement is executed at once.
+	Stall bool
+
+	lk       sync.Mutex
+	state    *State
+	nonces   map[address.Address]uint64
+	messages map[cid.Cid]*types.SignedMessage
+	receipts map[cid.Cid]types.MessageReceipt
+	pushed   []*types.SignedMessage
+	mpool    []*types.SignedMessage
+	replaced map[cid.Cid]cid.Cid
+	nextID   uint64
+	nextTxn  map[address.Address]int64
+
+	server *http.Server
+}
+
+func NewNode(state *State) *Node {
+	if state == nil {
+		state = NewState()
+	}
+	return &Node{
+		state:    state,
+		nonces:   make(map[address.Address]uint64),
+		messages: make(map[cid.Cid]*types.SignedMessage),
+		receipts: make(map[cid.Cid]types.MessageReceipt),
+		replaced: make(map[cid.Cid]cid.Cid),
+		nextID:   1000,
+		nextTxn:  make(map[address.Address]int64),
+	}
+}
+
+// Start serves the node on listen, e.g. "127.0.0.1:0", and returns the endpoint to put in config.toml.
+func (n *Node) Start(listen string) (string, error) {
+	ln, err := net.Listen("tcp", listen)
+	if err != nil {
+		return "", err
+	}
+	n.server = &http.Server{Handler: n}
+	go n.server.Serve(ln)
+	return "http://" + ln.Addr().String() + "/rpc/v0", nil
+}
+
+func (n *Node) Close() error {
+	if n.server == nil {
+		return nil
+	}
+	return n.server.Shutdown(context.Background())
+}
+
+func (n *Node) SetBalance(addr address.Address, bal types.BigInt) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Balances[n.resolve(addr).String()] = bal
+}
+
+func (n *Node) Balance(addr address.Address) types.BigInt {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	return n.balance(addr)
+}
+
+// SetID registers the ID address of a robust address, like an account actor created by a first transfer.
+func (n *Node) SetID(addr, id address.Address) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.IDs[addr.String()] = id
+}
+
+func (n *Node) SetActor(addr address.Address, code cid.Cid) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Actors[n.resolve(addr).String()] = code
+}
+
+func (n *Node) SetMinerAvailable(miner address.Address, bal types.BigInt) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.MinerAvailable[n.resolve(miner).String()] = bal
+}
+
+// SetMiner makes addr a storage miner with the state st.
+func (n *Node) SetMiner(addr address.Address, st *MinerState) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Miners[n.resolve(addr).String()] = st
+}
+
+// SetMarketBalance sets the storage market collateral of a provider or client.
+func (n *Node) SetMarketBalance(addr address.Address, bal *chain.MarketBalance) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Market[n.resolve(addr).String()] = bal
+}
+
+// SetVerifier makes addr a verifier with allowance left to grant.
+func (n *Node) SetVerifier(addr address.Address, allowance types.BigInt) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Verifiers[n.resolve(addr).String()] = allowance
+}
+
+func (n *Node) SetMsig(msig address.Address, st *MsigState) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Msigs[n.resolve(msig).String()] = st
+}
+
+func (n *Node) SetHeight(height abi.ChainEpoch) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Height = height
+}
+
+// SetPending replaces the pending transactions of msig, e.g. with one no signer approves any more.
+func (n *Node) SetPending(msig address.Address, trxs []chain.MsigTransaction) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	n.state.Pending[n.resolve(msig).String()] = trxs
+}
+
+func (n *Node) Pending(msig address.Address) []chain.MsigTransaction {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	return append([]chain.MsigTransaction(nil), n.state.Pending[n.resolve(msig).String()]...)
+}
+
+// Pushed returns every message accepted by MpoolPush, in order.
+func (n *Node) Pushed() []*types.SignedMessage {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	return append([]*types.SignedMessage(nil), n.pushed...)
+}
+
+func (n *Node) Receipt(c cid.Cid) (types.MessageReceipt, bool) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	rcpt, found := n.receipts[c]
+	return rcpt, found
+}
+
+// resolve returns the ID address of addr when the node knows one.
+func (n *Node) resolve(addr address.Address) address.Address {
+	if id, found := n.state.IDs[addr.String()]; found {
+		return id
+	}
+	return addr
+}
+
+// robust is the reverse of resolve, signatures are checked against the key address.
+func (n *Node) robust(addr address.Address) address.Address {
+	if addr.Protocol() != address.ID {
+		return addr
+	}
+	for key, id := range n.state.IDs {
+		if id == addr {
+			if a, err := address.NewFromString(key); err == nil {
+				return a
+			}
+		}
+	}
+	return addr
+}
+
+func (n *Node) balance(addr address.Address) types.BigInt {
+	if bal, found := n.state.Balances[n.resolve(addr).String()]; found {
+		return bal
+	}
+	return types.NewInt(0)
+}
+
+func (n *Node) push(smsg *types.SignedMessage) (cid.Cid, error) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	msg := &smsg.Message
+	from := n.robust(msg.From)
+	signer, err := lib.SignerOf(smsg.Signature.Type)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if err = signer.Verify(from, msg.Cid().Bytes(), smsg.Signature.Data); err != nil {
+		return cid.Undef, xerrors.Errorf("invalid signature: %w", err)
+	}
+	if n.Reject != nil {
+		if err = n.Reject(smsg); err != nil {
+			return cid.Undef, err
+		}
+	}
+
+	key := n.resolve(msg.From)
+	c := smsg.Cid()
+	for i, held := range n.mpool {
+		if n.resolve(held.Message.From) != key || held.Message.Nonce != msg.Nonce {
+			continue
+		}
+		if minPremium := chain.MinRBF(held.Message.GasPremium); msg.GasPremium.LessThan(minPremium) {
+			return cid.Undef, xerrors.Errorf("replace by fee needs a premium of at least %s, got %s", minPremium,
+				msg.GasPremium)
+		}
+		n.replaced[held.Cid()] = c
+		n.messages[c] = smsg
+		n.pushed = append(n.pushed, smsg)
+		if n.Stall {
+			n.mpool[i] = smsg
+		} else {
+			n.mpool = append(n.mpool[:i:i], n.mpool[i+1:]...)
+			n.apply(smsg)
+		}
+		return c, nil
+	}
+
+	if msg.Nonce != n.nonces[key] {
+		return cid.Undef, xerrors.Errorf("nonce %d of %s is not the expected %d", msg.Nonce, msg.From, n.nonces[key])
+	}
+	n.nonces[key]++
+
+	n.messages[c] = smsg
+	n.pushed = append(n.pushed, smsg)
+	if n.Stall {
+		n.mpool = append(n.mpool, smsg)
+	} else {
+		n.apply(smsg)
+	}
+	return c, nil
+}
+
+// Mine executes the messages held in the mpool.
+func (n *Node) Mine() {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+
+	for _, smsg := range n.mpool {
+		n.apply(smsg)
+	}
+	n.mpool = nil
+}
+
+func (n *Node) apply(smsg *types.SignedMessage) {
+	c := smsg.Cid()
+	if n.Exec != nil {
+		if rcpt, ok := n.Exec(&smsg.Message); ok {
+			n.receipts[c] = rcpt
+			return
+		}
+	}
+	n.receipts[c] = n.execute(&smsg.Message, c)
+}
+
+// lookup follows the replacements of c and returns the cid which got executed with its receipt.
+func (n *Node) lookup(c cid.Cid) (cid.Cid, types.MessageReceipt, error) {
+	for {
+		next, found := n.replaced[c]
+		if !found {
+			break
+		}
+		c = next
+	}
+	if rcpt, found := n.receipts[c]; found {
+		return c, rcpt, nil
+	}
+	if _, found := n.messages[c]; found {
+		return c, types.MessageReceipt{}, xerrors.Errorf("message %s is still in the mpool", c)
+	}
+	return c, types.MessageReceipt{}, xerrors.Errorf("message %s not found", c)
+}
@@ -0,0 +1,110 @@
+package mock
+
+import (
+	"context"
+	"fil-assistant/lib"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"strings"
+	"testing"
+)
+
+func newTestKey(t *testing.T) ([]byte, address.Address) {
+	t.Helper()
+	signer := new(lib.Secp256Signer)
+	pk, err := signer.GenPriKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := signer.ToAddress(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return pk, addr
+}
+
+func signedSend(t *testing.T, pk []byte, from, to address.Address, nonce uint64) *types.SignedMessage {
+	t.Helper()
+	smsg, err := lib.SignMessage(new(lib.Secp256Signer), pk, &types.Message{
+		From:       from,
+		To:         to,
+		Value:      types.NewInt(1),
+		Method:     builtin.MethodSend,
+		Nonce:      nonce,
+		GasLimit:   GasLimit,
+		GasFeeCap:  types.NewInt(BaseFee + GasPremium),
+		GasPremium: types.NewInt(GasPremium),
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return smsg
+}
+
+func TestPushRejectsSignature(t *testing.T) {
+	ctx := context.Background()
+	n := NewNode(nil)
+	c := n.Client()
+	pk, from := newTestKey(t)
+	otherPk, _ := newTestKey(t)
+	_, to := newTestKey(t)
+	n.SetBalance(from, types.NewInt(10))
+
+	if _, err := c.PushMsg(ctx, signedSend(t, otherPk, from, to, 0)); err == nil ||
+		!strings.Contains(err.Error(), "invalid signature") {
+		t.Fatalf("message signed by another key: got %v", err)
+	}
+
+	smsg := signedSend(t, pk, from, to, 0)
+	smsg.Message.Value = types.NewInt(2)
+	if _, err := c.PushMsg(ctx, smsg); err == nil || !strings.Contains(err.Error(), "invalid signature") {
+		t.Fatalf("message changed after signing: got %v", err)
+	}
+
+	if bal := n.Balance(to); len(n.Pushed()) != 0 || !bal.IsZero() {
+		t.Fatalf("rejected messages were accepted: %d pushed, balance %s", len(n.Pushed()), bal)
+	}
+	if nonce, _ := c.GetNonce(ctx, from); nonce != 0 {
+		t.Fatalf("rejected messages moved the nonce to %d", nonce)
+	}
+}
+
+func TestPushChecksNonce(t *testing.T) {
+	ctx := context.Background()
+	n := NewNode(nil)
+	c := n.Client()
+	pk, from := newTestKey(t)
+	_, to := newTestKey(t)
+	n.SetBalance(from, types.NewInt(10))
+
+	if _, err := c.PushMsg(ctx, signedSend(t, pk, from, to, 1)); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("nonce from the future: got %v", err)
+	}
+
+	mc, err := c.PushMsg(ctx, signedSend(t, pk, from, to, 0))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = c.WaitMessage(ctx, mc, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err = c.PushMsg(ctx, signedSend(t, pk, from, to, 0)); err == nil || !strings.Contains(err.Error(), "nonce") {
+		t.Fatalf("reused nonce: got %v", err)
+	}
+	if nonce, _ := c.GetNonce(ctx, from); nonce != 1 {
+		t.Fatalf("nonce is %d, want 1", nonce)
+	}
+	if bal := n.Balance(to); !bal.Equals(types.NewInt(1)) {
+		t.Fatalf("receiver balance %s, want 1", bal)
+	}
+}
+
+func TestClientUnsupportedMethod(t *testing.T) {
+	var res interface{}
+	err := (&client{n: NewNode(nil)}).call(&res, "Filecoin.ChainHead")
+	if err == nil || !strings.Contains(err.Error(), "Filecoin.ChainHead") {
+		t.Fatalf("got %v, want an error naming the method", err)
+	}
+}
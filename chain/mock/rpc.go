@@ -0,0 +1,480 @@
+package mock
+
+import (
+	"encoding/json"
+	"fil-assistant/chain"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+	"net/http"
+)
+
+type request struct {
+	ID     json.RawMessage   `json:"id"`
+	Method string            `json:"method"`
+	Params []json.RawMessage `json:"params"`
+}
+
+type rpcError struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
+}
+
+type response struct {
+	Version string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id"`
+	Result  interface{}     `json:"result,omitempty"`
+	Error   *rpcError       `json:"error,omitempty"`
+}
+
+// method handles one JSON-RPC call, params are decoded by the method itself.
+type method func(n *Node, params []json.RawMessage) (interface{}, error)
+
+var methods = map[string]method{
+	"Filecoin.MpoolGetNonce":              mpoolGetNonce,
+	"Filecoin.GasEstimateMessageGas":      gasEstimateMessageGas,
+	"Filecoin.MpoolPush":                  mpoolPush,
+	"Filecoin.MpoolPending":               mpoolPending,
+	"Filecoin.StateWaitMsg":               stateWaitMsg,
+	"Filecoin.ChainGetMessage":            chainGetMessage,
+	"Filecoin.WalletBalance":              walletBalance,
+	"Filecoin.StateLookupID":              stateLookupID,
+	"Filecoin.StateMinerAvailableBalance": stateMinerAvailableBalance,
+	"Filecoin.MsigGetPending":             msigGetPending,
+	"Filecoin.StateGetActor":              stateGetActor,
+	"Filecoin.StateReadState":             stateReadState,
+	"Filecoin.StateAccountKey":            stateAccountKey,
+	"Filecoin.MsigGetAvailableBalance":    msigGetAvailableBalance,
+	"Filecoin.MsigGetVestingSchedule":     msigGetVestingSchedule,
+	"Filecoin.StateMinerInfo":             stateMinerInfo,
+	"Filecoin.StateMinerPower":            stateMinerPower,
+	"Filecoin.StateMarketBalance":         stateMarketBalance,
+	"Filecoin.StateVerifiedClientStatus":  stateVerifiedClientStatus,
+	"Filecoin.StateVerifierStatus":        stateVerifierStatus,
+}
+
+func (n *Node) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "only POST is supported", http.StatusMethodNotAllowed)
+		return
+	}
+
+	req := new(request)
+	if err := json.NewDecoder(r.Body).Decode(req); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	resp := &response{Version: "2.0", ID: req.ID}
+	if m, found := methods[req.Method]; !found {
+		resp.Error = &rpcError{Code: -32601, Message: "method " + req.Method + " not found"}
+	} else if res, err := m(n, req.Params); err != nil {
+		resp.Error = &rpcError{Code: 1, Message: err.Error()}
+	} else if res == nil {
+		// Lotus answers null for missing values, which omitempty would drop
+		resp.Result = json.RawMessage("null")
+	} else {
+		resp.Result = res
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// param decodes the i-th param into v.
+func param(params []json.RawMessage, i int, v interface{}) error {
+	if i >= len(params) {
+		return xerrors.Errorf("missing param %d", i)
+	}
+	if err := json.Unmarshal(params[i], v); err != nil {
+		return xerrors.Errorf("invalid param %d: %w", i, err)
+	}
+	return nil
+}
+
+func addrParam(params []json.RawMessage) (address.Address, error) {
+	var addr address.Address
+	return addr, param(params, 0, &addr)
+}
+
+func mpoolGetNonce(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	return n.nonces[n.resolve(addr)], nil
+}
+
+// gasEstimateMessageGas fills fixed gas values, capping the fee cap so that the fee stays under MaxFee.
+func gasEstimateMessageGas(n *Node, params []json.RawMessage) (interface{}, error) {
+	msg := new(types.Message)
+	if err := param(params, 0, msg); err != nil {
+		return nil, err
+	}
+	var spec struct {
+		MaxFee abi.TokenAmount
+	}
+	if err := param(params, 1, &spec); err != nil {
+		return nil, err
+	}
+
+	msg.GasLimit = GasLimit
+	if msg.GasPremium.Nil() || msg.GasPremium.IsZero() {
+		msg.GasPremium = types.NewInt(GasPremium)
+	}
+	if msg.GasFeeCap.Nil() || msg.GasFeeCap.IsZero() {
+		msg.GasFeeCap = types.NewInt(BaseFee + GasPremium)
+	}
+	if !spec.MaxFee.Nil() && !spec.MaxFee.IsZero() {
+		maxFeeCap := types.BigDiv(spec.MaxFee, types.NewInt(uint64(msg.GasLimit)))
+		if msg.GasFeeCap.GreaterThan(maxFeeCap) {
+			msg.GasFeeCap = maxFeeCap
+		}
+	}
+	if msg.GasPremium.GreaterThan(msg.GasFeeCap) {
+		msg.GasPremium = msg.GasFeeCap
+	}
+	return msg, nil
+}
+
+func mpoolPush(n *Node, params []json.RawMessage) (interface{}, error) {
+	smsg := new(types.SignedMessage)
+	if err := param(params, 0, smsg); err != nil {
+		return nil, err
+	}
+	return n.push(smsg)
+}
+
+func stateWaitMsg(n *Node, params []json.RawMessage) (interface{}, error) {
+	var c cid.Cid
+	if err := param(params, 0, &c); err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	c, rcpt, err := n.lookup(c)
+	if err != nil {
+		return nil, err
+	}
+	return &chain.MsgLookup{
+		Message: c,
+		Receipt: rcpt,
+		TipSet:  types.EmptyTSK,
+	}, nil
+}
+
+func mpoolPending(n *Node, params []json.RawMessage) (interface{}, error) {
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	return append([]*types.SignedMessage{}, n.mpool...), nil
+}
+
+func chainGetMessage(n *Node, params []json.RawMessage) (interface{}, error) {
+	var c cid.Cid
+	if err := param(params, 0, &c); err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	smsg, found := n.messages[c]
+	if !found {
+		return nil, xerrors.Errorf("message %s not found", c)
+	}
+	return &smsg.Message, nil
+}
+
+func walletBalance(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+	return n.Balance(addr), nil
+}
+
+func stateLookupID(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	if id := n.resolve(addr); id.Protocol() == address.ID {
+		return id, nil
+	}
+	return nil, xerrors.Errorf("%s: %w", addr, chain.ErrActorNotFound)
+}
+
+func stateMinerAvailableBalance(n *Node, params []json.RawMessage) (interface{}, error) {
+	miner, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	if bal, found := n.state.MinerAvailable[n.resolve(miner).String()]; found {
+		return bal, nil
+	}
+	st, err := n.miner(miner)
+	if err != nil {
+		return nil, err
+	}
+	locked := big.Add(st.locked(), orZero(st.FeeDebt))
+	return big.Max(big.Sub(n.balance(miner), locked), big.Zero()), nil
+}
+
+func (n *Node) miner(addr address.Address) (*MinerState, error) {
+	st, found := n.state.Miners[n.resolve(addr).String()]
+	if !found {
+		return nil, xerrors.Errorf("miner %s not found", addr)
+	}
+	return st, nil
+}
+
+// orZero turns the amounts left out of a scripted state into zero.
+func orZero(val types.BigInt) types.BigInt {
+	if val.Nil() {
+		return big.Zero()
+	}
+	return val
+}
+
+func stateMinerInfo(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	st, err := n.miner(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &st.Info, nil
+}
+
+// minerMinPower is the consensus minimum power of mainnet miners, 10 TiB.
+var minerMinPower = big.NewInt(10 << 40)
+
+// stateMinerPower sums the power of every scripted miner into the network power.
+func stateMinerPower(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	st, err := n.miner(addr)
+	if err != nil {
+		return nil, err
+	}
+	power := &chain.MinerPower{
+		MinerPower: chain.PowerClaim{
+			RawBytePower:    orZero(st.Power.RawBytePower),
+			QualityAdjPower: orZero(st.Power.QualityAdjPower),
+		},
+		TotalPower: chain.PowerClaim{RawBytePower: big.Zero(), QualityAdjPower: big.Zero()},
+	}
+	for _, miner := range n.state.Miners {
+		power.TotalPower.RawBytePower = big.Add(power.TotalPower.RawBytePower, orZero(miner.Power.RawBytePower))
+		power.TotalPower.QualityAdjPower = big.Add(power.TotalPower.QualityAdjPower, orZero(miner.Power.QualityAdjPower))
+	}
+	power.HasMinPower = power.MinerPower.QualityAdjPower.GreaterThanEqual(minerMinPower)
+	return power, nil
+}
+
+func msigGetPending(n *Node, params []json.RawMessage) (interface{}, error) {
+	msig, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+	return n.Pending(msig), nil
+}
+
+func stateGetActor(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	id := n.resolve(addr)
+	code, found := n.code(id)
+	if !found && n.exists(id) {
+		// IDs scripted in the state without an actor are accounts
+		code, found = builtin.AccountActorCodeID, true
+	}
+	if !found {
+		return nil, xerrors.Errorf("%s: %w", addr, chain.ErrActorNotFound)
+	}
+	return &types.Actor{
+		Code:    code,
+		Head:    code,
+		Nonce:   n.nonces[n.resolve(addr)],
+		Balance: n.balance(addr),
+	}, nil
+}
+
+func (n *Node) msig(addr address.Address) (*MsigState, error) {
+	st, found := n.state.Msigs[n.resolve(addr).String()]
+	if !found {
+		return nil, xerrors.Errorf("multisig %s not found", addr)
+	}
+	return st, nil
+}
+
+// stateReadState only knows the state of multisigs and the funds of miners.
+func stateReadState(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	if miner, err := n.miner(addr); err == nil {
+		return &chain.ActorState{
+			Balance: n.balance(addr),
+			Code:    builtin.StorageMinerActorCodeID,
+			State: mustJSON(map[string]types.BigInt{
+				"PreCommitDeposits": orZero(miner.PreCommitDeposits),
+				"LockedFunds":       orZero(miner.LockedFunds),
+				"InitialPledge":     orZero(miner.InitialPledge),
+				"FeeDebt":           orZero(miner.FeeDebt),
+			}),
+		}, nil
+	}
+	st, err := n.msig(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &chain.ActorState{
+		Balance: n.balance(addr),
+		Code:    builtin.MultisigActorCodeID,
+		State: mustJSON(&multisig.State{
+			Signers:               st.Signers,
+			NumApprovalsThreshold: st.Threshold,
+			NextTxnID:             multisig.TxnID(n.nextTxn[n.resolve(addr)]),
+			InitialBalance:        st.InitialBalance,
+			StartEpoch:            st.StartEpoch,
+			UnlockDuration:        st.UnlockDuration,
+			PendingTxns:           builtin.MultisigActorCodeID,
+		}),
+	}, nil
+}
+
+func mustJSON(v interface{}) json.RawMessage {
+	val, err := json.Marshal(v)
+	if err != nil {
+		panic(err)
+	}
+	return val
+}
+
+func stateAccountKey(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	if key := n.robust(n.resolve(addr)); key.Protocol() == address.SECP256K1 || key.Protocol() == address.BLS {
+		return key, nil
+	}
+	return nil, xerrors.Errorf("%s is not an account actor", addr)
+}
+
+func msigGetAvailableBalance(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	st, err := n.msig(addr)
+	if err != nil {
+		return nil, err
+	}
+	locked := big.Zero()
+	if st.UnlockDuration != 0 {
+		ms := &multisig.State{InitialBalance: st.InitialBalance, StartEpoch: st.StartEpoch, UnlockDuration: st.UnlockDuration}
+		locked = ms.AmountLocked(n.state.Height - st.StartEpoch)
+	}
+	return big.Max(big.Sub(n.balance(addr), locked), big.Zero()), nil
+}
+
+func msigGetVestingSchedule(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	st, err := n.msig(addr)
+	if err != nil {
+		return nil, err
+	}
+	return &chain.MsigVesting{
+		InitialBalance: st.InitialBalance,
+		StartEpoch:     st.StartEpoch,
+		UnlockDuration: st.UnlockDuration,
+	}, nil
+}
+
+func stateMarketBalance(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	return n.market(addr), nil
+}
+
+// stateVerifiedClientStatus returns null for addresses without DataCap, like Lotus.
+func stateVerifiedClientStatus(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	if dcap, found := n.state.VerifiedClients[n.resolve(addr).String()]; found {
+		return dcap, nil
+	}
+	return nil, nil
+}
+
+// stateVerifierStatus returns null for addresses that are not verifiers, like Lotus.
+func stateVerifierStatus(n *Node, params []json.RawMessage) (interface{}, error) {
+	addr, err := addrParam(params)
+	if err != nil {
+		return nil, err
+	}
+
+	n.lk.Lock()
+	defer n.lk.Unlock()
+	if allowance, found := n.state.Verifiers[n.resolve(addr).String()]; found {
+		return allowance, nil
+	}
+	return nil, nil
+}
@@ -0,0 +1,44 @@
+package chain
+
+import (
+	"context"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+)
+
+// ErrActorNotFound is returned, wrapped, by StateGetActorCode when no actor exists at the address yet.
+var ErrActorNotFound = xerrors.New("actor not found")
+
+// Node is the part of the Filecoin API the Handler relies on. LotusClient implements it over JSON-RPC, which also
+// works with a Lotus Gateway or a Venus node as long as they serve the methods an operation calls.
+type Node interface {
+	GetNonce(ctx context.Context, addr address.Address) (uint64, error)
+	EstimateMessageGas(ctx context.Context, maxFee abi.TokenAmount, msg *types.Message) (*types.Message, error)
+	PushMsg(ctx context.Context, signedMsg *types.SignedMessage) (cid.Cid, error)
+	PendingMessages(ctx context.Context) ([]*types.SignedMessage, error)
+	GetBalance(ctx context.Context, addr address.Address) (types.BigInt, error)
+	LookupMessage(ctx context.Context, c cid.Cid) (*types.Message, error)
+	// WaitMessage returns the return value of the message, failing when its exit code is not 0.
+	WaitMessage(ctx context.Context, c cid.Cid, confidence uint64) ([]byte, error)
+	WaitMsgLookup(ctx context.Context, c cid.Cid, confidence uint64) (*MsgLookup, error)
+	LookupID(ctx context.Context, addr address.Address) (address.Address, error)
+	GetMinerAvailableBalance(ctx context.Context, minerID address.Address) (types.BigInt, error)
+	GetPendingMsigTrxs(ctx context.Context, msigAddr address.Address) ([]MsigTransaction, error)
+	StateGetActorCode(ctx context.Context, actor address.Address) (cid.Cid, error)
+	ReadState(ctx context.Context, actor address.Address) (*ActorState, error)
+	// AccountKey returns the key address of an account actor.
+	AccountKey(ctx context.Context, addr address.Address) (address.Address, error)
+	GetMsigAvailableBalance(ctx context.Context, msigAddr address.Address) (types.BigInt, error)
+	GetMsigVesting(ctx context.Context, msigAddr address.Address) (*MsigVesting, error)
+	GetMinerInfo(ctx context.Context, minerID address.Address) (*MinerInfo, error)
+	GetMinerPower(ctx context.Context, minerID address.Address) (*MinerPower, error)
+	GetMarketBalance(ctx context.Context, addr address.Address) (*MarketBalance, error)
+	GetVerifiedClientStatus(ctx context.Context, addr address.Address) (*abi.StoragePower, error)
+	GetVerifierStatus(ctx context.Context, addr address.Address) (*abi.StoragePower, error)
+	Close()
+}
+
+var _ Node = (*LotusClient)(nil)
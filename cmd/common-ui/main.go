@@ -2,13 +2,18 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fil-assistant/common"
+	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"os"
+	"path/filepath"
 	"strings"
 )
 
@@ -26,14 +31,29 @@ func main() {
 
 	globalVar.Init(w)
 
-	tabs := make([]*container.TabItem, 7)
+	tabs := make([]*container.TabItem, 22)
 	tabs[0] = container.NewTabItem("私钥加/解密", encryption())
 	tabs[1] = container.NewTabItem("签名", sign())
-	tabs[2] = container.NewTabItem("转账", send())
-	tabs[3] = container.NewTabItem("矿工提现", withdraw())
-	tabs[4] = container.NewTabItem("发起更换owner", proposeChangeOwner())
-	tabs[5] = container.NewTabItem("确认更换owner", confirmChangeOwner())
-	tabs[6] = container.NewTabItem("更换worker", changeWorker())
+	tabs[2] = container.NewTabItem("验签", verify())
+	tabs[3] = container.NewTabItem("转账", send())
+	tabs[4] = container.NewTabItem("批量转账", batchSend())
+	tabs[5] = container.NewTabItem("矿工提现", withdraw())
+	tabs[6] = container.NewTabItem("发起更换owner", proposeChangeOwner())
+	tabs[7] = container.NewTabItem("确认更换owner", confirmChangeOwner())
+	tabs[8] = container.NewTabItem("更换worker", changeWorker())
+	tabs[9] = container.NewTabItem("离线签名", offline())
+	tabs[10] = container.NewTabItem("钱包", globalVar.WalletTab())
+	tabs[11] = container.NewTabItem("加速/取消消息", globalVar.MpoolTab())
+	tabs[12] = container.NewTabItem("历史记录", globalVar.HistoryTab())
+	tabs[13] = container.NewTabItem("地址簿", globalVar.AddressBookTab())
+	tabs[14] = container.NewTabItem("自定义消息", globalVar.CallTab(false))
+	tabs[15] = container.NewTabItem("矿工信息", minerInfo())
+	tabs[16] = container.NewTabItem("更换节点信息", globalVar.PeerInfoTab(false))
+	tabs[17] = container.NewTabItem("偿还欠费", globalVar.RepayDebtTab(false))
+	tabs[18] = container.NewTabItem("存储市场", globalVar.MarketTab(false))
+	tabs[19] = container.NewTabItem("创建矿工", globalVar.CreateMinerTab(false))
+	tabs[20] = container.NewTabItem("生成密钥", globalVar.KeyGenTab())
+	tabs[21] = container.NewTabItem("助记词", globalVar.MnemonicTab())
 
 	w.SetContent(container.NewVBox(Process(), container.NewAppTabs(tabs...)))
 	w.Resize(fyne.NewSize(800, 200))
@@ -49,8 +69,7 @@ func Process() fyne.CanvasObject {
 }
 
 func sign() fyne.CanvasObject {
-	pkEntry := widget.NewPasswordEntry()
-	pkEntry.PlaceHolder = "私钥"
+	pkEntry := globalVar.NewKeyEntry()
 
 	MsgEntry := widget.NewEntry()
 	MsgEntry.PlaceHolder = "签名内容"
@@ -67,7 +86,7 @@ func sign() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if pkEntry.Text == "" {
+		if pkEntry.Key() == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -77,7 +96,7 @@ func sign() fyne.CanvasObject {
 			return
 		}
 
-		sig, err := globalVar.Handler.Sign(strings.TrimSpace(pkEntry.Text), strings.TrimSpace(MsgEntry.Text))
+		sig, err := globalVar.Handler.Sign(pkEntry.Key(), strings.TrimSpace(MsgEntry.Text))
 		if err != nil {
 			globalVar.Msg(common.Warn, err.Error())
 		} else {
@@ -88,9 +107,50 @@ func sign() fyne.CanvasObject {
 	return container.NewVBox(pkEntry, MsgEntry, signer, result)
 }
 
+func verify() fyne.CanvasObject {
+	addrEntry := globalVar.NewAddressEntry("签名地址")
+
+	MsgEntry := widget.NewEntry()
+	MsgEntry.PlaceHolder = "签名内容"
+
+	sigEntry := widget.NewEntry()
+	sigEntry.PlaceHolder = "签名结果"
+
+	verifier := widget.NewButton("验签", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if addrEntry.Text == "" || sigEntry.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		err := globalVar.Handler.Verify(strings.TrimSpace(addrEntry.Text), strings.TrimSpace(MsgEntry.Text),
+			strings.TrimSpace(sigEntry.Text))
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+		} else {
+			globalVar.Msg(common.Info, "验签通过")
+		}
+	})
+
+	return container.NewVBox(addrEntry, MsgEntry, sigEntry, verifier)
+}
+
 func encryption() fyne.CanvasObject {
 	pkEntry := widget.NewPasswordEntry()
-	pkEntry.PlaceHolder = "私钥"
+	pkEntry.PlaceHolder = "私钥/密文"
+
+	passEntry := widget.NewPasswordEntry()
+	passEntry.PlaceHolder = "密码"
 
 	address := widget.NewEntry()
 	address.PlaceHolder = "加/解密对应地址"
@@ -109,7 +169,7 @@ func encryption() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if pkEntry.Text == "" {
+		if pkEntry.Text == "" || passEntry.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -119,7 +179,7 @@ func encryption() fyne.CanvasObject {
 			return
 		}
 
-		addr, newPk, err := globalVar.Handler.Encrypt(strings.TrimSpace(pkEntry.Text))
+		addr, newPk, err := globalVar.Handler.Encrypt(strings.TrimSpace(pkEntry.Text), passEntry.Text)
 		if err != nil {
 			globalVar.Msg(common.Warn, err.Error())
 		} else {
@@ -135,7 +195,7 @@ func encryption() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if pkEntry.Text == "" {
+		if pkEntry.Text == "" || passEntry.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -145,7 +205,7 @@ func encryption() fyne.CanvasObject {
 			return
 		}
 
-		addr, rawPk, err := globalVar.Handler.Decrypt(strings.TrimSpace(pkEntry.Text))
+		addr, rawPk, err := globalVar.Handler.Decrypt(strings.TrimSpace(pkEntry.Text), passEntry.Text)
 		if err != nil {
 			globalVar.Msg(common.Warn, err.Error())
 		} else {
@@ -154,19 +214,43 @@ func encryption() fyne.CanvasObject {
 		}
 	})
 
-	mid := container.NewGridWithColumns(2, encrypt, decrypt)
-	return container.NewVBox(pkEntry, mid, address, private)
+	migrate := widget.NewButton("迁移旧密文", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if pkEntry.Text == "" || passEntry.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		addr, newPk, err := globalVar.Handler.MigrateKey(strings.TrimSpace(pkEntry.Text), passEntry.Text)
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+		} else {
+			ad.Set(addr)
+			pri.Set(newPk)
+		}
+	})
+
+	mid := container.NewGridWithColumns(3, encrypt, decrypt, migrate)
+	return container.NewVBox(pkEntry, passEntry, mid, address, private)
 }
 
 func send() fyne.CanvasObject {
-	toEntry := widget.NewEntry()
-	toEntry.PlaceHolder = "收款地址"
+	toEntry := globalVar.NewAddressEntry("收款地址")
 
 	amountEntry := widget.NewEntry()
 	amountEntry.PlaceHolder = "金额"
 
-	pkEntry := widget.NewPasswordEntry()
-	pkEntry.PlaceHolder = "私钥"
+	pkEntry := globalVar.NewKeyEntry()
 
 	confirm := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
@@ -175,7 +259,7 @@ func send() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if toEntry.Text == "" || amountEntry.Text == "" || pkEntry.Text == "" {
+		if toEntry.Text == "" || amountEntry.Text == "" || pkEntry.Key() == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -187,10 +271,10 @@ func send() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.Send(context.TODO(), strings.TrimSpace(pkEntry.Text), strings.TrimSpace(toEntry.Text),
+		err := globalVar.Handler.Send(context.TODO(), pkEntry.Key(), strings.TrimSpace(toEntry.Text),
 			strings.TrimSpace(amountEntry.Text), nil)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, "转账成功")
 			globalVar.Process.Set(1)
@@ -201,17 +285,162 @@ func send() fyne.CanvasObject {
 	return container.NewVBox(pkEntry, toEntry, bottom)
 }
 
+func batchSend() fyne.CanvasObject {
+	pkEntry := globalVar.NewKeyEntry()
+
+	pathEntry := widget.NewEntry()
+	pathEntry.PlaceHolder = "CSV文件(收款地址,金额,备注)"
+
+	choose := widget.NewButton("选择文件", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err == nil && reader != nil {
+				pathEntry.SetText(reader.URI().Path())
+				reader.Close()
+			}
+		}, globalVar.Window)
+	})
+
+	confirm := widget.NewButton("提交", func() {
+		if pathEntry.Text == "" || pkEntry.Key() == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		path := strings.TrimSpace(pathEntry.Text)
+		file, err := os.Open(path)
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+			return
+		}
+		items, err := common.ParseBatchCSV(file)
+		file.Close()
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+			return
+		}
+		total, err := common.BatchTotal(items)
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+			return
+		}
+
+		var text strings.Builder
+		for i, item := range items {
+			if i == 10 {
+				fmt.Fprintf(&text, "...\n")
+				break
+			}
+			fmt.Fprintf(&text, "%s  %s FIL\n", globalVar.Handler.Describe(item.To), item.Amount)
+		}
+		fmt.Fprintf(&text, "共%d笔转账, 合计%s, 确认发送?", len(items), total)
+		dialog.ShowConfirm("批量转账", text.String(), func(ok bool) {
+			if ok {
+				sendBatch(pkEntry.Key(), path, items)
+			}
+		}, globalVar.Window)
+	})
+
+	bottom := container.NewGridWithColumns(2, choose, confirm)
+	return container.NewVBox(pkEntry, pathEntry, bottom)
+}
+
+func sendBatch(pk, path string, items []common.BatchItem) {
+	if !globalVar.Locker.TryLock(0) {
+		globalVar.Msg(common.Warn, "请稍后再试")
+		return
+	}
+	defer globalVar.Locker.Unlock()
+
+	if globalVar.Handler == nil {
+		globalVar.Msg(common.Error, "初始化异常")
+		return
+	}
+
+	globalVar.Process.Set(0)
+
+	results, err := globalVar.Handler.SendBatch(context.TODO(), pk, items)
+	if err != nil {
+		globalVar.Fail(err)
+		return
+	}
+
+	resultPath := strings.TrimSuffix(path, filepath.Ext(path)) + "_结果.csv"
+	file, err := os.Create(resultPath)
+	if err != nil {
+		globalVar.Msg(common.Warn, err.Error())
+		return
+	}
+	defer file.Close()
+	if err = common.WriteBatchResult(file, results); err != nil {
+		globalVar.Msg(common.Warn, err.Error())
+		return
+	}
+
+	ok := 0
+	for _, res := range results {
+		if res.ExitCode == 0 {
+			ok++
+		}
+	}
+	globalVar.Msg(common.Info, fmt.Sprintf("成功%d笔, 失败%d笔, 结果已写入%s", ok, len(results) - ok, resultPath))
+	globalVar.Process.Set(1)
+}
+
+func minerInfo() fyne.CanvasObject {
+	minerEntry := globalVar.NewAddressEntry("矿工号")
+
+	result := widget.NewMultiLineEntry()
+	result.PlaceHolder = "矿工信息"
+	res := binding.NewString()
+	result.Bind(res)
+
+	query := widget.NewButton("查询", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		if minerEntry.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		globalVar.Process.Set(0)
+
+		overview, err := globalVar.Handler.GetMinerOverview(context.TODO(), strings.TrimSpace(minerEntry.Text))
+		if err != nil {
+			globalVar.Fail(err)
+			return
+		}
+		val, err := json.MarshalIndent(overview, "", "\t")
+		if err != nil {
+			globalVar.Fail(err)
+			return
+		}
+		res.Set(string(val))
+		globalVar.Process.Set(1)
+	})
+
+	return container.NewBorder(container.NewGridWithColumns(2, minerEntry, query), nil, nil, nil, result)
+}
+
 func withdraw() fyne.CanvasObject {
 	// 初始化输入框
-	pkEntry := widget.NewPasswordEntry()
-	pkEntry.PlaceHolder = "私钥"
+	pkEntry := globalVar.NewKeyEntry()
 
-	minerEntry := widget.NewEntry()
-	minerEntry.PlaceHolder = "矿工号"
+	minerEntry := globalVar.NewAddressEntry("矿工号")
 
 	amountEntry := widget.NewEntry()
 	amountEntry.PlaceHolder = "金额"
 
+	allCheck := globalVar.NewWithdrawAllCheck(amountEntry)
+
 	confirm := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
 			globalVar.Msg(common.Warn, "请稍后再试")
@@ -219,7 +448,7 @@ func withdraw() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if minerEntry.Text == "" || amountEntry.Text == "" || pkEntry.Text == "" {
+		if minerEntry.Text == "" || (amountEntry.Text == "" && !allCheck.Checked) || pkEntry.Key() == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -231,29 +460,31 @@ func withdraw() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.Withdraw(context.TODO(), strings.TrimSpace(pkEntry.Text),
-			strings.TrimSpace(minerEntry.Text), strings.TrimSpace(amountEntry.Text), nil)
+		amount := strings.TrimSpace(amountEntry.Text)
+		if allCheck.Checked {
+			amount = ""
+		}
+		err := globalVar.Handler.Withdraw(context.TODO(), pkEntry.Key(),
+			strings.TrimSpace(minerEntry.Text), amount, nil)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, "提现成功")
 			globalVar.Process.Set(1)
 		}
 	})
 
-	top := container.NewGridWithColumns(2, minerEntry, amountEntry)
-	return container.NewVBox(pkEntry, top, confirm)
+	top := container.NewGridWithColumns(3, minerEntry, amountEntry, allCheck)
+	bottom := container.NewGridWithColumns(2, globalVar.NewPreflightButton(minerEntry, amountEntry, allCheck), confirm)
+	return container.NewVBox(pkEntry, top, bottom)
 }
 
 func proposeChangeOwner() fyne.CanvasObject {
-	minerEntry := widget.NewEntry()
-	minerEntry.PlaceHolder = "矿工号"
+	minerEntry := globalVar.NewAddressEntry("矿工号")
 
-	pkEntry := widget.NewPasswordEntry()
-	pkEntry.PlaceHolder = "私钥"
+	pkEntry := globalVar.NewKeyEntry()
 
-	newOwner := widget.NewEntry()
-	newOwner.PlaceHolder = "新owner地址"
+	newOwner := globalVar.NewAddressEntry("新owner地址")
 
 	submit := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
@@ -262,7 +493,7 @@ func proposeChangeOwner() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if minerEntry.Text == "" || pkEntry.Text == "" || newOwner.Text == "" {
+		if minerEntry.Text == "" || pkEntry.Key() == "" || newOwner.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -274,10 +505,10 @@ func proposeChangeOwner() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.ChangeOwner1(context.TODO(), strings.TrimSpace(pkEntry.Text),
+		err := globalVar.Handler.ChangeOwner1(context.TODO(), pkEntry.Key(),
 			strings.TrimSpace(newOwner.Text), strings.TrimSpace(minerEntry.Text), nil)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, "发起更换owner完成")
 			globalVar.Process.Set(1)
@@ -288,11 +519,9 @@ func proposeChangeOwner() fyne.CanvasObject {
 }
 
 func confirmChangeOwner() fyne.CanvasObject {
-	minerEntry := widget.NewEntry()
-	minerEntry.PlaceHolder = "矿工号"
+	minerEntry := globalVar.NewAddressEntry("矿工号")
 
-	pkEntry := widget.NewPasswordEntry()
-	pkEntry.PlaceHolder = "私钥"
+	pkEntry := globalVar.NewKeyEntry()
 
 	submit := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
@@ -301,7 +530,7 @@ func confirmChangeOwner() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if minerEntry.Text == "" || pkEntry.Text == "" {
+		if minerEntry.Text == "" || pkEntry.Key() == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -313,10 +542,10 @@ func confirmChangeOwner() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.ChangeOwner2(context.TODO(), strings.TrimSpace(pkEntry.Text),
+		err := globalVar.Handler.ChangeOwner2(context.TODO(), pkEntry.Key(),
 			strings.TrimSpace(minerEntry.Text), nil)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, "确认更换owner完成")
 			globalVar.Process.Set(1)
@@ -327,14 +556,11 @@ func confirmChangeOwner() fyne.CanvasObject {
 }
 
 func changeWorker() fyne.CanvasObject {
-	minerEntry := widget.NewEntry()
-	minerEntry.PlaceHolder = "矿工号"
+	minerEntry := globalVar.NewAddressEntry("矿工号")
 
-	pkEntry := widget.NewPasswordEntry()
-	pkEntry.PlaceHolder = "私钥"
+	pkEntry := globalVar.NewKeyEntry()
 
-	workerEntry := widget.NewEntry()
-	workerEntry.PlaceHolder = "worker地址"
+	workerEntry := globalVar.NewAddressEntry("worker地址")
 
 	controlsEntry := widget.NewMultiLineEntry()
 	controlsEntry.PlaceHolder = "controls地址"
@@ -351,7 +577,7 @@ func changeWorker() fyne.CanvasObject {
 			return
 		}
 
-		if minerEntry.Text == "" || workerEntry.Text == "" || pkEntry.Text == "" {
+		if minerEntry.Text == "" || workerEntry.Text == "" || pkEntry.Key() == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -370,10 +596,10 @@ func changeWorker() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.ProposeChangeWorker(context.TODO(), strings.TrimSpace(pkEntry.Text),
+		err := globalVar.Handler.ProposeChangeWorker(context.TODO(), pkEntry.Key(),
 			strings.TrimSpace(minerEntry.Text), strings.TrimSpace(workerEntry.Text), newControls, nil)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, "完成更换worker第一步")
 			globalVar.Process.Set(1)
@@ -387,7 +613,7 @@ func changeWorker() fyne.CanvasObject {
 		}
 		defer globalVar.Locker.Unlock()
 
-		if minerEntry.Text == "" || workerEntry.Text == "" || pkEntry.Text == "" {
+		if minerEntry.Text == "" || workerEntry.Text == "" || pkEntry.Key() == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -399,10 +625,10 @@ func changeWorker() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.ConfirmChangeWorker(context.TODO(), strings.TrimSpace(pkEntry.Text),
+		err := globalVar.Handler.ConfirmChangeWorker(context.TODO(), pkEntry.Key(),
 			strings.TrimSpace(minerEntry.Text), nil)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, "完成更换worker第二步")
 			globalVar.Process.Set(1)
@@ -411,5 +637,75 @@ func changeWorker() fyne.CanvasObject {
 
 	bottomRight := container.NewGridWithColumns(2, propose, confirm)
 	bottom := container.NewGridWithColumns(2, minerEntry, bottomRight)
-	return container.NewVBox(pkEntry, workerEntry, controlsEntry, bottom)
-}
\ No newline at end of file
+	return container.NewVBox(pkEntry, workerEntry, controlsEntry, globalVar.NewAddressPicker(controlsEntry), bottom)
+}
+
+func offline() fyne.CanvasObject {
+	pkEntry := globalVar.NewKeyEntry()
+
+	unsignedEntry := widget.NewMultiLineEntry()
+	unsignedEntry.PlaceHolder = "待签名消息"
+
+	signedEntry := widget.NewMultiLineEntry()
+	signedEntry.PlaceHolder = "已签名消息"
+
+	signer := widget.NewButton("离线签名", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if pkEntry.Key() == "" || unsignedEntry.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		signed, err := globalVar.Handler.SignMessage(pkEntry.Key(), []byte(unsignedEntry.Text))
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+		} else {
+			signedEntry.SetText(string(signed))
+		}
+	})
+
+	broadcast := widget.NewButton("广播", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if signedEntry.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		globalVar.Process.Set(0)
+
+		c, ret, err := globalVar.Handler.Broadcast(context.TODO(), []byte(signedEntry.Text))
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+			return
+		}
+		val, err := json.Marshal(ret)
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+		} else {
+			globalVar.Msg(common.Info, fmt.Sprintf("广播完成: %s\n返回值: %s", c, val))
+			globalVar.Process.Set(1)
+		}
+	})
+
+	return container.NewVBox(pkEntry, unsignedEntry, signer, signedEntry, broadcast)
+}
@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/common"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func init() {
+	register(
+		&command{Name: "address-list", Usage: "list the address book", Run: addressList},
+		&command{Name: "address-set", Usage: "add or update an address of the address book", Run: addressSet},
+		&command{Name: "address-delete", Usage: "delete an address from the address book", Run: addressDelete},
+		&command{Name: "address-import", Usage: "import addresses from a CSV or JSON file", Run: addressImport},
+		&command{Name: "address-export", Usage: "export the address book to a CSV or JSON file", Run: addressExport},
+	)
+}
+
+func addressBook(ctx context.Context) (*common.AddressBook, error) {
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.AddressBook(), nil
+}
+
+// fileFormat returns format, or guesses it from the extension of path when empty.
+func fileFormat(format, path string) (string, error) {
+	if format == "" {
+		format = "csv"
+		if strings.EqualFold(filepath.Ext(path), ".json") {
+			format = "json"
+		}
+	}
+	if format != "json" && format != "csv" {
+		return "", usagef("unknown format %q", format)
+	}
+	return format, nil
+}
+
+func addressList(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("address-list")
+	tag := fs.String("tag", "", "only list the addresses with this tag")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	book, err := addressBook(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]common.AddressBookEntry, 0)
+	for _, entry := range book.List() {
+		if *tag == "" || entry.Tag == *tag {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+func addressSet(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("address-set")
+	addr := fs.String("address", "", "address")
+	label := fs.String("label", "", "label")
+	tag := fs.String("tag", "", "type of the address: "+strings.Join(common.AddressTags, ", "))
+	note := fs.String("note", "", "note")
+	if err := parse(fs, args, "address", "label"); err != nil {
+		return nil, err
+	}
+	book, err := addressBook(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	entry := common.AddressBookEntry{Address: *addr, Label: *label, Tag: *tag, Note: *note}
+	if err = book.Set(entry); err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "ok", "address": *addr}, nil
+}
+
+func addressDelete(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("address-delete")
+	addr := fs.String("address", "", "address")
+	if err := parse(fs, args, "address"); err != nil {
+		return nil, err
+	}
+	book, err := addressBook(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = book.Delete(*addr); err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "ok", "address": *addr}, nil
+}
+
+func addressImport(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("address-import")
+	in := fs.String("in", "", "file of address,label[,tag[,note]] lines, or a JSON array")
+	format := fs.String("format", "", "csv or json, guessed from the file extension by default")
+	if err := parse(fs, args, "in"); err != nil {
+		return nil, err
+	}
+	f, err := fileFormat(*format, *in)
+	if err != nil {
+		return nil, err
+	}
+	book, err := addressBook(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	var entries []common.AddressBookEntry
+	if f == "json" {
+		entries, err = common.ReadAddressJSON(file)
+	} else {
+		entries, err = common.ReadAddressCSV(file)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = book.Import(entries); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"status": "ok", "imported": len(entries)}, nil
+}
+
+func addressExport(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("address-export")
+	out := fs.String("out", "", "file to write")
+	format := fs.String("format", "", "csv or json, guessed from the file extension by default")
+	if err := parse(fs, args, "out"); err != nil {
+		return nil, err
+	}
+	f, err := fileFormat(*format, *out)
+	if err != nil {
+		return nil, err
+	}
+	book, err := addressBook(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	entries := book.List()
+	if f == "json" {
+		err = common.WriteAddressJSON(file, entries)
+	} else {
+		err = common.WriteAddressCSV(file, entries)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"status": "ok", "file": *out, "exported": len(entries)}, nil
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/common"
+	"os"
+)
+
+func init() {
+	register(
+		&command{Name: "send-batch", Usage: "send the transfers listed in a CSV file with consecutive nonces", Run: sendBatch},
+	)
+}
+
+func sendBatch(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("send-batch")
+	key := addKeyFlags(fs)
+	in := fs.String("in", "", "CSV file of to,amount[,memo] lines")
+	out := fs.String("result", "result.csv", "CSV file the cids and exit codes are written to")
+	if err := parse(fs, args, "in"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+
+	file, err := os.Open(*in)
+	if err != nil {
+		return nil, err
+	}
+	items, err := common.ParseBatchCSV(file)
+	file.Close()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	results, err := h.SendBatch(ctx, pk, items)
+	if err != nil {
+		return nil, err
+	}
+	file, err = os.Create(*out)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if err = common.WriteBatchResult(file, results); err != nil {
+		return nil, err
+	}
+
+	failed := 0
+	for _, res := range results {
+		if res.ExitCode != 0 || res.Error != "" {
+			failed++
+		}
+	}
+	if failed != 0 {
+		// scripts tell a partial batch from a full one by the exit code
+		return map[string]interface{}{"status": "failed", "file": *out, "sent": len(results) - failed, "failed": failed},
+			partialf("%d of %d transfers failed, see %s", failed, len(results), *out)
+	}
+	return map[string]interface{}{"status": "ok", "file": *out, "sent": len(results), "failed": 0}, nil
+}
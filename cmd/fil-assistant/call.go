@@ -0,0 +1,91 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"io/ioutil"
+)
+
+func init() {
+	register(
+		&command{Name: "actor-methods", Usage: "list the methods of a builtin actor with their params as JSON", Run: actorMethods},
+		&command{Name: "call", Usage: "call any method of a builtin actor, directly or as a multisig proposal", Run: call},
+		&command{Name: "propose-call", Usage: "propose calling any method of a builtin actor from a multisig", Run: proposeCall},
+	)
+}
+
+func actorMethods(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("actor-methods")
+	to := fs.String("to", "", "actor address")
+	if err := parse(fs, args, "to"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	methods, err := h.ActorMethods(ctx, *to)
+	if err != nil {
+		return nil, err
+	}
+	res := make([]map[string]interface{}, 0, len(methods))
+	for _, method := range methods {
+		res = append(res, map[string]interface{}{
+			"num":    method.Num,
+			"name":   method.Name,
+			"params": json.RawMessage(method.Params),
+		})
+	}
+	return res, nil
+}
+
+func call(ctx context.Context, args []string) (interface{}, error) {
+	return runCall(ctx, "call", args)
+}
+
+func proposeCall(ctx context.Context, args []string) (interface{}, error) {
+	return runCall(ctx, "propose-call", args, "msig")
+}
+
+func runCall(ctx context.Context, name string, args []string, required ...string) (interface{}, error) {
+	fs := newFlagSet(name)
+	key := addKeyFlags(fs)
+	msig := fs.String("msig", "", "multisig address, the call is sent directly when empty")
+	to := fs.String("to", "", "actor address")
+	method := fs.String("method", "", "method name or number, see actor-methods")
+	params := fs.String("params", "", "params as JSON, see actor-methods")
+	paramsFile := fs.String("params-file", "", "file containing the params as JSON")
+	amount := fs.String("amount", "0", "value sent with the call")
+	if err := parse(fs, args, append(required, "to", "method")...); err != nil {
+		return nil, err
+	}
+	if *paramsFile != "" {
+		if *params != "" {
+			return nil, usagef("%s: -params and -params-file are exclusive", name)
+		}
+		val, err := ioutil.ReadFile(*paramsFile)
+		if err != nil {
+			return nil, err
+		}
+		*params = string(val)
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	ret, err := h.Call(ctx, pk, *to, *method, *params, *amount, proposal)
+	if err != nil {
+		return nil, err
+	}
+	if proposal != nil || ret == nil {
+		return done(proposal), nil
+	}
+	return map[string]interface{}{"status": "ok", "return": ret}, nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/common"
+	"os"
+)
+
+func init() {
+	register(
+		&command{Name: "history", Usage: "search the messages pushed by the assistants, optionally exporting them", Run: history},
+	)
+}
+
+func history(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("history")
+	text := fs.String("q", "", "text to match in the operation, addresses, cid or TxnID")
+	since := fs.String("since", "", "first day, as 2006-01-02")
+	until := fs.String("until", "", "last day, as 2006-01-02")
+	out := fs.String("export", "", "write the records to this file instead of stdout")
+	format := fs.String("format", "json", "export format, json or csv")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if *format != "json" && *format != "csv" {
+		return nil, usagef("history: unknown format %q", *format)
+	}
+	q, err := common.NewHistoryQuery(*text, *since, *until)
+	if err != nil {
+		return nil, usagef("history: %s", err)
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	recs, err := h.History().Search(q)
+	if err != nil {
+		return nil, err
+	}
+	if *out == "" {
+		return recs, nil
+	}
+
+	file, err := os.Create(*out)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if *format == "csv" {
+		err = common.WriteHistoryCSV(file, recs)
+	} else {
+		err = common.WriteHistoryJSON(file, recs)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"status": "ok", "file": *out, "records": len(recs)}, nil
+}
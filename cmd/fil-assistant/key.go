@@ -0,0 +1,261 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fil-assistant/common"
+	"fil-assistant/lib"
+	"io/ioutil"
+	"strings"
+)
+
+func init() {
+	register(
+		&command{Name: "encrypt", Usage: "encrypt a private key into a passphrase protected keystore", Run: encrypt},
+		&command{Name: "decrypt", Usage: "decrypt a keystore back to the hex encoded private key", Run: decrypt},
+		&command{Name: "migrate-key", Usage: "convert a key encrypted with the legacy AESKey into a keystore", Run: migrateKey},
+		&command{Name: "sign", Usage: "sign a hex encoded message", Run: sign},
+		&command{Name: "verify", Usage: "verify a signature made by sign", Run: verify},
+		&command{Name: "keygen", Usage: "generate keys without the wallet, optionally encrypted or imported", Run: keygen},
+		&command{Name: "mnemonic-new", Usage: "generate a BIP39 mnemonic to derive secp256k1 keys from", Run: mnemonicNew},
+		&command{Name: "mnemonic-derive", Usage: "derive the f1 addresses of a mnemonic along m/44'/461'/0'/0/i", Run: mnemonicDerive},
+	)
+}
+
+func encrypt(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("encrypt")
+	key := addKeyFlags(fs)
+	pass := addPassFlags(fs)
+	out := fs.String("keystore", "", "also write the keystore to this file")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := pass.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, ks, err := h.Encrypt(pk, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if *out != "" {
+		if err = ioutil.WriteFile(*out, []byte(ks), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]string{"address": addr, "keystore": ks}, nil
+}
+
+func decrypt(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("decrypt")
+	in := fs.String("keystore", "", "keystore file written by encrypt")
+	pass := addPassFlags(fs)
+	if err := parse(fs, args, "keystore"); err != nil {
+		return nil, err
+	}
+	ks, err := ioutil.ReadFile(*in)
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := pass.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, rawPk, err := h.Decrypt(string(ks), passphrase)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"address": addr, "key": rawPk}, nil
+}
+
+func migrateKey(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("migrate-key")
+	key := addKeyFlags(fs)
+	pass := addPassFlags(fs)
+	out := fs.String("keystore", "", "also write the keystore to this file")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	legacy, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	passphrase, err := pass.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, ks, err := h.MigrateKey(legacy, passphrase)
+	if err != nil {
+		return nil, err
+	}
+	if *out != "" {
+		if err = ioutil.WriteFile(*out, []byte(ks), 0600); err != nil {
+			return nil, err
+		}
+	}
+	return map[string]string{"address": addr, "keystore": ks}, nil
+}
+
+func sign(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("sign")
+	key := addKeyFlags(fs)
+	msg := fs.String("msg", "", "hex encoded message to sign")
+	if err := parse(fs, args, "msg"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sig, err := h.Sign(pk, *msg)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"signature": sig}, nil
+}
+
+func verify(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("verify")
+	addr := fs.String("address", "", "address of the signer")
+	msg := fs.String("msg", "", "hex encoded message")
+	sig := fs.String("sig", "", "hex encoded signature, as printed by sign")
+	if err := parse(fs, args, "address", "sig"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = h.Verify(*addr, *msg, *sig); err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "valid"}, nil
+}
+
+func keygen(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("keygen")
+	pass := addPassFlags(fs)
+	keyType := fs.String("type", "secp256k1", "key type, secp256k1 or bls")
+	count := fs.Int("count", 1, "number of keys")
+	encrypted := fs.Bool("encrypt", false, "export keystores encrypted with the passphrase instead of hex keys")
+	toWallet := fs.Bool("wallet", false, "also import the keys into the local wallet, unlocked with the passphrase")
+	label := fs.String("label", "", "wallet label, numbered when more than one key is generated")
+	out := fs.String("out", "", "write the keys to this file and only print the addresses")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	var passphrase string
+	if *encrypted || *toWallet {
+		var err error
+		if passphrase, err = pass.get(); err != nil {
+			return nil, err
+		}
+	}
+	// key generation is cold storage work, only the wallet is opened when the keys go into it
+	var w *lib.Wallet
+	if *toWallet {
+		var err error
+		if w, err = getWallet(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	if !*encrypted {
+		passphrase = ""
+	}
+
+	keys, err := common.GenerateKeys(w, *keyType, *count, passphrase, *toWallet, *label, progress)
+	if err != nil {
+		return nil, err
+	}
+	if *out == "" {
+		return keys, nil
+	}
+
+	buf := new(bytes.Buffer)
+	if err = common.WriteKeysJSON(buf, keys); err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(*out, buf.Bytes(), 0600); err != nil {
+		return nil, err
+	}
+	addrs := make([]string, 0, len(keys))
+	for _, key := range keys {
+		addrs = append(addrs, key.Address)
+	}
+	return map[string]interface{}{"status": "ok", "file": *out, "addresses": addrs}, nil
+}
+
+func mnemonicNew(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("mnemonic-new")
+	words := fs.Int("words", 24, "number of words, 12, 15, 18, 21 or 24")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	mnemonic, err := common.GenerateMnemonic(*words)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"mnemonic": mnemonic}, nil
+}
+
+func mnemonicDerive(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("mnemonic-derive")
+	mnemonic := fs.String("mnemonic", "", "BIP39 mnemonic")
+	mnemonicFile := fs.String("mnemonic-file", "", "file containing the BIP39 mnemonic")
+	start := fs.Int("start", 0, "index of the first key")
+	count := fs.Int("count", 1, "number of keys")
+	toWallet := fs.Bool("wallet", false, "also import the keys into the local wallet, unlocked with the passphrase")
+	label := fs.String("label", "", "wallet label, suffixed with the key index")
+	pass := addPassFlags(fs)
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	if *mnemonicFile != "" {
+		val, err := ioutil.ReadFile(*mnemonicFile)
+		if err != nil {
+			return nil, err
+		}
+		*mnemonic = string(val)
+	}
+	if strings.TrimSpace(*mnemonic) == "" {
+		return nil, usagef("mnemonic-derive: -mnemonic or -mnemonic-file is required")
+	}
+	// deriving is cold storage work, only the wallet is opened when the keys go into it
+	var w *lib.Wallet
+	if *toWallet {
+		passphrase, err := pass.get()
+		if err != nil {
+			return nil, err
+		}
+		if w, err = getWallet(passphrase); err != nil {
+			return nil, err
+		}
+	}
+
+	return common.DeriveKeys(w, *mnemonic, *start, *count, *toWallet, *label, progress)
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fil-assistant/common"
+	"fil-assistant/lib"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+)
+
+const (
+	exitOK = iota
+	exitFailed
+	exitUsage
+)
+
+const (
+	keyEnv        = "FIL_ASSISTANT_KEY"
+	passphraseEnv = "FIL_ASSISTANT_PASSPHRASE"
+)
+
+type command struct {
+	Name  string
+	Usage string
+	Run   func(ctx context.Context, args []string) (interface{}, error)
+}
+
+// commands is filled by the init functions of the files next to main.go, one file per group of operations.
+var commands = map[string]*command{}
+
+func register(cmds ...*command) {
+	for _, cmd := range cmds {
+		commands[cmd.Name] = cmd
+	}
+}
+
+var (
+	configPath   string
+	verbose      bool
+	handler      *common.Handler
+	unsignedPath string
+)
+
+type usageError struct {
+	msg string
+}
+
+func (e *usageError) Error() string {
+	return e.msg
+}
+
+func usagef(format string, args ...interface{}) error {
+	return &usageError{msg: fmt.Sprintf(format, args...)}
+}
+
+// partialError fails a command whose result is still printed, such as a batch of which some transfers failed.
+type partialError struct {
+	msg string
+}
+
+func (e *partialError) Error() string {
+	return e.msg
+}
+
+func partialf(format string, args ...interface{}) error {
+	return &partialError{msg: fmt.Sprintf(format, args...)}
+}
+
+func main() {
+	os.Exit(run(os.Args[1:]))
+}
+
+func run(args []string) int {
+	fs := flag.NewFlagSet("fil-assistant", flag.ContinueOnError)
+	fs.StringVar(&configPath, "config", common.DefaultConfigPath, "path of config.toml")
+	fs.BoolVar(&verbose, "v", false, "print progress to stderr")
+	fs.Usage = func() { printUsage(fs) }
+	if err := fs.Parse(args); err != nil {
+		return exitUsage
+	}
+
+	if fs.NArg() == 0 {
+		printUsage(fs)
+		return exitUsage
+	}
+	cmd, found := commands[fs.Arg(0)]
+	if !found {
+		return fail(usagef("unknown command %q", fs.Arg(0)))
+	}
+
+	defer func() {
+		if handler != nil {
+			handler.Close()
+		}
+	}()
+
+	res, err := cmd.Run(context.Background(), fs.Args()[1:])
+	var prepared *common.PreparedError
+	if errors.As(err, &prepared) {
+		res, err = writePrepared(prepared)
+	}
+	var partial *partialError
+	if errors.As(err, &partial) {
+		if oerr := output(res); oerr != nil {
+			return fail(oerr)
+		}
+	}
+	if err != nil {
+		return fail(err)
+	}
+	if err = output(res); err != nil {
+		return fail(err)
+	}
+	return exitOK
+}
+
+func printUsage(fs *flag.FlagSet) {
+	out := fs.Output()
+	fmt.Fprintln(out, "Usage: fil-assistant [-config path] [-v] <command> [flags]")
+	fmt.Fprintln(out, "\nCommands:")
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		fmt.Fprintf(out, "  %-22s %s\n", name, commands[name].Usage)
+	}
+	fmt.Fprintln(out, "\nGlobal flags:")
+	fs.PrintDefaults()
+	fmt.Fprintln(out, "\nRun 'fil-assistant <command> -h' for the flags of a command.")
+}
+
+func output(res interface{}) error {
+	enc := json.NewEncoder(os.Stdout)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(res)
+}
+
+func fail(err error) int {
+	if errors.Is(err, flag.ErrHelp) {
+		return exitUsage
+	}
+
+	val, _ := json.Marshal(map[string]string{"error": err.Error()})
+	fmt.Fprintln(os.Stderr, string(val))
+
+	var ue *usageError
+	if errors.As(err, &ue) {
+		return exitUsage
+	}
+	return exitFailed
+}
+
+// getHandler connects to the node configured in config.toml, the same way the UIs do on startup. The wallet is
+// unlocked with FIL_ASSISTANT_PASSPHRASE when it is set.
+func getHandler(ctx context.Context) (*common.Handler, error) {
+	if handler != nil {
+		return handler, nil
+	}
+
+	h, err := common.LoadHandler(ctx, configPath, progress)
+	if err != nil {
+		return nil, err
+	}
+	handler = h
+	if passphrase := os.Getenv(passphraseEnv); passphrase != "" {
+		if err = h.UnlockWallet(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	return h, nil
+}
+
+// getWallet opens only the wallet of config.toml, for the commands which run on an offline machine without a node.
+// It is unlocked with passphrase when it is set.
+func getWallet(passphrase string) (*lib.Wallet, error) {
+	w, err := common.OpenWallet(configPath)
+	if err != nil {
+		return nil, err
+	}
+	if passphrase != "" {
+		if err = w.Unlock(passphrase); err != nil {
+			return nil, err
+		}
+	}
+	return w, nil
+}
+
+func progress(p float64) error {
+	if verbose {
+		fmt.Fprintf(os.Stderr, "progress %3.0f%%\n", p*100)
+	}
+	return nil
+}
+
+func newFlagSet(name string) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.SetOutput(os.Stderr)
+	return fs
+}
+
+// parse parses args and checks that all required flags are set.
+func parse(fs *flag.FlagSet, args []string, required ...string) error {
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 0 {
+		return usagef("%s: unexpected arguments %v", fs.Name(), fs.Args())
+	}
+
+	set := make(map[string]bool)
+	fs.Visit(func(f *flag.Flag) {
+		set[f.Name] = true
+	})
+	for _, name := range required {
+		if !set[name] {
+			return usagef("%s: flag -%s is required", fs.Name(), name)
+		}
+	}
+	return nil
+}
+
+type keyFlags struct {
+	key     *string
+	keyFile *string
+	from    *string
+}
+
+// addKeyFlags registers the flags of the private key used to sign the message. The key can also be
+// passed through the FIL_ASSISTANT_KEY environment variable so that it does not show up in ps. With
+// -from the message is signed by the wallet key of that address, or only prepared and written to -out
+// for offline signing when the wallet does not hold it or is locked.
+func addKeyFlags(fs *flag.FlagSet) *keyFlags {
+	fs.StringVar(&unsignedPath, "out", "unsigned.json", "file the unsigned message is written to when -from is used")
+	return &keyFlags{
+		key:     fs.String("key", "", "hex encoded private key, as accepted by the UIs (env "+keyEnv+")"),
+		keyFile: fs.String("key-file", "", "file containing the hex encoded private key"),
+		from:    fs.String("from", "", "sender address, signed by the wallet or prepared for offline signing"),
+	}
+}
+
+func (k *keyFlags) get() (string, error) {
+	switch {
+	case *k.from != "":
+		return *k.from, nil
+	case *k.key != "":
+		return *k.key, nil
+	case *k.keyFile != "":
+		val, err := ioutil.ReadFile(*k.keyFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimSpace(string(val)), nil
+	case os.Getenv(keyEnv) != "":
+		return strings.TrimSpace(os.Getenv(keyEnv)), nil
+	default:
+		return "", usagef("private key is required, use -key, -key-file or %s", keyEnv)
+	}
+}
+
+type passFlags struct {
+	passphrase     *string
+	passphraseFile *string
+}
+
+func addPassFlags(fs *flag.FlagSet) *passFlags {
+	return &passFlags{
+		passphrase:     fs.String("passphrase", "", "keystore passphrase (env "+passphraseEnv+")"),
+		passphraseFile: fs.String("passphrase-file", "", "file containing the keystore passphrase"),
+	}
+}
+
+func (p *passFlags) get() (string, error) {
+	switch {
+	case *p.passphrase != "":
+		return *p.passphrase, nil
+	case *p.passphraseFile != "":
+		val, err := ioutil.ReadFile(*p.passphraseFile)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(string(val), "\r\n"), nil
+	case os.Getenv(passphraseEnv) != "":
+		return os.Getenv(passphraseEnv), nil
+	default:
+		return "", usagef("passphrase is required, use -passphrase, -passphrase-file or %s", passphraseEnv)
+	}
+}
+
+func writePrepared(prepared *common.PreparedError) (interface{}, error) {
+	val, err := json.MarshalIndent(prepared.Message, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(unsignedPath, val, 0600); err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"status": "prepared", "file": unsignedPath, "message": prepared.Message}, nil
+}
+
+// splitList splits a comma separated flag value, dropping empty items.
+func splitList(val string) []string {
+	items := strings.Split(val, ",")
+	res := make([]string, 0, len(items))
+	for _, item := range items {
+		if item = strings.TrimSpace(item); item != "" {
+			res = append(res, item)
+		}
+	}
+	return res
+}
+
+// proposalFor returns the multisig proposal to send the operation through, or nil for a direct message.
+func proposalFor(msig string) *common.Proposal {
+	if msig == "" {
+		return nil
+	}
+	return &common.Proposal{Msig: msig}
+}
+
+func done(proposal *common.Proposal) interface{} {
+	if proposal == nil {
+		return map[string]string{"status": "ok"}
+	}
+	return map[string]string{"status": "proposed", "msig": proposal.Msig, "txnId": proposal.TxnID}
+}
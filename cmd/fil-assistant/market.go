@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+)
+
+func init() {
+	register(
+		&command{Name: "market-balance", Usage: "show the storage market escrow of a provider or client", Run: marketBalance},
+		&command{Name: "market-add", Usage: "add storage market collateral for a provider or client", Run: marketAdd},
+		&command{Name: "market-withdraw", Usage: "withdraw available storage market collateral", Run: marketWithdraw},
+	)
+}
+
+func marketBalance(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("market-balance")
+	addr := fs.String("address", "", "miner or client address")
+	if err := parse(fs, args, "address"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.GetMarketBalance(ctx, *addr)
+}
+
+func marketAdd(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("market-add")
+	key := addKeyFlags(fs)
+	addr := fs.String("address", "", "miner or client address")
+	amount := fs.String("amount", "", "amount to add")
+	msig := fs.String("msig", "", "propose the deposit from this multisig")
+	if err := parse(fs, args, "address", "amount"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.MarketAddBalance(ctx, pk, *addr, *amount, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func marketWithdraw(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("market-withdraw")
+	key := addKeyFlags(fs)
+	addr := fs.String("address", "", "miner or client address")
+	amount := fs.String("amount", "", "amount to withdraw")
+	all := fs.Bool("all", false, "withdraw all of the available collateral, read at send time")
+	msig := fs.String("msig", "", "propose the withdrawal from this multisig owner or client")
+	if err := parse(fs, args, "address"); err != nil {
+		return nil, err
+	}
+	if *all == (*amount != "") {
+		return nil, usagef("market-withdraw: one of -amount and -all is required")
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.MarketWithdraw(ctx, pk, *addr, *amount, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
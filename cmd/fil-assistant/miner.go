@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"github.com/filecoin-project/lotus/chain/types"
+)
+
+func init() {
+	register(
+		&command{Name: "send", Usage: "transfer FIL, directly or as a multisig proposal", Run: send},
+		&command{Name: "withdraw", Usage: "withdraw available balance from a miner", Run: withdraw},
+		&command{Name: "repay-debt", Usage: "repay the fee debt of a miner", Run: repayDebt},
+		&command{Name: "change-owner", Usage: "propose a new owner for a miner (step 1)", Run: changeOwner},
+		&command{Name: "confirm-owner", Usage: "confirm the owner change as the new owner (step 2)", Run: confirmOwner},
+		&command{Name: "change-worker", Usage: "propose a new worker and control addresses (step 1)", Run: changeWorker},
+		&command{Name: "confirm-worker", Usage: "confirm the pending worker change (step 2)", Run: confirmWorker},
+		&command{Name: "miner-info", Usage: "show the addresses, power and funds of a miner", Run: minerInfo},
+		&command{Name: "change-peer-id", Usage: "change the libp2p peer ID of a miner", Run: changePeerID},
+		&command{Name: "change-multiaddrs", Usage: "change the multiaddrs a miner announces", Run: changeMultiaddrs},
+		&command{Name: "create-miner", Usage: "create a miner actor through the power actor", Run: createMiner},
+	)
+}
+
+func send(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("send")
+	key := addKeyFlags(fs)
+	to := fs.String("to", "", "recipient address")
+	amount := fs.String("amount", "", "amount, e.g. \"1.5\" or \"1.5 FIL\"")
+	msig := fs.String("msig", "", "propose the transfer from this multisig instead of sending directly")
+	if err := parse(fs, args, "to", "amount"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.Send(ctx, pk, *to, *amount, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func withdraw(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("withdraw")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	amount := fs.String("amount", "", "amount to withdraw")
+	all := fs.Bool("all", false, "withdraw all of the available balance, read at send time")
+	check := fs.Bool("check", false, "only check that the withdrawal can be made, explaining why not")
+	msig := fs.String("msig", "", "propose the withdrawal from this multisig owner")
+	if err := parse(fs, args, "miner"); err != nil {
+		return nil, err
+	}
+	if *all == (*amount != "") {
+		return nil, usagef("withdraw: one of -amount and -all is required")
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if *check {
+		amnt, err := h.WithdrawPreflight(ctx, *minerID, *amount)
+		if err != nil {
+			return nil, err
+		}
+		return map[string]string{"status": "ok", "amount": types.FIL(amnt).String()}, nil
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	proposal := proposalFor(*msig)
+	if err = h.Withdraw(ctx, pk, *minerID, *amount, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func repayDebt(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("repay-debt")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	amount := fs.String("amount", "", "amount sent along, by default what the unlocked balance lacks to cover the debt")
+	msig := fs.String("msig", "", "propose the repayment from this multisig owner")
+	if err := parse(fs, args, "miner"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.RepayDebt(ctx, pk, *minerID, *amount, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func changeOwner(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("change-owner")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	newOwner := fs.String("new-owner", "", "address of the new owner")
+	msig := fs.String("msig", "", "propose the change from this multisig owner")
+	if err := parse(fs, args, "miner", "new-owner"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ChangeOwner1(ctx, pk, *newOwner, *minerID, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func confirmOwner(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("confirm-owner")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	msig := fs.String("msig", "", "confirm as this multisig new owner")
+	if err := parse(fs, args, "miner"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ChangeOwner2(ctx, pk, *minerID, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func changeWorker(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("change-worker")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	worker := fs.String("worker", "", "address of the new worker")
+	controls := fs.String("controls", "", "comma separated control addresses")
+	msig := fs.String("msig", "", "propose the change from this multisig owner")
+	if err := parse(fs, args, "miner", "worker", "controls"); err != nil {
+		return nil, err
+	}
+	newControls := splitList(*controls)
+	if len(newControls) == 0 {
+		return nil, usagef("change-worker: -controls has no address")
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ProposeChangeWorker(ctx, pk, *minerID, *worker, newControls, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func confirmWorker(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("confirm-worker")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	msig := fs.String("msig", "", "propose the confirmation from this multisig owner")
+	if err := parse(fs, args, "miner"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ConfirmChangeWorker(ctx, pk, *minerID, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func minerInfo(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("miner-info")
+	minerID := fs.String("miner", "", "miner actor address")
+	if err := parse(fs, args, "miner"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.GetMinerOverview(ctx, *minerID)
+}
+
+func changePeerID(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("change-peer-id")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	peerID := fs.String("peer-id", "", "new peer ID, e.g. 12D3KooW...")
+	msig := fs.String("msig", "", "propose the change from this multisig owner")
+	if err := parse(fs, args, "miner", "peer-id"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ChangePeerID(ctx, pk, *minerID, *peerID, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func changeMultiaddrs(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("change-multiaddrs")
+	key := addKeyFlags(fs)
+	minerID := fs.String("miner", "", "miner actor address")
+	addrs := fs.String("addrs", "", "comma separated multiaddrs, e.g. /ip4/1.2.3.4/tcp/24001")
+	msig := fs.String("msig", "", "propose the change from this multisig owner")
+	if err := parse(fs, args, "miner", "addrs"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ChangeMultiaddrs(ctx, pk, *minerID, splitList(*addrs), proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func createMiner(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("create-miner")
+	key := addKeyFlags(fs)
+	owner := fs.String("owner", "", "owner address, the sender or the proposing multisig by default")
+	worker := fs.String("worker", "", "worker address, the sender by default")
+	sectorSize := fs.String("sector-size", "32GiB", "sector size, or a seal proof number")
+	peerID := fs.String("peer-id", "", "peer ID, e.g. 12D3KooW...")
+	addrs := fs.String("addrs", "", "comma separated multiaddrs, e.g. /ip4/1.2.3.4/tcp/24001")
+	msig := fs.String("msig", "", "propose the creation from this multisig")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	id, robust, err := h.CreateMiner(ctx, pk, *owner, *worker, *sectorSize, *peerID, splitList(*addrs), proposal)
+	if err != nil {
+		return nil, err
+	}
+	if proposal != nil {
+		return done(proposal), nil
+	}
+	return map[string]string{"status": "ok", "miner": id, "robust": robust}, nil
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/chain/mock"
+	"fmt"
+	"os"
+	"os/signal"
+)
+
+func init() {
+	register(
+		&command{Name: "mock-node", Usage: "serve a fake Lotus node to rehearse operations against", Run: mockNode},
+	)
+}
+
+// mockNode serves until interrupted, point EndPoint in a copy of config.toml to the printed endpoint.
+func mockNode(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("mock-node")
+	listen := fs.String("listen", "127.0.0.1:1234", "address to listen on")
+	statePath := fs.String("state", "", "JSON file with the initial balances, IDs, actors, miners and multisigs")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+
+	state := mock.NewState()
+	if *statePath != "" {
+		var err error
+		if state, err = mock.LoadState(*statePath); err != nil {
+			return nil, err
+		}
+	}
+
+	node := mock.NewNode(state)
+	endpoint, err := node.Start(*listen)
+	if err != nil {
+		return nil, err
+	}
+	defer node.Close()
+	fmt.Fprintf(os.Stderr, "mock node listening on %s\n", endpoint)
+
+	sig := make(chan os.Signal, 1)
+	signal.Notify(sig, os.Interrupt)
+	select {
+	case <-sig:
+	case <-ctx.Done():
+	}
+	return map[string]interface{}{"status": "stopped", "messages": len(node.Pushed())}, nil
+}
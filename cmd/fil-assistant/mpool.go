@@ -0,0 +1,46 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/common"
+)
+
+func init() {
+	register(
+		&command{Name: "replace", Usage: "speed up a message stuck in the mpool by raising its gas premium", Run: replace},
+		&command{Name: "cancel-message", Usage: "replace a message stuck in the mpool by a zero value self send", Run: cancelMessage},
+	)
+}
+
+func replace(ctx context.Context, args []string) (interface{}, error) {
+	return runReplace(ctx, "replace", args, (*common.Handler).Replace)
+}
+
+func cancelMessage(ctx context.Context, args []string) (interface{}, error) {
+	return runReplace(ctx, "cancel-message", args, (*common.Handler).Cancel)
+}
+
+func runReplace(ctx context.Context, name string, args []string,
+	op func(h *common.Handler, ctx context.Context, pk, target, feeCap string) (string, error)) (interface{}, error) {
+	fs := newFlagSet(name)
+	key := addKeyFlags(fs)
+	target := fs.String("target", "", "cid or nonce of the pending message")
+	feeCap := fs.String("fee-cap", "", "GasFeeCap in attoFIL, by default the higher of the message's and the configured one")
+	if err := parse(fs, args, "target"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, err := op(h, ctx, pk, *target, *feeCap)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "ok", "cid": c}, nil
+}
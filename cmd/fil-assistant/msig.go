@@ -0,0 +1,277 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/common"
+	"os"
+)
+
+func init() {
+	register(
+		&command{Name: "create-msig", Usage: "create a multisig account", Run: createMsig},
+		&command{Name: "add-signer", Usage: "propose adding a signer to a multisig", Run: addSigner},
+		&command{Name: "remove-signer", Usage: "propose removing a signer from a multisig", Run: removeSigner},
+		&command{Name: "swap-signer", Usage: "propose swapping a signer of a multisig", Run: swapSigner},
+		&command{Name: "change-threshold", Usage: "propose a new approval threshold", Run: changeThreshold},
+		&command{Name: "lock-balance", Usage: "propose locking part of the multisig balance", Run: lockBalance},
+		&command{Name: "approve", Usage: "approve a pending multisig proposal", Run: approve},
+		&command{Name: "cancel", Usage: "cancel a pending multisig proposal", Run: cancel},
+		&command{Name: "pending", Usage: "list the pending proposals of a multisig", Run: pending},
+		&command{Name: "proposal", Usage: "show a pending proposal and its hash, to review before approve", Run: showProposal},
+		&command{Name: "msig-info", Usage: "show the signers, threshold and balances of a multisig", Run: msigInfo},
+	)
+}
+
+func createMsig(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("create-msig")
+	key := addKeyFlags(fs)
+	signers := fs.String("signers", "", "comma separated signer addresses")
+	threshold := fs.String("threshold", "", "number of approvals required")
+	duration := fs.String("duration", "0", "unlock duration in epochs")
+	amount := fs.String("amount", "0", "initial balance")
+	if err := parse(fs, args, "signers", "threshold"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	id, err := h.CreateMultisig(ctx, splitList(*signers), pk, *threshold, *duration, *amount)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "ok", "msig": id}, nil
+}
+
+func addSigner(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("add-signer")
+	key := addKeyFlags(fs)
+	msig := fs.String("msig", "", "multisig address")
+	signer := fs.String("signer", "", "address of the signer to add")
+	increase := fs.Bool("increase", false, "increase the approval threshold")
+	if err := parse(fs, args, "msig", "signer"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ProposeAddSigner(ctx, pk, *signer, *increase, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func removeSigner(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("remove-signer")
+	key := addKeyFlags(fs)
+	msig := fs.String("msig", "", "multisig address")
+	signer := fs.String("signer", "", "address of the signer to remove")
+	decrease := fs.Bool("decrease", false, "decrease the approval threshold")
+	if err := parse(fs, args, "msig", "signer"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ProposeRemoveSigner(ctx, pk, *signer, *decrease, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func swapSigner(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("swap-signer")
+	key := addKeyFlags(fs)
+	msig := fs.String("msig", "", "multisig address")
+	oldSigner := fs.String("old", "", "address of the signer to replace")
+	newSigner := fs.String("new", "", "address of the new signer")
+	if err := parse(fs, args, "msig", "old", "new"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ProposeSwapSigner(ctx, pk, *oldSigner, *newSigner, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func changeThreshold(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("change-threshold")
+	key := addKeyFlags(fs)
+	msig := fs.String("msig", "", "multisig address")
+	threshold := fs.String("threshold", "", "new number of approvals required")
+	if err := parse(fs, args, "msig", "threshold"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ProposeChangeThreshold(ctx, pk, *threshold, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func lockBalance(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("lock-balance")
+	key := addKeyFlags(fs)
+	msig := fs.String("msig", "", "multisig address")
+	start := fs.String("start", "", "start epoch of the vesting")
+	duration := fs.String("duration", "", "unlock duration in epochs")
+	amount := fs.String("amount", "", "amount to lock")
+	if err := parse(fs, args, "msig", "start", "duration", "amount"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.ProposeLockBalance(ctx, pk, *start, *duration, *amount, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
+
+func approve(ctx context.Context, args []string) (interface{}, error) {
+	return approveOrCancel(ctx, "approve", true, args)
+}
+
+func cancel(ctx context.Context, args []string) (interface{}, error) {
+	return approveOrCancel(ctx, "cancel", false, args)
+}
+
+func approveOrCancel(ctx context.Context, name string, approve bool, args []string) (interface{}, error) {
+	fs := newFlagSet(name)
+	key := addKeyFlags(fs)
+	msig := fs.String("msig", "", "multisig address")
+	txnID := fs.String("txn", "", "id of the pending proposal")
+	hash := fs.String("hash", "", "proposal hash shown by the proposal command")
+	if err := parse(fs, args, "msig", "txn", "hash"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := common.Proposal{Msig: *msig, TxnID: *txnID, Hash: *hash}
+	if err = h.ApproveOrCancel(ctx, pk, approve, proposal); err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "ok", "msig": *msig, "txnId": *txnID}, nil
+}
+
+func showProposal(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("proposal")
+	msig := fs.String("msig", "", "multisig address")
+	txnID := fs.String("txn", "", "id of the pending proposal")
+	if err := parse(fs, args, "msig", "txn"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.GetProposal(ctx, common.Proposal{Msig: *msig, TxnID: *txnID})
+}
+
+func pending(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("pending")
+	msig := fs.String("msig", "", "multisig address")
+	export := fs.String("export", "", "also write the proposals to this file")
+	format := fs.String("format", "", "csv or json, guessed from the file extension by default")
+	if err := parse(fs, args, "msig"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposals, err := h.GetPendingProposals(ctx, *msig)
+	if err != nil {
+		return nil, err
+	}
+	if *export == "" {
+		return proposals, nil
+	}
+
+	f, err := fileFormat(*format, *export)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Create(*export)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+	if f == "json" {
+		err = common.WriteProposalsJSON(file, proposals)
+	} else {
+		err = common.WriteProposalsCSV(file, proposals)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return proposals, nil
+}
+
+func msigInfo(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("msig-info")
+	msig := fs.String("msig", "", "multisig address")
+	if err := parse(fs, args, "msig"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.GetMultisigInfo(ctx, *msig)
+}
@@ -0,0 +1,73 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/common"
+	"fil-assistant/lib"
+	"io/ioutil"
+	"os"
+)
+
+func init() {
+	register(
+		&command{Name: "sign-message", Usage: "sign a prepared message offline, needs no node", Run: signMessage},
+		&command{Name: "broadcast", Usage: "push a message signed offline and wait for it", Run: broadcast},
+	)
+}
+
+func signMessage(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("sign-message")
+	key := addKeyFlags(fs)
+	in := fs.String("in", "unsigned.json", "unsigned message written by an operation run with -from")
+	out := fs.String("signed", "signed.json", "file the signed message is written to")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+
+	unsigned, err := ioutil.ReadFile(*in)
+	if err != nil {
+		return nil, err
+	}
+	// only the wallet is opened, the node may not be reachable from the machine holding the key
+	var w *lib.Wallet
+	if *key.from != "" {
+		if w, err = getWallet(os.Getenv(passphraseEnv)); err != nil {
+			return nil, err
+		}
+	}
+
+	signed, err := common.SignMessage(w, pk, unsigned)
+	if err != nil {
+		return nil, err
+	}
+	if err = ioutil.WriteFile(*out, signed, 0600); err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "signed", "file": *out}, nil
+}
+
+func broadcast(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("broadcast")
+	in := fs.String("in", "signed.json", "signed message written by sign-message")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	signed, err := ioutil.ReadFile(*in)
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	c, ret, err := h.Broadcast(ctx, signed)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]interface{}{"status": "ok", "cid": c, "return": ret}, nil
+}
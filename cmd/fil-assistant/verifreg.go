@@ -0,0 +1,51 @@
+package main
+
+import (
+	"context"
+)
+
+func init() {
+	register(
+		&command{Name: "datacap", Usage: "show the DataCap of a client and the allowance of a verifier", Run: dataCap},
+		&command{Name: "grant-datacap", Usage: "grant DataCap to a client as a verifier", Run: grantDataCap},
+	)
+}
+
+func dataCap(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("datacap")
+	addr := fs.String("address", "", "client or verifier address")
+	if err := parse(fs, args, "address"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return h.GetDataCap(ctx, *addr)
+}
+
+func grantDataCap(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("grant-datacap")
+	key := addKeyFlags(fs)
+	client := fs.String("client", "", "client address")
+	allowance := fs.String("allowance", "", "DataCap to grant, in bytes or e.g. 32GiB")
+	msig := fs.String("msig", "", "propose the grant from this multisig verifier")
+	if err := parse(fs, args, "client", "allowance"); err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	proposal := proposalFor(*msig)
+	if err = h.AddVerifiedClient(ctx, pk, *client, *allowance, proposal); err != nil {
+		return nil, err
+	}
+	return done(proposal), nil
+}
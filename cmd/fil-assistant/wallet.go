@@ -0,0 +1,130 @@
+package main
+
+import (
+	"context"
+	"fil-assistant/common"
+	"flag"
+)
+
+func init() {
+	register(
+		&command{Name: "wallet-list", Usage: "list the addresses of the local wallet", Run: walletList},
+		&command{Name: "wallet-import", Usage: "import a private key into the local wallet", Run: walletImport},
+		&command{Name: "wallet-generate", Usage: "generate a new key in the local wallet", Run: walletGenerate},
+		&command{Name: "wallet-export", Usage: "export the private key of a wallet address", Run: walletExport},
+		&command{Name: "wallet-delete", Usage: "delete an address from the local wallet", Run: walletDelete},
+		&command{Name: "wallet-label", Usage: "set the label of a wallet address", Run: walletLabel},
+	)
+}
+
+// unlockedHandler parses args and returns a Handler whose wallet is unlocked with the passphrase flags.
+func unlockedHandler(ctx context.Context, fs *flag.FlagSet, args []string, required ...string) (*common.Handler, error) {
+	pass := addPassFlags(fs)
+	if err := parse(fs, args, required...); err != nil {
+		return nil, err
+	}
+	passphrase, err := pass.get()
+	if err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h, h.UnlockWallet(passphrase)
+}
+
+func walletList(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("wallet-list")
+	if err := parse(fs, args); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return h.ListWallet()
+}
+
+func walletImport(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("wallet-import")
+	key := addKeyFlags(fs)
+	label := fs.String("label", "", "label of the address")
+	h, err := unlockedHandler(ctx, fs, args)
+	if err != nil {
+		return nil, err
+	}
+	pk, err := key.get()
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := h.ImportKey(pk, *label)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"address": addr}, nil
+}
+
+func walletGenerate(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("wallet-generate")
+	keyType := fs.String("type", "secp256k1", "key type, secp256k1 or bls")
+	label := fs.String("label", "", "label of the address")
+	h, err := unlockedHandler(ctx, fs, args)
+	if err != nil {
+		return nil, err
+	}
+
+	addr, err := h.GenerateKey(*keyType, *label)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"address": addr}, nil
+}
+
+func walletExport(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("wallet-export")
+	addr := fs.String("address", "", "wallet address")
+	h, err := unlockedHandler(ctx, fs, args, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	pk, err := h.ExportKey(*addr)
+	if err != nil {
+		return nil, err
+	}
+	return map[string]string{"address": *addr, "key": pk}, nil
+}
+
+func walletDelete(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("wallet-delete")
+	addr := fs.String("address", "", "wallet address")
+	h, err := unlockedHandler(ctx, fs, args, "address")
+	if err != nil {
+		return nil, err
+	}
+
+	if err = h.DeleteKey(*addr); err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "ok"}, nil
+}
+
+func walletLabel(ctx context.Context, args []string) (interface{}, error) {
+	fs := newFlagSet("wallet-label")
+	addr := fs.String("address", "", "wallet address")
+	label := fs.String("label", "", "new label, empty removes it")
+	if err := parse(fs, args, "address"); err != nil {
+		return nil, err
+	}
+	h, err := getHandler(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = h.LabelKey(*addr, *label); err != nil {
+		return nil, err
+	}
+	return map[string]string{"status": "ok"}, nil
+}
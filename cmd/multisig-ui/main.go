@@ -2,15 +2,22 @@ package main
 
 import (
 	"context"
+	"encoding/json"
 	"fil-assistant/common"
 	"fmt"
 	"fyne.io/fyne/v2"
 	"fyne.io/fyne/v2/app"
 	"fyne.io/fyne/v2/container"
-	"fyne.io/fyne/v2/layout"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
 	"fyne.io/fyne/v2/theme"
 	"fyne.io/fyne/v2/widget"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"io"
 	"os"
+	"sort"
+	"strconv"
 	"strings"
 )
 
@@ -28,12 +35,22 @@ func main() {
 
 	globalVar.Init(w)
 
-	tabs := make([]*container.TabItem, 5)
+	tabs := make([]*container.TabItem, 15)
 	tabs[0] = container.NewTabItem("创建多签账户", createMsig())
 	tabs[1] = container.NewTabItem("发起通用提案", generalProposals())
 	tabs[2] = container.NewTabItem("发起矿工提案", miningProposals())
-	tabs[3] = container.NewTabItem("赞成/反对提案", approveOrCancel())
-	tabs[4] = container.NewTabItem("查询待定提案", getProposals())
+	tabs[3] = container.NewTabItem("自定义提案", globalVar.CallTab(true))
+	tabs[4] = container.NewTabItem("赞成/反对提案", approveOrCancel())
+	tabs[5] = container.NewTabItem("查询待定提案", getProposals())
+	tabs[6] = container.NewTabItem("多签账户信息", msigInfo())
+	tabs[7] = container.NewTabItem("离线签名", offline())
+	tabs[8] = container.NewTabItem("钱包", globalVar.WalletTab())
+	tabs[9] = container.NewTabItem("加速/取消消息", globalVar.MpoolTab())
+	tabs[10] = container.NewTabItem("历史记录", globalVar.HistoryTab())
+	tabs[11] = container.NewTabItem("地址簿", globalVar.AddressBookTab())
+	tabs[12] = container.NewTabItem("DataCap公证", dataCap())
+	tabs[13] = container.NewTabItem("生成密钥", globalVar.KeyGenTab())
+	tabs[14] = container.NewTabItem("助记词", globalVar.MnemonicTab())
 
 	w.SetContent(container.NewVBox(Process(), container.NewAppTabs(tabs...)))
 	w.Resize(fyne.NewSize(800, 0))
@@ -61,18 +78,21 @@ func generalProposals() fyne.CanvasObject {
 }
 
 func miningProposals() fyne.CanvasObject {
-	tabs := make([]*container.TabItem, 4)
+	tabs := make([]*container.TabItem, 8)
 	tabs[0] = container.NewTabItem("发起更换owner", proposeChangeOwner())
 	tabs[1] = container.NewTabItem("确认更换owner", confirmChangeOwner())
 	tabs[2] = container.NewTabItem("挖矿提现", withdraw())
 	tabs[3] = container.NewTabItem("更换worker地址", changeWorker())
+	tabs[4] = container.NewTabItem("更换节点信息", globalVar.PeerInfoTab(true))
+	tabs[5] = container.NewTabItem("偿还欠费", globalVar.RepayDebtTab(true))
+	tabs[6] = container.NewTabItem("存储市场", globalVar.MarketTab(true))
+	tabs[7] = container.NewTabItem("创建矿工", globalVar.CreateMinerTab(true))
 
 	return container.NewAppTabs(tabs...)
 }
 
 func createMsig() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
 	signers := widget.NewMultiLineEntry()
 	signers.PlaceHolder = "signers地址"
@@ -98,7 +118,7 @@ func createMsig() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || threshold.Text == "" || duration.Text == "" || initAmount.Text == "" {
+		if pk.Key() == "" || threshold.Text == "" || duration.Text == "" || initAmount.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -117,10 +137,10 @@ func createMsig() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		id, err := globalVar.Handler.CreateMultisig(context.TODO(), newSigners, strings.TrimSpace(pk.Text),
+		id, err := globalVar.Handler.CreateMultisig(context.TODO(), newSigners, pk.Key(),
 			strings.TrimSpace(threshold.Text), strings.TrimSpace(duration.Text), strings.TrimSpace(initAmount.Text))
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("多签账号已生成: %s", id))
 			globalVar.Process.Set(1)
@@ -129,18 +149,15 @@ func createMsig() fyne.CanvasObject {
 
 	mid := container.NewGridWithColumns(2, threshold, duration)
 	bottom := container.NewGridWithColumns(2, initAmount, submit)
-	return container.NewVBox(pk, signers, mid, bottom)
+	return container.NewVBox(pk, signers, globalVar.NewAddressPicker(signers), mid, bottom)
 }
 
 func addSigner() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	adding := widget.NewEntry()
-	adding.PlaceHolder = "signer地址"
+	adding := globalVar.NewAddressEntry("signer地址")
 
 	increase := widget.NewCheck("增加投票阈值", nil)
 
@@ -156,7 +173,7 @@ func addSigner() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || adding.Text == "" {
+		if pk.Key() == "" || msig.Text == "" || adding.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -164,10 +181,10 @@ func addSigner() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ProposeAddSigner(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ProposeAddSigner(context.TODO(), pk.Key(),
 			strings.TrimSpace(adding.Text), increase.Checked, proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -179,14 +196,11 @@ func addSigner() fyne.CanvasObject {
 }
 
 func removeSigner() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	removing := widget.NewEntry()
-	removing.PlaceHolder = "signer地址"
+	removing := globalVar.NewAddressEntry("signer地址")
 
 	decrease := widget.NewCheck("减少投票阈值", nil)
 
@@ -202,7 +216,7 @@ func removeSigner() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || removing.Text == "" {
+		if pk.Key() == "" || msig.Text == "" || removing.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -210,10 +224,10 @@ func removeSigner() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ProposeRemoveSigner(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ProposeRemoveSigner(context.TODO(), pk.Key(),
 			strings.TrimSpace(removing.Text), decrease.Checked, proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -225,17 +239,13 @@ func removeSigner() fyne.CanvasObject {
 }
 
 func swapSigner() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	oldSigner := widget.NewEntry()
-	oldSigner.PlaceHolder = "旧signer地址"
+	oldSigner := globalVar.NewAddressEntry("旧signer地址")
 
-	newSigner := widget.NewEntry()
-	newSigner.PlaceHolder = "新signer地址"
+	newSigner := globalVar.NewAddressEntry("新signer地址")
 
 	submit := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
@@ -249,7 +259,7 @@ func swapSigner() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || oldSigner.Text == "" || newSigner.Text == "" {
+		if pk.Key() == "" || msig.Text == "" || oldSigner.Text == "" || newSigner.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -257,10 +267,10 @@ func swapSigner() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ProposeSwapSigner(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ProposeSwapSigner(context.TODO(), pk.Key(),
 			strings.TrimSpace(oldSigner.Text), strings.TrimSpace(newSigner.Text), proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -271,11 +281,9 @@ func swapSigner() fyne.CanvasObject {
 }
 
 func updateThreshold() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
 	threshold := widget.NewEntry()
 	threshold.PlaceHolder = "投票阈值"
@@ -292,7 +300,7 @@ func updateThreshold() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || threshold.Text == "" {
+		if pk.Key() == "" || msig.Text == "" || threshold.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -300,10 +308,10 @@ func updateThreshold() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ProposeChangeThreshold(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ProposeChangeThreshold(context.TODO(), pk.Key(),
 			strings.TrimSpace(threshold.Text), proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -314,11 +322,9 @@ func updateThreshold() fyne.CanvasObject {
 }
 
 func lockBalance() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
 	start := widget.NewEntry()
 	start.PlaceHolder = "启动区块高度"
@@ -341,7 +347,7 @@ func lockBalance() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || start.Text == "" || duration.Text == "" || amount.Text != "" {
+		if pk.Key() == "" || msig.Text == "" || start.Text == "" || duration.Text == "" || amount.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -349,10 +355,10 @@ func lockBalance() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ProposeLockBalance(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ProposeLockBalance(context.TODO(), pk.Key(),
 			strings.TrimSpace(start.Text), strings.TrimSpace(duration.Text), strings.TrimSpace(amount.Text), proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -365,14 +371,11 @@ func lockBalance() fyne.CanvasObject {
 }
 
 func send() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	to := widget.NewEntry()
-	to.PlaceHolder = "收款地址"
+	to := globalVar.NewAddressEntry("收款地址")
 
 	amount := widget.NewEntry()
 	amount.PlaceHolder = "金额"
@@ -389,7 +392,7 @@ func send() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || to.Text == "" || amount.Text != "" {
+		if pk.Key() == "" || msig.Text == "" || to.Text == "" || amount.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -397,10 +400,10 @@ func send() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.Send(context.TODO(), strings.TrimSpace(pk.Text), strings.TrimSpace(to.Text),
+		err := globalVar.Handler.Send(context.TODO(), pk.Key(), strings.TrimSpace(to.Text),
 			strings.TrimSpace(amount.Text), proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -411,17 +414,13 @@ func send() fyne.CanvasObject {
 }
 
 func proposeChangeOwner() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	newOwner := widget.NewEntry()
-	newOwner.PlaceHolder = "新owner地址"
+	newOwner := globalVar.NewAddressEntry("新owner地址")
 
-	minerID := widget.NewEntry()
-	minerID.PlaceHolder = "矿工号"
+	minerID := globalVar.NewAddressEntry("矿工号")
 
 	submit := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
@@ -437,16 +436,16 @@ func proposeChangeOwner() fyne.CanvasObject {
 
 		globalVar.Process.Set(0)
 
-		if pk.Text == "" || msig.Text == "" || newOwner.Text == "" || minerID.Text != "" {
+		if pk.Key() == "" || msig.Text == "" || newOwner.Text == "" || minerID.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ChangeOwner1(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ChangeOwner1(context.TODO(), pk.Key(),
 			strings.TrimSpace(newOwner.Text), strings.TrimSpace(minerID.Text), proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -457,14 +456,11 @@ func proposeChangeOwner() fyne.CanvasObject {
 }
 
 func confirmChangeOwner() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	minerID := widget.NewEntry()
-	minerID.PlaceHolder = "矿工号"
+	minerID := globalVar.NewAddressEntry("矿工号")
 
 	submit := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
@@ -478,7 +474,7 @@ func confirmChangeOwner() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || minerID.Text != "" {
+		if pk.Key() == "" || msig.Text == "" || minerID.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -486,10 +482,10 @@ func confirmChangeOwner() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ChangeOwner2(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ChangeOwner2(context.TODO(), pk.Key(),
 			strings.TrimSpace(minerID.Text), proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -500,18 +496,17 @@ func confirmChangeOwner() fyne.CanvasObject {
 }
 
 func withdraw() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	minerID := widget.NewEntry()
-	minerID.PlaceHolder = "矿工号"
+	minerID := globalVar.NewAddressEntry("矿工号")
 
 	amount := widget.NewEntry()
 	amount.PlaceHolder = "金额"
 
+	all := globalVar.NewWithdrawAllCheck(amount)
+
 	submit := widget.NewButton("提交", func() {
 		if !globalVar.Locker.TryLock(0) {
 			globalVar.Msg(common.Warn, "请稍后再试")
@@ -524,41 +519,41 @@ func withdraw() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || minerID.Text != "" || amount.Text != "" {
+		if pk.Key() == "" || msig.Text == "" || minerID.Text == "" || (amount.Text == "" && !all.Checked) {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
 
 		globalVar.Process.Set(0)
 
+		value := strings.TrimSpace(amount.Text)
+		if all.Checked {
+			value = ""
+		}
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.Withdraw(context.TODO(), strings.TrimSpace(pk.Text),
-			strings.TrimSpace(minerID.Text), strings.TrimSpace(amount.Text), proposal)
+		err := globalVar.Handler.Withdraw(context.TODO(), pk.Key(),
+			strings.TrimSpace(minerID.Text), value, proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
 		}
 	})
 
-	mid := container.NewGridWithColumns(2, minerID, amount)
-	bottom := container.NewGridWithColumns(2, msig, submit)
+	mid := container.NewGridWithColumns(3, minerID, amount, all)
+	bottom := container.NewGridWithColumns(3, msig, globalVar.NewPreflightButton(minerID, amount, all), submit)
 	return container.NewVBox(pk, mid, bottom)
 }
 
 func changeWorker() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
-	minerID := widget.NewEntry()
-	minerID.PlaceHolder = "矿工号"
+	minerID := globalVar.NewAddressEntry("矿工号")
 
-	worker := widget.NewEntry()
-	worker.PlaceHolder = "worker地址"
+	worker := globalVar.NewAddressEntry("worker地址")
 
 	controls := widget.NewMultiLineEntry()
 	controls.PlaceHolder = "controls地址"
@@ -575,7 +570,7 @@ func changeWorker() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || minerID.Text != "" || worker.Text != "" {
+		if pk.Key() == "" || msig.Text == "" || minerID.Text == "" || worker.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -595,10 +590,10 @@ func changeWorker() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ProposeChangeWorker(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ProposeChangeWorker(context.TODO(), pk.Key(),
 			strings.TrimSpace(minerID.Text), strings.TrimSpace(worker.Text), newControls, proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
@@ -617,7 +612,7 @@ func changeWorker() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || minerID.Text != "" {
+		if pk.Key() == "" || msig.Text == "" || minerID.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
@@ -625,30 +620,267 @@ func changeWorker() fyne.CanvasObject {
 		globalVar.Process.Set(0)
 
 		proposal := &common.Proposal{ Msig: strings.TrimSpace(msig.Text) }
-		err := globalVar.Handler.ConfirmChangeWorker(context.TODO(), strings.TrimSpace(pk.Text),
+		err := globalVar.Handler.ConfirmChangeWorker(context.TODO(), pk.Key(),
 			strings.TrimSpace(minerID.Text), proposal)
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
+			globalVar.Fail(err)
 		} else {
 			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
 			globalVar.Process.Set(1)
 		}
 	})
 
-	return container.NewVBox(pk, worker, controls, container.NewGridWithColumns(4, msig, minerID, propose, confirm))
+	return container.NewVBox(pk, worker, controls, globalVar.NewAddressPicker(controls), container.NewGridWithColumns(4, msig, minerID, propose, confirm))
 }
 
 func approveOrCancel() fyne.CanvasObject {
-	pk := widget.NewPasswordEntry()
-	pk.PlaceHolder = "私钥"
+	pk := globalVar.NewKeyEntry()
 
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+	msig := globalVar.NewAddressEntry("多签账号")
 
 	txID := widget.NewEntry()
 	txID.PlaceHolder = "提案号"
 
 	approve := widget.NewButton("赞成提案", func() {
+		reviewProposal(pk.Key(), msig.Text, txID.Text, true, nil)
+	})
+	aSrc, err := fyne.LoadResourceFromPath("./resource/approve.png")
+	if err == nil {
+		approve.SetIcon(aSrc)
+	}
+
+	reject := widget.NewButton("反对提案", func() {
+		reviewProposal(pk.Key(), msig.Text, txID.Text, false, nil)
+	})
+	rSrc, err := fyne.LoadResourceFromPath("./resource/reject.png")
+	if err == nil {
+		reject.SetIcon(rSrc)
+	}
+
+	mid := container.NewGridWithColumns(2, msig, txID)
+	bottom := container.NewGridWithColumns(2, approve, reject)
+	return container.NewVBox(pk, mid, bottom)
+}
+
+// reviewProposal shows the pending transaction for confirmation before approving or cancelling it. The proposal
+// hash of what was shown is sent along, so a transaction replaced in the meantime is rejected by the chain. done, when
+// not nil, is called once the message executed.
+func reviewProposal(pk, msig, txID string, approve bool, done func()) {
+	if !globalVar.Locker.TryLock(0) {
+		globalVar.Msg(common.Warn, "请稍后再试")
+		return
+	}
+	defer globalVar.Locker.Unlock()
+
+	if globalVar.Handler == nil {
+		globalVar.Msg(common.Error, "初始化异常")
+		return
+	}
+
+	if pk == "" || msig == "" || txID == "" {
+		globalVar.Msg(common.Warn, "输入为空")
+		return
+	}
+
+	proposal := common.Proposal{Msig: strings.TrimSpace(msig), TxnID: strings.TrimSpace(txID)}
+	info, err := globalVar.Handler.GetProposal(context.TODO(), proposal)
+	if err != nil {
+		globalVar.Fail(err)
+		return
+	}
+	proposal.Hash = info.Hash
+
+	title, finished := "反对提案", "反对提案完成"
+	if approve {
+		title, finished = "赞成提案", "赞成提案完成"
+	}
+	text := globalVar.Handler.DescribeProposal(info) + "\n\n确认" + title + "?"
+	dialog.ShowConfirm(title, text, func(ok bool) {
+		if !ok {
+			return
+		}
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+
+		globalVar.Process.Set(0)
+
+		err := globalVar.Handler.ApproveOrCancel(context.TODO(), pk, approve, proposal)
+		globalVar.Locker.Unlock()
+		if err != nil {
+			globalVar.Fail(err)
+			return
+		}
+		globalVar.Msg(common.Info, finished)
+		globalVar.Process.Set(1)
+		if done != nil {
+			done()
+		}
+	}, globalVar.Window)
+}
+
+// proposalColumn is a column of the pending proposals table, the rows are sorted with less.
+type proposalColumn struct {
+	title string
+	width float32
+	text  func(info *common.ProposalInfo) string
+	less  func(a, b *common.ProposalInfo) bool
+}
+
+// labeled appends the address book label of addr, when info knows one.
+func labeled(info *common.ProposalInfo, addr address.Address) string {
+	if label := info.Labels[addr.String()]; label != "" {
+		return fmt.Sprintf("%s (%s)", addr, label)
+	}
+	return addr.String()
+}
+
+var proposalColumns = []proposalColumn{
+	{"提案号", 70, func(info *common.ProposalInfo) string {
+		return strconv.FormatInt(info.ID, 10)
+	}, func(a, b *common.ProposalInfo) bool {
+		return a.ID < b.ID
+	}},
+	{"接收方", 220, func(info *common.ProposalInfo) string {
+		return labeled(info, info.To)
+	}, nil},
+	{"金额(FIL)", 110, func(info *common.ProposalInfo) string {
+		return types.FIL(info.Value).Unitless()
+	}, func(a, b *common.ProposalInfo) bool {
+		return a.Value.LessThan(b.Value)
+	}},
+	{"方法", 140, func(info *common.ProposalInfo) string {
+		return info.MethodChain()
+	}, nil},
+	{"参数", 240, func(info *common.ProposalInfo) string {
+		val, _ := json.Marshal(info.Params)
+		if info.Note != "" {
+			return fmt.Sprintf("%s (%s)", val, info.Note)
+		}
+		return string(val)
+	}, nil},
+	{"赞成数", 70, func(info *common.ProposalInfo) string {
+		return fmt.Sprintf("%d/%d", len(info.Approved), info.Threshold)
+	}, func(a, b *common.ProposalInfo) bool {
+		return len(a.Approved) < len(b.Approved)
+	}},
+	{"已赞成signer", 220, func(info *common.ProposalInfo) string {
+		approved := make([]string, 0, len(info.Approved))
+		for _, addr := range info.Approved {
+			approved = append(approved, labeled(info, addr))
+		}
+		return strings.Join(approved, ", ")
+	}, nil},
+}
+
+// getProposals shows the pending proposals of a multisig in a table. Tapping a header sorts by the column, tapping
+// a row shows the proposal in full, and every row can be approved or cancelled after review.
+func getProposals() fyne.CanvasObject {
+	pk := globalVar.NewKeyEntry()
+
+	msig := globalVar.NewAddressEntry("多签账号")
+
+	filter := widget.NewEntry()
+	filter.PlaceHolder = "筛选"
+
+	detail := widget.NewMultiLineEntry()
+	detail.PlaceHolder = "点击提案查看详情"
+
+	// queried is the multisig of proposals, rows the proposals shown after filtering and sorting
+	var queried string
+	var proposals, rows []*common.ProposalInfo
+	sortCol, desc := 0, false
+
+	var table *widget.Table
+	show := func() {
+		text := strings.ToLower(strings.TrimSpace(filter.Text))
+		rows = rows[:0]
+		for _, info := range proposals {
+			for _, col := range proposalColumns {
+				if strings.Contains(strings.ToLower(col.text(info)), text) {
+					rows = append(rows, info)
+					break
+				}
+			}
+		}
+		col := proposalColumns[sortCol]
+		sort.SliceStable(rows, func(i, j int) bool {
+			a, b := rows[i], rows[j]
+			if desc {
+				a, b = b, a
+			}
+			if col.less != nil {
+				return col.less(a, b)
+			}
+			return col.text(a) < col.text(b)
+		})
+		table.Refresh()
+	}
+	filter.OnChanged = func(string) {
+		show()
+	}
+
+	var query func()
+	table = widget.NewTable(func() (int, int) {
+		return len(rows) + 1, len(proposalColumns) + 1
+	}, func() fyne.CanvasObject {
+		label := widget.NewLabel("")
+		label.Wrapping = fyne.TextTruncate
+		approve := widget.NewButton("赞成", nil)
+		cancel := widget.NewButton("反对", nil)
+		return container.NewMax(label, container.NewGridWithColumns(2, approve, cancel))
+	}, func(id widget.TableCellID, obj fyne.CanvasObject) {
+		cell := obj.(*fyne.Container)
+		label := cell.Objects[0].(*widget.Label)
+		actions := cell.Objects[1].(*fyne.Container)
+		if id.Col == len(proposalColumns) && id.Row > 0 {
+			info, msigAddr := rows[id.Row-1], queried
+			txID := strconv.FormatInt(info.ID, 10)
+			actions.Objects[0].(*widget.Button).OnTapped = func() {
+				reviewProposal(pk.Key(), msigAddr, txID, true, query)
+			}
+			actions.Objects[1].(*widget.Button).OnTapped = func() {
+				reviewProposal(pk.Key(), msigAddr, txID, false, query)
+			}
+			label.Hide()
+			actions.Show()
+			return
+		}
+
+		actions.Hide()
+		label.Show()
+		switch {
+		case id.Col == len(proposalColumns):
+			label.SetText("操作")
+		case id.Row > 0:
+			label.SetText(proposalColumns[id.Col].text(rows[id.Row-1]))
+		case id.Col == sortCol && desc:
+			label.SetText(proposalColumns[id.Col].title + " ▼")
+		case id.Col == sortCol:
+			label.SetText(proposalColumns[id.Col].title + " ▲")
+		default:
+			label.SetText(proposalColumns[id.Col].title)
+		}
+	})
+	for i, col := range proposalColumns {
+		table.SetColumnWidth(i, col.width)
+	}
+	table.SetColumnWidth(len(proposalColumns), 140)
+	table.OnSelected = func(id widget.TableCellID) {
+		table.Unselect(id)
+		switch {
+		case id.Col == len(proposalColumns):
+		case id.Row == 0:
+			desc = id.Col == sortCol && !desc
+			sortCol = id.Col
+			show()
+		case globalVar.Handler != nil:
+			detail.SetText(globalVar.Handler.DescribeProposal(rows[id.Row-1]))
+		}
+	}
+
+	query = func() {
 		if !globalVar.Locker.TryLock(0) {
 			globalVar.Msg(common.Warn, "请稍后再试")
 			return
@@ -660,28 +892,60 @@ func approveOrCancel() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || txID.Text != "" {
+		if msig.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.ApproveOrCancel(context.TODO(), strings.TrimSpace(pk.Text), true,
-			common.Proposal{ Msig: strings.TrimSpace(msig.Text) })
+		res, err := globalVar.Handler.GetPendingProposals(context.TODO(), strings.TrimSpace(msig.Text))
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
-		} else {
-			globalVar.Msg(common.Info, "赞成提案完成")
-			globalVar.Process.Set(1)
+			globalVar.Fail(err)
+			return
 		}
-	})
-	aSrc, err := fyne.LoadResourceFromPath("./resource/approve.png")
-	if err == nil {
-		approve.SetIcon(aSrc)
+		queried, proposals = strings.TrimSpace(msig.Text), res
+		detail.SetText("")
+		show()
+		globalVar.Process.Set(1)
 	}
 
-	reject := widget.NewButton("反对提案", func() {
+	export := func(path string, write func(w io.Writer, infos []*common.ProposalInfo) error) func() {
+		return func() {
+			if len(rows) == 0 {
+				globalVar.Msg(common.Warn, "没有待定提案")
+				return
+			}
+			file, err := os.Create(path)
+			if err != nil {
+				globalVar.Msg(common.Warn, err.Error())
+				return
+			}
+			defer file.Close()
+			if err = write(file, rows); err != nil {
+				globalVar.Msg(common.Warn, err.Error())
+			} else {
+				globalVar.Msg(common.Info, fmt.Sprintf("%d个提案已导出到%s", len(rows), path))
+			}
+		}
+	}
+	exportCSV := widget.NewButton("导出CSV", export("./待定提案.csv", common.WriteProposalsCSV))
+	exportJSON := widget.NewButton("导出JSON", export("./待定提案.json", common.WriteProposalsJSON))
+
+	top := container.NewVBox(pk, container.NewGridWithColumns(2, msig, widget.NewButton("查询", query)),
+		container.NewGridWithColumns(3, filter, exportCSV, exportJSON))
+	return container.NewBorder(top, detail, nil, nil, table)
+}
+
+func msigInfo() fyne.CanvasObject {
+	msig := globalVar.NewAddressEntry("多签账号")
+
+	result := widget.NewMultiLineEntry()
+	result.PlaceHolder = "账户信息"
+	res := binding.NewString()
+	result.Bind(res)
+
+	query := widget.NewButton("查询", func() {
 		if !globalVar.Locker.TryLock(0) {
 			globalVar.Msg(common.Warn, "请稍后再试")
 			return
@@ -693,35 +957,44 @@ func approveOrCancel() fyne.CanvasObject {
 			return
 		}
 
-		if pk.Text == "" || msig.Text == "" || txID.Text != "" {
+		if msig.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
 
 		globalVar.Process.Set(0)
 
-		err := globalVar.Handler.ApproveOrCancel(context.TODO(), strings.TrimSpace(pk.Text), false,
-			common.Proposal{ Msig: strings.TrimSpace(msig.Text) })
+		info, err := globalVar.Handler.GetMultisigInfo(context.TODO(), strings.TrimSpace(msig.Text))
 		if err != nil {
-			globalVar.Msg(common.Warn, err.Error())
-		} else {
-			globalVar.Msg(common.Info, "反对提案完成")
-			globalVar.Process.Set(1)
+			globalVar.Fail(err)
+			return
+		}
+		val, err := json.MarshalIndent(info, "", "\t")
+		if err != nil {
+			globalVar.Fail(err)
+			return
 		}
+		res.Set(string(val))
+		globalVar.Process.Set(1)
 	})
-	rSrc, err := fyne.LoadResourceFromPath("./resource/reject.png")
-	if err == nil {
-		reject.SetIcon(rSrc)
-	}
 
-	mid := container.NewGridWithColumns(2, msig, txID)
-	bottom := container.NewGridWithColumns(2, approve, reject)
-	return container.NewVBox(pk, mid, bottom)
+	return container.NewVBox(container.NewGridWithColumns(2, msig, query), result)
 }
 
-func getProposals() fyne.CanvasObject {
-	msig := widget.NewEntry()
-	msig.PlaceHolder = "多签账号"
+func dataCap() fyne.CanvasObject {
+	pk := globalVar.NewKeyEntry()
+
+	msig := globalVar.NewAddressEntry("多签账号(公证人)")
+
+	client := globalVar.NewAddressEntry("客户地址")
+
+	allowance := widget.NewEntry()
+	allowance.PlaceHolder = "DataCap, 如32GiB或字节数"
+
+	result := widget.NewMultiLineEntry()
+	result.PlaceHolder = "公证人剩余额度/客户剩余DataCap"
+	res := binding.NewString()
+	result.Bind(res)
 
 	query := widget.NewButton("查询", func() {
 		if !globalVar.Locker.TryLock(0) {
@@ -735,26 +1008,135 @@ func getProposals() fyne.CanvasObject {
 			return
 		}
 
-		if msig.Text == "" {
+		if msig.Text == "" && client.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		globalVar.Process.Set(0)
+
+		var infos []*common.DataCapInfo
+		for _, addr := range []string{strings.TrimSpace(msig.Text), strings.TrimSpace(client.Text)} {
+			if addr == "" {
+				continue
+			}
+			info, err := globalVar.Handler.GetDataCap(context.TODO(), addr)
+			if err != nil {
+				globalVar.Fail(err)
+				return
+			}
+			infos = append(infos, info)
+		}
+		val, err := json.MarshalIndent(infos, "", "\t")
+		if err != nil {
+			globalVar.Fail(err)
+			return
+		}
+		res.Set(string(val))
+		globalVar.Process.Set(1)
+	})
+
+	submit := widget.NewButton("发起授予DataCap提案", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		if pk.Key() == "" || msig.Text == "" || client.Text == "" || allowance.Text == "" {
 			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
 
 		globalVar.Process.Set(0)
 
-		str, err := globalVar.Handler.GetPendingProposals(context.TODO(), strings.TrimSpace(msig.Text))
+		proposal := &common.Proposal{Msig: strings.TrimSpace(msig.Text)}
+		err := globalVar.Handler.AddVerifiedClient(context.TODO(), pk.Key(), strings.TrimSpace(client.Text),
+			strings.TrimSpace(allowance.Text), proposal)
+		if err != nil {
+			globalVar.Fail(err)
+		} else {
+			globalVar.Msg(common.Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
+			globalVar.Process.Set(1)
+		}
+	})
+
+	top := container.NewVBox(pk, container.NewGridWithColumns(3, msig, client, allowance),
+		container.NewGridWithColumns(2, query, submit))
+	return container.NewBorder(top, nil, nil, nil, result)
+}
+
+func offline() fyne.CanvasObject {
+	pkEntry := globalVar.NewKeyEntry()
+
+	unsignedEntry := widget.NewMultiLineEntry()
+	unsignedEntry.PlaceHolder = "待签名消息"
+
+	signedEntry := widget.NewMultiLineEntry()
+	signedEntry.PlaceHolder = "已签名消息"
+
+	signer := widget.NewButton("离线签名", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if pkEntry.Key() == "" || unsignedEntry.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
+			return
+		}
+
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		signed, err := globalVar.Handler.SignMessage(pkEntry.Key(), []byte(unsignedEntry.Text))
 		if err != nil {
 			globalVar.Msg(common.Warn, err.Error())
+		} else {
+			signedEntry.SetText(string(signed))
+		}
+	})
+
+	broadcast := widget.NewButton("广播", func() {
+		if !globalVar.Locker.TryLock(0) {
+			globalVar.Msg(common.Warn, "请稍后再试")
+			return
+		}
+		defer globalVar.Locker.Unlock()
+
+		if signedEntry.Text == "" {
+			globalVar.Msg(common.Warn, "输入为空")
 			return
 		}
 
-		if err = os.WriteFile("./待定提案.txt", str, 0666); err != nil {
+		if globalVar.Handler == nil {
+			globalVar.Msg(common.Error, "初始化异常")
+			return
+		}
+
+		globalVar.Process.Set(0)
+
+		c, ret, err := globalVar.Handler.Broadcast(context.TODO(), []byte(signedEntry.Text))
+		if err != nil {
+			globalVar.Msg(common.Warn, err.Error())
+			return
+		}
+		val, err := json.Marshal(ret)
+		if err != nil {
 			globalVar.Msg(common.Warn, err.Error())
 		} else {
-			globalVar.Msg(common.Info, "待定提案.txt 已生成")
+			globalVar.Msg(common.Info, fmt.Sprintf("广播完成: %s\n返回值: %s", c, val))
 			globalVar.Process.Set(1)
 		}
 	})
 
-	return container.NewVBox(container.NewGridWithColumns(2, msig, query), layout.NewSpacer())
-}
\ No newline at end of file
+	return container.NewVBox(pkEntry, unsignedEntry, signer, signedEntry, broadcast)
+}
@@ -0,0 +1,256 @@
+package common
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"github.com/filecoin-project/go-address"
+	"golang.org/x/xerrors"
+	"io"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const (
+	TagMiner    = "miner"
+	TagMultisig = "multisig"
+	TagAccount  = "account"
+	TagExchange = "exchange"
+)
+
+var AddressTags = []string{TagMiner, TagMultisig, TagAccount, TagExchange}
+
+type AddressBookEntry struct {
+	Address string
+	Label   string
+	Tag     string `json:",omitempty"`
+	Note    string `json:",omitempty"`
+}
+
+// AddressBook keeps the labelled addresses of the operator in a JSON file, rewritten on every change.
+type AddressBook struct {
+	path    string
+	entries map[string]AddressBookEntry
+	lk      sync.Mutex
+}
+
+func OpenAddressBook(path string) (*AddressBook, error) {
+	b := &AddressBook{path: path, entries: make(map[string]AddressBookEntry)}
+	val, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	} else if err != nil {
+		return nil, xerrors.Errorf("open address book %s error: %w", path, err)
+	}
+
+	var entries []AddressBookEntry
+	if err = json.Unmarshal(val, &entries); err != nil {
+		return nil, xerrors.Errorf("corrupted address book %s: %w", path, err)
+	}
+	for _, entry := range entries {
+		b.entries[entry.Address] = entry
+	}
+	return b, nil
+}
+
+// List returns the entries sorted by label.
+func (b *AddressBook) List() []AddressBookEntry {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	return b.list()
+}
+
+func (b *AddressBook) list() []AddressBookEntry {
+	entries := make([]AddressBookEntry, 0, len(b.entries))
+	for _, entry := range b.entries {
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Label != entries[j].Label {
+			return entries[i].Label < entries[j].Label
+		}
+		return entries[i].Address < entries[j].Address
+	})
+	return entries
+}
+
+func (b *AddressBook) Get(addr string) (AddressBookEntry, bool) {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	entry, found := b.entries[addr]
+	return entry, found
+}
+
+// Label returns the label of addr, or an empty string when addr is not in the book or the book is nil.
+func (b *AddressBook) Label(addr string) string {
+	if b == nil {
+		return ""
+	}
+	entry, _ := b.Get(addr)
+	return entry.Label
+}
+
+// Set adds entry or replaces the entry of the same address.
+func (b *AddressBook) Set(entry AddressBookEntry) error {
+	return b.Import([]AddressBookEntry{entry})
+}
+
+// Import adds or replaces all entries, nothing is saved when one of them is invalid.
+func (b *AddressBook) Import(entries []AddressBookEntry) error {
+	for i := range entries {
+		if err := entries[i].normalize(); err != nil {
+			return err
+		}
+	}
+
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	for _, entry := range entries {
+		b.entries[entry.Address] = entry
+	}
+	return b.save()
+}
+
+func (b *AddressBook) Delete(addr string) error {
+	b.lk.Lock()
+	defer b.lk.Unlock()
+
+	if _, found := b.entries[addr]; !found {
+		return xerrors.Errorf("%s is not in the address book", addr)
+	}
+	delete(b.entries, addr)
+	return b.save()
+}
+
+func (b *AddressBook) save() error {
+	val, err := json.MarshalIndent(b.list(), "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(b.path, val, 0600)
+}
+
+func (entry *AddressBookEntry) normalize() error {
+	addr, err := address.NewFromString(strings.TrimSpace(entry.Address))
+	if err != nil {
+		return xerrors.Errorf("invalid address %s: %w", entry.Address, err)
+	}
+	entry.Address = addr.String()
+	entry.Label = strings.TrimSpace(entry.Label)
+	entry.Tag = strings.ToLower(strings.TrimSpace(entry.Tag))
+	entry.Note = strings.TrimSpace(entry.Note)
+
+	if entry.Label == "" {
+		return xerrors.Errorf("label of %s is empty", entry.Address)
+	}
+	if entry.Tag == "" {
+		return nil
+	}
+	for _, tag := range AddressTags {
+		if entry.Tag == tag {
+			return nil
+		}
+	}
+	return xerrors.Errorf("unknown tag %s, expected one of %s", entry.Tag, strings.Join(AddressTags, ", "))
+}
+
+// ReadAddressCSV reads address,label[,tag[,note]] lines, skipping a header line starting with "address".
+func ReadAddressCSV(r io.Reader) ([]AddressBookEntry, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]AddressBookEntry, 0, len(records))
+	for i, record := range records {
+		if i == 0 && len(record) != 0 && strings.EqualFold(strings.TrimSpace(record[0]), "address") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 4 {
+			return nil, xerrors.Errorf("line %d: expected address,label[,tag[,note]], got %d fields", i+1, len(record))
+		}
+		entry := AddressBookEntry{Address: record[0], Label: record[1]}
+		if len(record) > 2 {
+			entry.Tag = record[2]
+		}
+		if len(record) > 3 {
+			entry.Note = record[3]
+		}
+		entries = append(entries, entry)
+	}
+	return entries, nil
+}
+
+func ReadAddressJSON(r io.Reader) ([]AddressBookEntry, error) {
+	var entries []AddressBookEntry
+	if err := json.NewDecoder(r).Decode(&entries); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+func WriteAddressCSV(w io.Writer, entries []AddressBookEntry) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"address", "label", "tag", "note"}); err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		if err := writer.Write([]string{entry.Address, entry.Label, entry.Tag, entry.Note}); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteAddressJSON(w io.Writer, entries []AddressBookEntry) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(entries)
+}
+
+// SetAddressBook sets the book used to label addresses, a nil book leaves them unlabelled.
+func (m *Handler) SetAddressBook(b *AddressBook) {
+	m.book = b
+}
+
+func (m *Handler) AddressBook() *AddressBook {
+	return m.book
+}
+
+// labels returns the labels of the addrs in the address book, nil when none has one.
+func (m *Handler) labels(addrs ...address.Address) map[string]string {
+	var res map[string]string
+	for _, addr := range addrs {
+		if m.book == nil {
+			break
+		}
+		if label := m.book.Label(addr.String()); label != "" {
+			if res == nil {
+				res = make(map[string]string)
+			}
+			res[addr.String()] = label
+		}
+	}
+	return res
+}
+
+// Describe returns addr followed by its label in the address book, if any. It may be called on a nil Handler.
+func (m *Handler) Describe(addr string) string {
+	if m == nil || m.book == nil {
+		return addr
+	}
+	if label := m.book.Label(addr); label != "" {
+		return addr + " (" + label + ")"
+	}
+	return addr
+}
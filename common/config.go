@@ -0,0 +1,98 @@
+package common
+
+import (
+	"context"
+	"encoding/base64"
+	"fil-assistant/chain"
+	"fil-assistant/lib"
+	"fil-assistant/utils"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+	"os"
+)
+
+const (
+	DefaultConfigPath  = "./config.toml"
+	DefaultWalletPath  = "./wallet"
+	DefaultHistoryPath = "./history.jsonl"
+	DefaultBookPath    = "./addressbook.json"
+)
+
+// LoadHandler reads the config file at path and connects a Handler to the configured node.
+func LoadHandler(ctx context.Context, path string, process func(float64) error) (*Handler, error) {
+	cfg, err := utils.ReadConfig(path)
+	if err != nil {
+		return nil, xerrors.Errorf("read %s error: %w", path, err)
+	}
+
+	var aesKey []byte
+	if len(cfg.AESKey) != 0 {
+		aesKey, err = base64.StdEncoding.DecodeString(cfg.AESKey)
+		if err != nil {
+			return nil, xerrors.Errorf("AES key %s decode error: %w", cfg.AESKey, err)
+		} else if len(aesKey) != 32 {
+			return nil, xerrors.Errorf("AES key length %d is invalid", len(aesKey))
+		}
+	}
+
+	maxFee, err := types.ParseFIL(cfg.MaxFee)
+	if err != nil {
+		return nil, err
+	}
+	gasFeeCap, err := types.BigFromString(cfg.GasFeeCap)
+	if err != nil {
+		return nil, err
+	}
+
+	wallet, err := openWallet(cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := chain.NewLotusRpcClient(ctx, cfg.EndPoint, cfg.ApiToken)
+	if err != nil {
+		return nil, xerrors.Errorf("initialization failed: %w", err)
+	}
+
+	h := NewHandler(client, abi.TokenAmount(maxFee), gasFeeCap, cfg.Confidence, aesKey, process)
+	h.SetWallet(wallet)
+
+	historyPath := cfg.HistoryPath
+	if historyPath == "" {
+		historyPath = DefaultHistoryPath
+	}
+	h.SetHistory(OpenHistory(historyPath))
+
+	bookPath := cfg.AddressBookPath
+	if bookPath == "" {
+		bookPath = DefaultBookPath
+	}
+	book, err := OpenAddressBook(bookPath)
+	if err != nil {
+		h.Close()
+		return nil, err
+	}
+	h.SetAddressBook(book)
+	return h, nil
+}
+
+// OpenWallet opens only the wallet of the config at path, or the default wallet when there is no config, for the
+// operations which run on an offline machine without a node.
+func OpenWallet(path string) (*lib.Wallet, error) {
+	cfg, err := utils.ReadConfig(path)
+	if os.IsNotExist(err) {
+		cfg = new(utils.Config)
+	} else if err != nil {
+		return nil, xerrors.Errorf("read %s error: %w", path, err)
+	}
+	return openWallet(cfg)
+}
+
+func openWallet(cfg *utils.Config) (*lib.Wallet, error) {
+	walletPath := cfg.WalletPath
+	if walletPath == "" {
+		walletPath = DefaultWalletPath
+	}
+	return lib.OpenWallet(walletPath)
+}
@@ -0,0 +1,355 @@
+package common
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"fil-assistant/chain"
+	"fil-assistant/utils"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	StatusPushed = "pushed"
+	StatusOK     = "ok"
+	StatusFailed = "failed"
+)
+
+// HistoryRecord is what the history keeps of a pushed message. Operation is the method called, for a proposal it is
+// the proposed method and Params are the decoded params of that method.
+type HistoryRecord struct {
+	Time      time.Time
+	Updated   time.Time
+	Operation string
+	From      string
+	To        string
+	Msig      string          `json:",omitempty"`
+	Params    json.RawMessage `json:",omitempty"`
+	Value     string
+	Nonce     uint64
+	Cid       string
+	Status    string
+	ExitCode  int64
+	GasUsed   int64
+	TxnID     string          `json:",omitempty"`
+	Return    json.RawMessage `json:",omitempty"`
+	Error     string          `json:",omitempty"`
+}
+
+// History is an append only JSON lines file. A message is appended when it is pushed and again when its receipt is
+// known, List keeps the latest line of every cid.
+type History struct {
+	path string
+	lk   sync.Mutex
+	// waiting holds the records pushed in this session which have no receipt yet, so that recording the receipt does
+	// not read the file back.
+	waiting map[string]*HistoryRecord
+}
+
+func OpenHistory(path string) *History {
+	return &History{path: path, waiting: make(map[string]*HistoryRecord)}
+}
+
+func (h *History) add(rec *HistoryRecord) error {
+	val, err := json.Marshal(rec)
+	if err != nil {
+		return err
+	}
+
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+	if _, err = file.Write(append(val, '\n')); err != nil {
+		return err
+	}
+	if rec.Status == StatusPushed {
+		h.waiting[rec.Cid] = rec
+	} else {
+		delete(h.waiting, rec.Cid)
+	}
+	return nil
+}
+
+// pushed returns a copy of the record of c if it was pushed in this session and still waits for its receipt, the
+// caller updates it without the lock and records it again with add.
+func (h *History) pushed(c cid.Cid) *HistoryRecord {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	rec, found := h.waiting[c.String()]
+	if !found {
+		return nil
+	}
+	r := *rec
+	return &r
+}
+
+// List returns the records, newest first.
+func (h *History) List() ([]*HistoryRecord, error) {
+	h.lk.Lock()
+	defer h.lk.Unlock()
+
+	file, err := os.Open(h.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	} else if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	var recs []*HistoryRecord
+	byCid := make(map[string]int)
+	scanner := bufio.NewScanner(file)
+	scanner.Buffer(nil, 1<<20)
+	for scanner.Scan() {
+		if len(bytes.TrimSpace(scanner.Bytes())) == 0 {
+			continue
+		}
+		rec := new(HistoryRecord)
+		if err = json.Unmarshal(scanner.Bytes(), rec); err != nil {
+			return nil, xerrors.Errorf("corrupted history %s: %w", h.path, err)
+		}
+		if i, found := byCid[rec.Cid]; found {
+			recs[i] = rec
+		} else {
+			byCid[rec.Cid] = len(recs)
+			recs = append(recs, rec)
+		}
+	}
+	if err = scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(recs, func(i, j int) bool {
+		return recs[i].Time.After(recs[j].Time)
+	})
+	return recs, nil
+}
+
+// HistoryQuery filters records. Text matches the operation, addresses, cid and TxnID, zero times are unbounded.
+type HistoryQuery struct {
+	Text  string
+	Since time.Time
+	Until time.Time
+}
+
+func (h *History) Search(q HistoryQuery) ([]*HistoryRecord, error) {
+	recs, err := h.List()
+	if err != nil {
+		return nil, err
+	}
+
+	res := make([]*HistoryRecord, 0, len(recs))
+	for _, rec := range recs {
+		if !q.Since.IsZero() && rec.Time.Before(q.Since) {
+			continue
+		}
+		if !q.Until.IsZero() && rec.Time.After(q.Until) {
+			continue
+		}
+		if q.Text != "" && !rec.matches(q.Text) {
+			continue
+		}
+		res = append(res, rec)
+	}
+	return res, nil
+}
+
+func (rec *HistoryRecord) matches(text string) bool {
+	text = strings.ToLower(text)
+	for _, field := range []string{rec.Operation, rec.From, rec.To, rec.Msig, rec.Cid, rec.TxnID, rec.Status} {
+		if strings.Contains(strings.ToLower(field), text) {
+			return true
+		}
+	}
+	return false
+}
+
+func WriteHistoryCSV(w io.Writer, recs []*HistoryRecord) error {
+	writer := csv.NewWriter(w)
+	header := []string{"time", "updated", "operation", "from", "to", "msig", "params", "value", "nonce", "cid",
+		"status", "exit_code", "gas_used", "txn_id", "return", "error"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, rec := range recs {
+		record := []string{rec.Time.Format(time.RFC3339), rec.Updated.Format(time.RFC3339), rec.Operation, rec.From,
+			rec.To, rec.Msig, string(rec.Params), rec.Value, strconv.FormatUint(rec.Nonce, 10), rec.Cid, rec.Status,
+			strconv.FormatInt(rec.ExitCode, 10), strconv.FormatInt(rec.GasUsed, 10), rec.TxnID, string(rec.Return),
+			rec.Error}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteHistoryJSON(w io.Writer, recs []*HistoryRecord) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(recs)
+}
+
+// SetHistory records every message the Handler pushes from now on, a nil history disables recording.
+func (m *Handler) SetHistory(h *History) {
+	m.history = h
+}
+
+func (m *Handler) History() *History {
+	return m.history
+}
+
+// push pushes signedMsg and records it in the history. Failing to record does not fail the operation, the message
+// is on its way already.
+func (m *Handler) push(ctx context.Context, signedMsg *types.SignedMessage) (cid.Cid, error) {
+	c, err := m.client.PushMsg(ctx, signedMsg)
+	if err != nil || m.history == nil {
+		return c, err
+	}
+
+	msg := &signedMsg.Message
+	now := time.Now()
+	rec := &HistoryRecord{
+		Time:    now,
+		Updated: now,
+		From:    msg.From.String(),
+		To:      msg.To.String(),
+		Value:   types.FIL(msg.Value).String(),
+		Nonce:   msg.Nonce,
+		Cid:     c.String(),
+		Status:  StatusPushed,
+	}
+	m.describe(ctx, msg, rec)
+	m.history.add(rec)
+	return c, nil
+}
+
+// wait waits for the message like chain.Node.WaitMessage and records its receipt. The lookup is returned with the
+// error of a non zero exit code too.
+func (m *Handler) wait(ctx context.Context, c cid.Cid, msg *types.Message) (*chain.MsgLookup, error) {
+	lookup, err := m.client.WaitMsgLookup(ctx, c, m.confidence)
+	if err == nil && lookup.Receipt.ExitCode != 0 {
+		err = xerrors.Errorf("WaitMessage executed, exit code %d", lookup.Receipt.ExitCode)
+	}
+	if m.history == nil {
+		return lookup, err
+	}
+
+	rec := m.history.pushed(c)
+	if rec == nil {
+		return lookup, err
+	}
+	rec.Updated = time.Now()
+	if lookup == nil {
+		rec.Status = StatusFailed
+		rec.Error = err.Error()
+	} else {
+		rec.ExitCode = int64(lookup.Receipt.ExitCode)
+		rec.GasUsed = lookup.Receipt.GasUsed
+		if lookup.Receipt.ExitCode != 0 {
+			rec.Status = StatusFailed
+		} else {
+			rec.Status = StatusOK
+			m.recordReturn(ctx, msg, lookup.Receipt.Return, rec)
+		}
+	}
+	m.history.add(rec)
+	return lookup, err
+}
+
+// describe fills the operation and the decoded params of msg, best effort.
+func (m *Handler) describe(ctx context.Context, msg *types.Message, rec *HistoryRecord) {
+	rec.Operation = "Method " + strconv.FormatUint(uint64(msg.Method), 10)
+	if msg.Method == builtin.MethodSend {
+		rec.Operation = "Send"
+		return
+	}
+
+	code, err := m.client.StateGetActorCode(ctx, msg.To)
+	if err != nil {
+		return
+	}
+	name, params, found := utils.LookupMethod(code, msg.Method)
+	if !found || params.UnmarshalCBOR(bytes.NewReader(msg.Params)) != nil {
+		return
+	}
+	rec.Operation = name
+
+	if txn, ok := params.(*multisig.TxnIDParams); ok {
+		rec.Msig = msg.To.String()
+		rec.TxnID = strconv.FormatInt(int64(txn.ID), 10)
+	}
+	proposal, ok := params.(*multisig.ProposeParams)
+	if !ok {
+		rec.Params, _ = json.Marshal(params)
+		return
+	}
+
+	rec.Msig = msg.To.String()
+	rec.To = proposal.To.String()
+	rec.Value = types.FIL(proposal.Value).String()
+	rec.Operation = "Propose Send"
+	if proposal.Method == builtin.MethodSend {
+		return
+	}
+	rec.Operation = "Propose Method " + strconv.FormatUint(uint64(proposal.Method), 10)
+	if code, err = m.client.StateGetActorCode(ctx, proposal.To); err != nil {
+		return
+	}
+	name, params, found = utils.LookupMethod(code, proposal.Method)
+	if !found || params.UnmarshalCBOR(bytes.NewReader(proposal.Params)) != nil {
+		return
+	}
+	rec.Operation = "Propose " + name
+	rec.Params, _ = json.Marshal(params)
+}
+
+func (m *Handler) recordReturn(ctx context.Context, msg *types.Message, ret []byte, rec *HistoryRecord) {
+	val, err := m.decodeReturn(ctx, msg, ret)
+	if err != nil || val == nil {
+		return
+	}
+
+	switch r := val.(type) {
+	case *multisig.ProposeReturn:
+		rec.TxnID = strconv.FormatInt(int64(r.TxnID), 10)
+	}
+	rec.Return, _ = json.Marshal(val)
+}
+
+// NewHistoryQuery parses since and until as 2006-01-02 dates, until is inclusive. Empty values are unbounded.
+func NewHistoryQuery(text, since, until string) (HistoryQuery, error) {
+	q := HistoryQuery{Text: text}
+	var err error
+	if since != "" {
+		if q.Since, err = time.ParseInLocation("2006-01-02", since, time.Local); err != nil {
+			return q, err
+		}
+	}
+	if until != "" {
+		if q.Until, err = time.ParseInLocation("2006-01-02", until, time.Local); err != nil {
+			return q, err
+		}
+		q.Until = q.Until.Add(24*time.Hour - time.Nanosecond)
+	}
+	return q, nil
+}
@@ -0,0 +1,151 @@
+package common
+
+import (
+	"github.com/filecoin-project/go-state-types/exitcode"
+	"github.com/filecoin-project/lotus/chain/types"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestHistory(t *testing.T) *History {
+	t.Helper()
+	return OpenHistory(filepath.Join(t.TempDir(), "history.jsonl"))
+}
+
+func TestHistoryList(t *testing.T) {
+	h := newTestHistory(t)
+	if recs, err := h.List(); err != nil || len(recs) != 0 {
+		t.Fatalf("new history: %d records, %v", len(recs), err)
+	}
+
+	start := time.Now()
+	for _, rec := range []*HistoryRecord{
+		{Time: start, Cid: "a", Status: StatusPushed},
+		{Time: start.Add(time.Second), Cid: "b", Status: StatusPushed},
+		{Time: start, Cid: "a", Status: StatusOK, GasUsed: 10},
+		{Time: start.Add(time.Second), Cid: "b", Status: StatusFailed, ExitCode: 16},
+	} {
+		if err := h.add(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	recs, err := h.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 2 || recs[0].Cid != "b" || recs[1].Cid != "a" {
+		t.Fatalf("got %d records, want b then a", len(recs))
+	}
+	if recs[0].Status != StatusFailed || recs[0].ExitCode != 16 || recs[1].Status != StatusOK || recs[1].GasUsed != 10 {
+		t.Errorf("latest lines not kept: %+v %+v", recs[0], recs[1])
+	}
+}
+
+func TestHistorySearch(t *testing.T) {
+	h := newTestHistory(t)
+	day := func(val string) time.Time {
+		tm, err := time.ParseInLocation("2006-01-02 15:04", val, time.Local)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return tm
+	}
+	for _, rec := range []*HistoryRecord{
+		{Time: day("2021-08-31 23:59"), Cid: "before", Operation: "Send"},
+		{Time: day("2021-09-01 00:00"), Cid: "first", Operation: "Send"},
+		{Time: day("2021-09-02 23:59"), Cid: "last", Operation: "Propose Send", TxnID: "7"},
+		{Time: day("2021-09-03 00:00"), Cid: "after", Operation: "Send"},
+	} {
+		if err := h.add(rec); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	for _, c := range []struct{ text, since, until, want string }{
+		{"", "2021-09-01", "2021-09-02", "last first"},
+		{"", "2021-09-02", "2021-09-02", "last"},
+		{"", "", "2021-08-31", "before"},
+		{"", "2021-09-03", "", "after"},
+		{"propose", "", "", "last"},
+		{"7", "2021-09-01", "", "last"},
+		{"send", "2021-09-03", "", "after"},
+	} {
+		q, err := NewHistoryQuery(c.text, c.since, c.until)
+		if err != nil {
+			t.Fatal(err)
+		}
+		recs, err := h.Search(q)
+		if err != nil {
+			t.Fatal(err)
+		}
+		got := ""
+		for _, rec := range recs {
+			if got != "" {
+				got += " "
+			}
+			got += rec.Cid
+		}
+		if got != c.want {
+			t.Errorf("search %q from %q to %q: got %q, want %q", c.text, c.since, c.until, got, c.want)
+		}
+	}
+
+	if _, err := NewHistoryQuery("", "2021/09/01", ""); err == nil {
+		t.Error("invalid date accepted")
+	}
+}
+
+// TestHistoryRecordsReceipts pushes through the Handler and checks the line appended once the receipt is known.
+func TestHistoryRecordsReceipts(t *testing.T) {
+	e := newTestEnv(t)
+	e.h.SetHistory(newTestHistory(t))
+	pkA, a := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	_, to := newKey(t, types.KTSecp256k1)
+	msig := e.newMultisig(t, "2", pkA, pkB)
+
+	if err := e.h.Send(testCtx, pkA, to.String(), "1", nil); err != nil {
+		t.Fatal(err)
+	}
+	proposal := &Proposal{Msig: msig}
+	if err := e.h.Send(testCtx, pkA, to.String(), "2", proposal); err != nil {
+		t.Fatal(err)
+	}
+	e.node.Exec = func(msg *types.Message) (types.MessageReceipt, bool) {
+		return types.MessageReceipt{ExitCode: exitcode.ErrForbidden}, true
+	}
+	if err := e.h.Send(testCtx, pkA, to.String(), "3", nil); err == nil {
+		t.Fatal("failed message reported as executed")
+	}
+
+	recs, err := e.h.History().List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(recs) != 4 {
+		t.Fatalf("%d records, want 4", len(recs))
+	}
+	byValue := make(map[string]*HistoryRecord)
+	for _, rec := range recs {
+		if rec.From != a.String() {
+			t.Errorf("record from %s, want %s", rec.From, a)
+		}
+		byValue[rec.Value] = rec
+	}
+
+	if rec := byValue["1 FIL"]; rec == nil || rec.Status != StatusOK || rec.Operation != "Send" || rec.GasUsed == 0 {
+		t.Errorf("send %+v", rec)
+	}
+	if rec := byValue["2 FIL"]; rec == nil || rec.Status != StatusOK || rec.Operation != "Propose Send" ||
+		rec.Msig != msig || rec.TxnID != proposal.TxnID {
+		t.Errorf("proposal %+v", rec)
+	}
+	if rec := byValue["3 FIL"]; rec == nil || rec.Status != StatusFailed || rec.ExitCode != int64(exitcode.ErrForbidden) {
+		t.Errorf("failed send %+v", rec)
+	}
+	if len(e.h.History().waiting) != 0 {
+		t.Errorf("%d records still wait for their receipt", len(e.h.History().waiting))
+	}
+}
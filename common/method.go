@@ -17,6 +17,7 @@ import (
 	"github.com/filecoin-project/specs-actors/v6/actors/builtin/miner"
 	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
 	"golang.org/x/xerrors"
+	"strings"
 )
 
 type Handler struct {
@@ -24,19 +25,24 @@ type Handler struct {
 	confidence 		uint64
 	maxFee 			abi.TokenAmount
 	gasFeeCap 		types.BigInt
-	client 			*chain.LotusClient
+	client 			chain.Node
 	block 			cipher.Block
+	wallet 			*lib.Wallet
+	history 		*History
+	book 			*AddressBook
 }
 
-func newHandler(ctx context.Context, ipAddr, token string, maxFee abi.TokenAmount, gasFeeCap types.BigInt,
-	confidence uint64, key []byte, process func(float64) error) (*Handler, error) {
-	client, err := chain.NewLotusRpcClient(ctx, ipAddr, token)
-	if err != nil {
-		return nil, err
-	}
-
+// NewHandler runs the operations against client, which may be a LotusClient or any other chain.Node. key is the
+// legacy AES key, only used to migrate old ciphertexts, and may be nil. The Handler has no wallet, so operations need
+// a private key or prepare the message for offline signing.
+func NewHandler(client chain.Node, maxFee abi.TokenAmount, gasFeeCap types.BigInt, confidence uint64, key []byte,
+	process func(float64) error) *Handler {
 	block, _ := aes.NewCipher(key)
 
+	if process == nil {
+		process = func(float64) error { return nil }
+	}
+
 	return &Handler{
 		process: 	process,
 		confidence: confidence,
@@ -44,10 +50,49 @@ func newHandler(ctx context.Context, ipAddr, token string, maxFee abi.TokenAmoun
 		gasFeeCap:  gasFeeCap,
 		client:     client,
 		block: 		block,
-	}, nil
+	}
+}
+
+// sender is the account a message is sent from. key is nil when the operator only gave an address which is not in
+// the wallet, the messages of such a sender are prepared for offline signing instead of being pushed.
+type sender struct {
+	addr 		address.Address
+	signer 		lib.Signer
+	key 		*types.KeyInfo
+}
+
+// sender accepts a hex encoded private key, a mnemonic as parsePrivateKey takes it, or an address, whose key is looked
+// up in the wallet. While the wallet is locked an address only prepares the message, as when the wallet lacks it.
+func (m *Handler) sender(pk string) (*sender, error) {
+	if addr, err := address.NewFromString(pk); err == nil && m.WalletLocked() {
+		return &sender{addr: addr}, nil
+	}
+	return walletSender(m.wallet, pk)
+}
+
+// walletSender is sender for the operations which run without a Handler, w may be nil.
+func walletSender(w *lib.Wallet, pk string) (*sender, error) {
+	var pki *types.KeyInfo
+	if addr, err := address.NewFromString(pk); err == nil {
+		if w == nil || !w.Has(addr) {
+			return &sender{addr: addr}, nil
+		}
+		if pki, err = w.Key(addr); err != nil {
+			return nil, err
+		}
+	} else if pki, err = parsePrivateKey(pk); err != nil {
+		return nil, err
+	}
+
+	signer := lib.ChooseSigner(pki.Type)
+	addr, err := signer.ToAddress(pki.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	return &sender{addr: addr, signer: signer, key: pki}, nil
 }
 
-func (m *Handler) messagePush(ctx context.Context, rawMsg *types.Message, pk *types.KeyInfo, signer lib.Signer, start int) ([]byte, error) {
+func (m *Handler) messagePush(ctx context.Context, rawMsg *types.Message, from *sender, start int) ([]byte, error) {
 	m.process(float64(start + 1) / float64(start + 6))
 	bal, err := m.client.GetBalance(ctx, rawMsg.From)
 	if err != nil {
@@ -71,18 +116,31 @@ func (m *Handler) messagePush(ctx context.Context, rawMsg *types.Message, pk *ty
 		return nil, err
 	}
 
+	if from.key == nil {
+		m.process(1)
+		return nil, &PreparedError{Message: newMsg}
+	}
+
 	m.process(float64(start + 4) / float64(start + 6))
-	c, err := m.client.SendMsg(ctx, pk.PrivateKey, newMsg, signer)
+	signedMsg, err := lib.SignMessage(from.signer, from.key.PrivateKey, newMsg)
+	if err != nil {
+		return nil, err
+	}
+	c, err := m.push(ctx, signedMsg)
 	if err != nil {
 		return nil, err
 	}
 
 	m.process(float64(start + 5) / float64(start + 6))
-	return m.client.WaitMessage(ctx, c, m.confidence)
+	lookup, err := m.wait(ctx, c, newMsg)
+	if err != nil {
+		return nil, err
+	}
+	return lookup.Receipt.Return, nil
 }
 
 func (m *Handler) Send(ctx context.Context, pk, toAddr, amount string, proposal *Proposal) error {
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -98,19 +156,13 @@ func (m *Handler) Send(ctx context.Context, pk, toAddr, amount string, proposal
 	}
 
 	if proposal == nil {
-		signer := lib.ChooseSigner(pki.Type)
-		from, err := signer.ToAddress(pki.PrivateKey)
-		if err != nil {
-			return err
-		}
-
 		rawMsg := &types.Message{
-			From:       from,
+			From:       from.addr,
 			To:         to,
 			Value:      abi.TokenAmount(amnt),
 			Method:     builtin.MethodSend,
 		}
-		_, err = m.messagePush(ctx, rawMsg, pki, signer, 0)
+		_, err = m.messagePush(ctx, rawMsg, from, 0)
 		return err
 	} else {
 		msigAddr, err := address.NewFromString(proposal.Msig)
@@ -118,7 +170,7 @@ func (m *Handler) Send(ctx context.Context, pk, toAddr, amount string, proposal
 			return err
 		}
 
-		proposal.TxnID, err = m.propose(ctx, pki, msigAddr, &multisig.ProposeParams{
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
 			To: to,
 			Value: abi.TokenAmount(amnt),
 			Method: builtin.MethodSend,
@@ -133,7 +185,7 @@ func (m *Handler) ChangeOwner1(ctx context.Context, pk, newAddr, minerID string,
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -159,19 +211,13 @@ func (m *Handler) ChangeOwner1(ctx context.Context, pk, newAddr, minerID string,
 	}
 
 	if proposal == nil {
-		signer := lib.ChooseSigner(pki.Type)
-		from, err := signer.ToAddress(pki.PrivateKey)
-		if err != nil {
-			return err
-		}
-
 		_, err = m.messagePush(ctx, &types.Message{
-			From:       from,
+			From:       from.addr,
 			To:         mID,
 			Value:      abi.NewTokenAmount(0),
 			Method:     builtin.MethodsMiner.ChangeOwnerAddress,
 			Params:     enc,
-		}, pki, signer, num)
+		}, from, num)
 		return err
 	} else {
 		msigAddr, err := address.NewFromString(proposal.Msig)
@@ -179,7 +225,7 @@ func (m *Handler) ChangeOwner1(ctx context.Context, pk, newAddr, minerID string,
 			return err
 		}
 
-		proposal.TxnID, err = m.propose(ctx, pki, msigAddr, &multisig.ProposeParams{
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
 			To: mID,
 			Value: abi.NewTokenAmount(0),
 			Method: builtin.MethodsMiner.ChangeOwnerAddress,
@@ -195,20 +241,14 @@ func (m *Handler) ChangeOwner2(ctx context.Context, pk, minerID string, proposal
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
 
 	if proposal == nil {
-		signer := lib.ChooseSigner(pki.Type)
-		from, err := signer.ToAddress(pki.PrivateKey)
-		if err != nil {
-			return err
-		}
-
 		m.process(1 / float64(1 + 6))
-		fromId, err := m.client.LookupID(ctx, from)
+		fromId, err := m.client.LookupID(ctx, from.addr)
 		if err != nil {
 			return err
 		}
@@ -219,12 +259,12 @@ func (m *Handler) ChangeOwner2(ctx context.Context, pk, minerID string, proposal
 		}
 
 		_, err = m.messagePush(ctx, &types.Message{
-			From:       from,
+			From:       from.addr,
 			To:         mID,
 			Value:      abi.NewTokenAmount(0),
 			Method:     builtin.MethodsMiner.ChangeOwnerAddress,
 			Params:     enc,
-		}, pki, signer, 1)
+		}, from, 1)
 		return err
 	} else {
 		msigAddr, err := address.NewFromString(proposal.Msig)
@@ -237,7 +277,7 @@ func (m *Handler) ChangeOwner2(ctx context.Context, pk, minerID string, proposal
 			return err
 		}
 
-		proposal.TxnID, err = m.propose(ctx, pki, msigAddr, &multisig.ProposeParams{
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
 			To: mID,
 			Value: abi.NewTokenAmount(0),
 			Method: builtin.MethodsMiner.ChangeOwnerAddress,
@@ -247,52 +287,40 @@ func (m *Handler) ChangeOwner2(ctx context.Context, pk, minerID string, proposal
 	}
 }
 
+// Withdraw withdraws amount FIL from the available balance of a miner, or all of it, read at send time, when amount
+// is empty.
 func (m *Handler) Withdraw(ctx context.Context, pk, minerID, amount string, proposal *Proposal) error {
 	mID, err := address.NewFromString(minerID)
 	if err != nil {
 		return err
 	}
 
-	amnt, err := types.ParseFIL(amount)
-	if err != nil {
-		return err
-	}
-
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
 
 	m.process(1 / float64(1 + 6))
-	avail, err := m.client.GetMinerAvailableBalance(ctx, mID)
+	amnt, err := m.WithdrawPreflight(ctx, minerID, amount)
 	if err != nil {
 		return err
 	}
-	if avail.LessThan(types.BigInt(amnt)) {
-		return xerrors.Errorf("avail balance %s is less than withdraw amount %s", types.FIL(avail).String(), amnt.String())
-	}
 
 	enc, err := actors.SerializeParams(&miner.WithdrawBalanceParams{
-		AmountRequested: abi.TokenAmount(amnt),
+		AmountRequested: amnt,
 	})
 	if err != nil {
 		return err
 	}
 
 	if proposal == nil {
-		signer := lib.ChooseSigner(pki.Type)
-		from, err := signer.ToAddress(pki.PrivateKey)
-		if err != nil {
-			return err
-		}
-
 		_, err = m.messagePush(ctx, &types.Message{
-			From:       from,
+			From:       from.addr,
 			To:         mID,
 			Value:      abi.NewTokenAmount(0),
 			Method:     builtin.MethodsMiner.WithdrawBalance,
 			Params:     enc,
-		}, pki, signer, 1)
+		}, from, 1)
 		return err
 	} else {
 		msigAddr, err := address.NewFromString(proposal.Msig)
@@ -300,7 +328,7 @@ func (m *Handler) Withdraw(ctx context.Context, pk, minerID, amount string, prop
 			return err
 		}
 
-		proposal.TxnID, err = m.propose(ctx, pki, msigAddr, &multisig.ProposeParams{
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
 			To: mID,
 			Value: abi.NewTokenAmount(0),
 			Method: builtin.MethodsMiner.WithdrawBalance,
@@ -337,7 +365,7 @@ func (m *Handler) ProposeChangeWorker(ctx context.Context, pk, minerID, newWorke
 		num++
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -374,19 +402,13 @@ func (m *Handler) ProposeChangeWorker(ctx context.Context, pk, minerID, newWorke
 	}
 
 	if proposal == nil {
-		signer := lib.ChooseSigner(pki.Type)
-		from, err := signer.ToAddress(pki.PrivateKey)
-		if err != nil {
-			return err
-		}
-
 		_, err = m.messagePush(ctx, &types.Message{
-			From:       from,
+			From:       from.addr,
 			To:         mID,
 			Value:      abi.NewTokenAmount(0),
 			Method:     builtin.MethodsMiner.ChangeWorkerAddress,
 			Params:     enc,
-		}, pki, signer, num)
+		}, from, num)
 		return err
 	} else {
 		msigAddr, err := address.NewFromString(proposal.Msig)
@@ -394,7 +416,7 @@ func (m *Handler) ProposeChangeWorker(ctx context.Context, pk, minerID, newWorke
 			return err
 		}
 
-		proposal.TxnID, err = m.propose(ctx, pki, msigAddr, &multisig.ProposeParams{
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
 			To: mID,
 			Value: abi.NewTokenAmount(0),
 			Method: builtin.MethodsMiner.ChangeWorkerAddress,
@@ -410,24 +432,18 @@ func (m *Handler) ConfirmChangeWorker(ctx context.Context, pk, minerID string, p
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
 
 	if proposal == nil {
-		signer := lib.ChooseSigner(pki.Type)
-		from, err := signer.ToAddress(pki.PrivateKey)
-		if err != nil {
-			return err
-		}
-
 		_, err = m.messagePush(ctx, &types.Message{
-			From:       from,
+			From:       from.addr,
 			To:         mID,
 			Value:      abi.NewTokenAmount(0),
 			Method:     builtin.MethodsMiner.ConfirmUpdateWorkerKey,
-		}, pki, signer, 0)
+		}, from, 0)
 		return err
 	} else {
 		msigAddr, err := address.NewFromString(proposal.Msig)
@@ -435,7 +451,7 @@ func (m *Handler) ConfirmChangeWorker(ctx context.Context, pk, minerID string, p
 			return err
 		}
 
-		proposal.TxnID, err = m.propose(ctx, pki, msigAddr, &multisig.ProposeParams{
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
 			To: mID,
 			Value: abi.NewTokenAmount(0),
 			Method: builtin.MethodsMiner.ConfirmUpdateWorkerKey,
@@ -444,45 +460,44 @@ func (m *Handler) ConfirmChangeWorker(ctx context.Context, pk, minerID string, p
 	}
 }
 
-func (m *Handler) Encrypt(pk string) (string, string, error) {
-	if m.block == nil {
-		return "", "", xerrors.New("no aes key for encryption")
-	}
-
+// Encrypt seals the private key into a keystore protected by passphrase.
+func (m *Handler) Encrypt(pk, passphrase string) (string, string, error) {
 	pki, err := parsePrivateKey(pk)
 	if err != nil {
 		return "", "", err
 	}
-	if len(pki.PrivateKey) != 32 {
-		return "", "", xerrors.Errorf("invalid private key size %d, should be 32", len(pki.PrivateKey))
-	}
 
-	newKi := &types.KeyInfo{
-		Type:       pki.Type,
-		PrivateKey: make([]byte, 32),
+	return encryptKey(pki, passphrase)
+}
+
+// Decrypt opens a keystore produced by Encrypt and returns the hex encoded private key.
+func (m *Handler) Decrypt(keystore, passphrase string) (string, string, error) {
+	ks, err := lib.ParseKeystore([]byte(keystore))
+	if err != nil {
+		return "", "", err
 	}
-	m.block.Encrypt(newKi.PrivateKey, pki.PrivateKey)
-	m.block.Encrypt(newKi.PrivateKey[16:], pki.PrivateKey[16:])
 
-	addr, err := lib.ChooseSigner(pki.Type).ToAddress(pki.PrivateKey)
+	pki, err := lib.DecryptKey(ks, passphrase)
 	if err != nil {
 		return "", "", err
 	}
 
-	val, err := json.Marshal(newKi)
+	val, err := json.Marshal(pki)
 	if err != nil {
 		return "", "", err
 	} else {
-		return addr.String(), hex.EncodeToString(val), nil
+		return ks.Address, hex.EncodeToString(val), nil
 	}
 }
 
-func (m *Handler) Decrypt(pk string) (string, string, error) {
+// MigrateKey converts a private key encrypted by the former Encrypt, as two raw AES blocks under the AESKey of
+// config.toml, into a keystore protected by passphrase.
+func (m *Handler) MigrateKey(legacy, passphrase string) (string, string, error) {
 	if m.block == nil {
 		return "", "", xerrors.New("no aes key for decryption")
 	}
 
-	pki, err := parsePrivateKey(pk)
+	pki, err := parsePrivateKey(legacy)
 	if err != nil {
 		return "", "", err
 	}
@@ -498,17 +513,7 @@ func (m *Handler) Decrypt(pk string) (string, string, error) {
 	m.block.Decrypt(rawKi.PrivateKey, pki.PrivateKey)
 	m.block.Decrypt(rawKi.PrivateKey[16:], pki.PrivateKey[16:])
 
-	addr, err := lib.ChooseSigner(rawKi.Type).ToAddress(rawKi.PrivateKey)
-	if err != nil {
-		return "", "", err
-	}
-
-	val, err := json.Marshal(rawKi)
-	if err != nil {
-		return "", "", err
-	} else {
-		return addr.String(), hex.EncodeToString(val), nil
-	}
+	return encryptKey(rawKi, passphrase)
 }
 
 func (m *Handler) Sign(pk, msg string) (string, error) {
@@ -517,17 +522,19 @@ func (m *Handler) Sign(pk, msg string) (string, error) {
 		return "", err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return "", err
+	} else if from.key == nil {
+		return "", xerrors.Errorf("no private key for %s", from.addr)
 	}
 
-	sig, err := lib.ChooseSigner(pki.Type).Sign(pki.PrivateKey, raw)
+	sig, err := from.signer.Sign(from.key.PrivateKey, raw)
 	if err != nil {
 		return "", err
 	} else {
 		var t crypto.SigType
-		switch pki.Type {
+		switch from.key.Type {
 		case types.KTBLS:
 			t = crypto.SigTypeBLS
 		case types.KTSecp256k1:
@@ -541,15 +548,63 @@ func (m *Handler) Sign(pk, msg string) (string, error) {
 	}
 }
 
+// Verify checks a signature in the format Sign returns, the signature type byte followed by the signature.
+func (m *Handler) Verify(addr, msg, sig string) error {
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return err
+	}
+	raw, err := hex.DecodeString(msg)
+	if err != nil {
+		return err
+	}
+	sigBytes, err := hex.DecodeString(sig)
+	if err != nil {
+		return err
+	} else if len(sigBytes) < 2 {
+		return xerrors.Errorf("signature is too short")
+	}
+
+	signer, err := lib.SignerOf(crypto.SigType(sigBytes[0]))
+	if err != nil {
+		return err
+	}
+	return signer.Verify(a, raw, sigBytes[1:])
+}
+
 func (m *Handler) Close() {
 	m.client.Close()
 }
 
+// parsePrivateKey accepts a hex encoded KeyInfo, or a BIP39 phrase optionally followed by the derivation path of the
+// key, lib.FilecoinPath(0) by default.
 func parsePrivateKey(pk string) (*types.KeyInfo, error) {
+	if words := strings.Fields(pk); len(words) > 1 {
+		path := lib.FilecoinPath(0)
+		if last := words[len(words)-1]; strings.HasPrefix(last, "m/") {
+			path, words = last, words[:len(words)-1]
+		}
+		return lib.MnemonicKey(strings.Join(words, " "), path)
+	}
+
 	p, err := hex.DecodeString(pk)
 	if err != nil {
 		return nil, err
 	}
 	pki := new(types.KeyInfo)
 	return pki, json.Unmarshal(p, pki)
+}
+
+func encryptKey(pki *types.KeyInfo, passphrase string) (string, string, error) {
+	ks, err := lib.EncryptKey(pki, passphrase)
+	if err != nil {
+		return "", "", err
+	}
+
+	val, err := json.Marshal(ks)
+	if err != nil {
+		return "", "", err
+	} else {
+		return ks.Address, string(val), nil
+	}
 }
\ No newline at end of file
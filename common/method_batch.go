@@ -0,0 +1,199 @@
+package common
+
+import (
+	"context"
+	"encoding/csv"
+	"fil-assistant/lib"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+	"io"
+	"strconv"
+	"strings"
+)
+
+// BatchItem is one line of a batch transfer CSV: to, amount in FIL and an optional memo kept for the result file.
+type BatchItem struct {
+	To     string
+	Amount string
+	Memo   string
+}
+
+// BatchResult is the outcome of a BatchItem. ExitCode is -1 until the message is executed, Error tells why not.
+type BatchResult struct {
+	BatchItem
+	Cid      string
+	ExitCode int64
+	Error    string
+}
+
+// ParseBatchCSV reads to,amount[,memo] lines, skipping a header line starting with "to".
+func ParseBatchCSV(r io.Reader) ([]BatchItem, error) {
+	reader := csv.NewReader(r)
+	reader.FieldsPerRecord = -1
+	reader.TrimLeadingSpace = true
+
+	records, err := reader.ReadAll()
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]BatchItem, 0, len(records))
+	for i, record := range records {
+		if i == 0 && len(record) != 0 && strings.EqualFold(strings.TrimSpace(record[0]), "to") {
+			continue
+		}
+		if len(record) < 2 || len(record) > 3 {
+			return nil, xerrors.Errorf("line %d: expected to,amount[,memo], got %d fields", i+1, len(record))
+		}
+		item := BatchItem{To: strings.TrimSpace(record[0]), Amount: strings.TrimSpace(record[1])}
+		if len(record) == 3 {
+			item.Memo = strings.TrimSpace(record[2])
+		}
+		items = append(items, item)
+	}
+	if len(items) == 0 {
+		return nil, xerrors.New("batch is empty")
+	}
+	return items, nil
+}
+
+func WriteBatchResult(w io.Writer, results []BatchResult) error {
+	writer := csv.NewWriter(w)
+	if err := writer.Write([]string{"to", "amount", "memo", "cid", "exit_code", "error"}); err != nil {
+		return err
+	}
+	for _, res := range results {
+		record := []string{res.To, res.Amount, res.Memo, res.Cid, strconv.FormatInt(res.ExitCode, 10), res.Error}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+// BatchTotal validates every line and returns the total amount, for the operator to confirm.
+func BatchTotal(items []BatchItem) (string, error) {
+	_, _, total, err := parseBatch(items)
+	if err != nil {
+		return "", err
+	}
+	return types.FIL(total).String(), nil
+}
+
+func parseBatch(items []BatchItem) ([]address.Address, []abi.TokenAmount, abi.TokenAmount, error) {
+	tos := make([]address.Address, 0, len(items))
+	amounts := make([]abi.TokenAmount, 0, len(items))
+	total := abi.NewTokenAmount(0)
+	for i, item := range items {
+		to, err := address.NewFromString(item.To)
+		if err != nil {
+			return nil, nil, total, xerrors.Errorf("line %d: invalid address %s: %w", i+1, item.To, err)
+		}
+		amount, err := types.ParseFIL(item.Amount)
+		if err != nil {
+			return nil, nil, total, xerrors.Errorf("line %d: invalid amount %s: %w", i+1, item.Amount, err)
+		}
+		if amount.Sign() <= 0 {
+			return nil, nil, total, xerrors.Errorf("line %d: amount %s is not positive", i+1, item.Amount)
+		}
+		tos = append(tos, to)
+		amounts = append(amounts, abi.TokenAmount(amount))
+		total = types.BigAdd(total, abi.TokenAmount(amount))
+	}
+	return tos, amounts, total, nil
+}
+
+// SendBatch pushes one transfer per item with consecutive nonces, then waits for all of them. The whole batch is
+// checked against the sender balance before anything is pushed; when a push fails the rest is not sent, since
+// their nonces could never be mined. The returned error only reports failures before the first push.
+func (m *Handler) SendBatch(ctx context.Context, pk string, items []BatchItem) ([]BatchResult, error) {
+	from, err := m.sender(pk)
+	if err != nil {
+		return nil, err
+	} else if from.key == nil {
+		return nil, xerrors.Errorf("no private key for %s, batch transfers can not be signed offline", from.addr)
+	}
+
+	tos, amounts, total, err := parseBatch(items)
+	if err != nil {
+		return nil, err
+	}
+
+	steps := float64(2 * len(items) + 2)
+	m.process(1 / steps)
+	bal, err := m.client.GetBalance(ctx, from.addr)
+	if err != nil {
+		return nil, err
+	}
+	need := types.BigAdd(total, types.BigMul(m.maxFee, types.NewInt(uint64(len(items)))))
+	if bal.LessThan(need) {
+		return nil, xerrors.Errorf("sender balance %s is less than %s", types.FIL(bal).String(), types.FIL(need).String())
+	}
+
+	nonce, err := m.client.GetNonce(ctx, from.addr)
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([]BatchResult, len(items))
+	cids := make([]cid.Cid, len(items))
+	msgs := make([]*types.Message, len(items))
+	failed := false
+	for i, item := range items {
+		results[i] = BatchResult{BatchItem: item, ExitCode: -1}
+		if failed {
+			results[i].Error = "not sent"
+			continue
+		}
+
+		m.process(float64(i + 2) / steps)
+		msgs[i], cids[i], err = m.pushTransfer(ctx, from, tos[i], amounts[i], nonce + uint64(i))
+		if err != nil {
+			results[i].Error = err.Error()
+			failed = true
+			continue
+		}
+		results[i].Cid = cids[i].String()
+	}
+
+	for i, c := range cids {
+		if !c.Defined() {
+			continue
+		}
+		m.process(float64(len(items) + i + 2) / steps)
+		lookup, err := m.wait(ctx, c, msgs[i])
+		if lookup != nil {
+			results[i].ExitCode = int64(lookup.Receipt.ExitCode)
+		} else if err != nil {
+			results[i].Error = err.Error()
+		}
+	}
+	return results, nil
+}
+
+func (m *Handler) pushTransfer(ctx context.Context, from *sender, to address.Address, amount abi.TokenAmount,
+	nonce uint64) (*types.Message, cid.Cid, error) {
+	newMsg, err := m.client.EstimateMessageGas(ctx, m.maxFee, &types.Message{
+		From:       from.addr,
+		To:         to,
+		Value:      amount,
+		Method:     builtin.MethodSend,
+		Nonce:      nonce,
+		GasFeeCap:  m.gasFeeCap,
+	})
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+
+	signedMsg, err := lib.SignMessage(from.signer, from.key.PrivateKey, newMsg)
+	if err != nil {
+		return nil, cid.Undef, err
+	}
+	c, err := m.push(ctx, signedMsg)
+	return newMsg, c, err
+}
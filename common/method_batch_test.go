@@ -0,0 +1,127 @@
+package common
+
+import (
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+	"strings"
+	"testing"
+)
+
+func TestParseBatchCSV(t *testing.T) {
+	_, to := newKey(t, types.KTSecp256k1)
+
+	for name, csv := range map[string]string{
+		"header":    "to,amount,memo\n" + to.String() + ",1,rent\n" + to.String() + ",2\n",
+		"no header": to.String() + ", 1, rent\n" + to.String() + ",2\n",
+	} {
+		items, err := ParseBatchCSV(strings.NewReader(csv))
+		if err != nil {
+			t.Fatalf("%s: %s", name, err)
+		}
+		want := []BatchItem{{To: to.String(), Amount: "1", Memo: "rent"}, {To: to.String(), Amount: "2"}}
+		if len(items) != len(want) || items[0] != want[0] || items[1] != want[1] {
+			t.Errorf("%s: got %+v, want %+v", name, items, want)
+		}
+	}
+
+	for _, bad := range []struct{ name, csv, err string }{
+		{"one field", to.String() + ",1\n" + to.String() + "\n", "line 2"},
+		{"four fields", to.String() + ",1,memo,extra\n", "line 1"},
+		{"only header", "to,amount\n", "empty"},
+		{"empty", "", "empty"},
+	} {
+		if _, err := ParseBatchCSV(strings.NewReader(bad.csv)); err == nil || !strings.Contains(err.Error(), bad.err) {
+			t.Errorf("%s: got %v, want %s", bad.name, err, bad.err)
+		}
+	}
+}
+
+func TestParseBatchAmounts(t *testing.T) {
+	_, to := newKey(t, types.KTSecp256k1)
+
+	for _, amount := range []string{"0", "-1", "abc"} {
+		if _, err := BatchTotal([]BatchItem{{To: to.String(), Amount: "1"}, {To: to.String(), Amount: amount}}); err == nil {
+			t.Errorf("amount %s accepted", amount)
+		} else if !strings.Contains(err.Error(), "line 2") {
+			t.Errorf("amount %s: %s does not name the line", amount, err)
+		}
+	}
+	if _, err := BatchTotal([]BatchItem{{To: "f1nope", Amount: "1"}}); err == nil {
+		t.Error("invalid address accepted")
+	}
+
+	total, err := BatchTotal([]BatchItem{{To: to.String(), Amount: "1.5"}, {To: to.String(), Amount: "0.25"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if total != "1.75 FIL" {
+		t.Errorf("total %s, want 1.75 FIL", total)
+	}
+}
+
+// TestSendBatchBalance checks the total with a MaxFee of 0.1 FIL for each transfer.
+func TestSendBatchBalance(t *testing.T) {
+	e := newTestEnv(t)
+	pk, _ := e.newAccount(t, "2")
+	_, a := newKey(t, types.KTSecp256k1)
+	_, b := newKey(t, types.KTSecp256k1)
+
+	items := []BatchItem{{To: a.String(), Amount: "1"}, {To: b.String(), Amount: "0.81"}}
+	if _, err := e.h.SendBatch(testCtx, pk, items); err == nil {
+		t.Fatal("sent a batch whose total and fees are above the balance")
+	}
+	if pushed := len(e.node.Pushed()); pushed != 0 {
+		t.Fatalf("%d messages pushed, want none", pushed)
+	}
+
+	items[1].Amount = "0.8"
+	results, err := e.h.SendBatch(testCtx, pk, items)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, res := range results {
+		if res.ExitCode != 0 || res.Cid == "" || res.Error != "" {
+			t.Errorf("%+v", res)
+		}
+	}
+	checkBalance(t, e, a, "1")
+	checkBalance(t, e, b, "0.8")
+}
+
+func TestSendBatchPushFails(t *testing.T) {
+	e := newTestEnv(t)
+	pk, _ := e.newAccount(t, "10")
+	_, a := newKey(t, types.KTSecp256k1)
+	_, b := newKey(t, types.KTSecp256k1)
+	_, c := newKey(t, types.KTSecp256k1)
+	e.node.Reject = func(smsg *types.SignedMessage) error {
+		if smsg.Message.To == b {
+			return xerrors.New("mpool is full")
+		}
+		return nil
+	}
+
+	results, err := e.h.SendBatch(testCtx, pk, []BatchItem{
+		{To: a.String(), Amount: "1"},
+		{To: b.String(), Amount: "2"},
+		{To: c.String(), Amount: "3"},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if res := results[0]; res.ExitCode != 0 || res.Cid == "" || res.Error != "" {
+		t.Errorf("first transfer %+v, want executed", res)
+	}
+	if res := results[1]; res.ExitCode != -1 || res.Cid != "" || !strings.Contains(res.Error, "mpool is full") {
+		t.Errorf("second transfer %+v, want the push error", res)
+	}
+	if res := results[2]; res.ExitCode != -1 || res.Cid != "" || res.Error != "not sent" {
+		t.Errorf("third transfer %+v, want not sent", res)
+	}
+	if pushed := len(e.node.Pushed()); pushed != 1 {
+		t.Errorf("%d messages pushed, want 1", pushed)
+	}
+	checkBalance(t, e, a, "1")
+	checkBalance(t, e, c, "0")
+}
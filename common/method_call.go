@@ -0,0 +1,155 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fil-assistant/utils"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"github.com/ipfs/go-cid"
+	cbg "github.com/whyrusleeping/cbor-gen"
+	"golang.org/x/xerrors"
+	"strings"
+)
+
+// ActorMethod is a method of a builtin actor, with its params as a JSON template to fill.
+type ActorMethod struct {
+	Num    abi.MethodNum
+	Name   string
+	Params string
+}
+
+// ActorMethods resolves the actor code of target and returns the methods it exports.
+func (m *Handler) ActorMethods(ctx context.Context, target string) ([]ActorMethod, error) {
+	code, err := m.actorCode(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+
+	nums := utils.Methods(code)
+	methods := make([]ActorMethod, 0, len(nums))
+	for _, num := range nums {
+		name, params, _ := utils.LookupMethod(code, num)
+		val, err := json.MarshalIndent(params, "", "\t")
+		if err != nil {
+			return nil, err
+		}
+		methods = append(methods, ActorMethod{Num: num, Name: name, Params: string(val)})
+	}
+	return methods, nil
+}
+
+func (m *Handler) actorCode(ctx context.Context, target string) (cid.Cid, error) {
+	to, err := address.NewFromString(target)
+	if err != nil {
+		return cid.Undef, err
+	}
+	code, err := m.client.StateGetActorCode(ctx, to)
+	if err != nil {
+		return cid.Undef, err
+	}
+	if _, found := utils.MethodsMap[code]; !found {
+		return cid.Undef, xerrors.Errorf("%s is not a builtin actor, code %s", target, code)
+	}
+	return code, nil
+}
+
+// EncodeParams checks params, a JSON object, against the param type of the method of the actor code and returns them
+// CBOR encoded. method is the name or the number of the method.
+func EncodeParams(code cid.Cid, method, params string) (abi.MethodNum, []byte, error) {
+	num, val, found := utils.LookupMethodByName(code, method)
+	if !found {
+		return 0, nil, xerrors.Errorf("unknown method %s for actor %s", method, code)
+	}
+
+	params = strings.TrimSpace(params)
+	if params != "" && params != "null" {
+		dec := json.NewDecoder(strings.NewReader(params))
+		dec.DisallowUnknownFields()
+		if err := dec.Decode(val); err != nil {
+			return 0, nil, xerrors.Errorf("invalid params of %s: %w", method, err)
+		}
+		if dec.More() {
+			return 0, nil, xerrors.Errorf("invalid params of %s: trailing data", method)
+		}
+	}
+
+	if _, empty := val.(*abi.EmptyValue); empty {
+		return num, nil, nil
+	}
+	marshaler, ok := val.(cbg.CBORMarshaler)
+	if !ok {
+		return 0, nil, xerrors.Errorf("params of %s can not be encoded", method)
+	}
+	var buf bytes.Buffer
+	if err := marshaler.MarshalCBOR(&buf); err != nil {
+		return 0, nil, xerrors.Errorf("invalid params of %s: %w", method, err)
+	}
+	return num, buf.Bytes(), nil
+}
+
+// Call calls method of the builtin actor target with params given as JSON and amount FIL, directly or through a
+// multisig proposal. A direct call returns the decoded return value of the method, a hex string when its type is
+// not known.
+func (m *Handler) Call(ctx context.Context, pk, target, method, params, amount string,
+	proposal *Proposal) (interface{}, error) {
+	from, err := m.sender(pk)
+	if err != nil {
+		return nil, err
+	}
+
+	to, err := address.NewFromString(target)
+	if err != nil {
+		return nil, err
+	}
+
+	value := abi.NewTokenAmount(0)
+	if amount != "" {
+		amnt, err := types.ParseFIL(amount)
+		if err != nil {
+			return nil, err
+		}
+		value = abi.TokenAmount(amnt)
+	}
+
+	m.process(1 / float64(7))
+	code, err := m.actorCode(ctx, target)
+	if err != nil {
+		return nil, err
+	}
+	num, enc, err := EncodeParams(code, method, params)
+	if err != nil {
+		return nil, err
+	}
+
+	if proposal == nil {
+		rawMsg := &types.Message{
+			From:   from.addr,
+			To:     to,
+			Value:  value,
+			Method: num,
+			Params: enc,
+		}
+		ret, err := m.messagePush(ctx, rawMsg, from, 1)
+		if err != nil {
+			return nil, err
+		}
+		return m.decodeReturn(ctx, rawMsg, ret)
+	} else {
+		msig, err := address.NewFromString(proposal.Msig)
+		if err != nil {
+			return nil, err
+		}
+
+		proposal.TxnID, err = m.propose(ctx, from, msig, &multisig.ProposeParams{
+			To:     to,
+			Value:  value,
+			Method: num,
+			Params: enc,
+		}, 1)
+		return nil, err
+	}
+}
@@ -0,0 +1,143 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/power"
+	"strings"
+	"testing"
+)
+
+func TestEncodeParams(t *testing.T) {
+	_, a := newKey(t, types.KTSecp256k1)
+	params := fmt.Sprintf(`{"Signer": %q, "Increase": true}`, a)
+	num, enc, err := EncodeParams(builtin.MultisigActorCodeID, "AddSigner", params)
+	if err != nil {
+		t.Fatal(err)
+	}
+	dec := new(multisig.AddSignerParams)
+	if err = dec.UnmarshalCBOR(bytes.NewReader(enc)); err != nil {
+		t.Fatal(err)
+	}
+	if num != builtin.MethodsMultisig.AddSigner || dec.Signer != a || !dec.Increase {
+		t.Errorf("method %d params %+v", num, dec)
+	}
+
+	num, enc, err = EncodeParams(builtin.StorageMinerActorCodeID, "ConfirmUpdateWorkerKey", "")
+	if err != nil || num != builtin.MethodsMiner.ConfirmUpdateWorkerKey || enc != nil {
+		t.Errorf("method without params: %d %x %v", num, enc, err)
+	}
+	if num, _, err = EncodeParams(builtin.MultisigActorCodeID, "8", `{"NewThreshold": 1}`); err != nil ||
+		num != builtin.MethodsMultisig.ChangeNumApprovalsThreshold {
+		t.Errorf("method by number: %d %v", num, err)
+	}
+
+	for _, bad := range []struct{ method, params, err string }{
+		{"Nope", "", "unknown method"},
+		{"99", "", "unknown method"},
+		{"AddSigner", `{"Signer": "f0100", "Increase": true, "Extra": 1}`, "unknown field"},
+		{"AddSigner", `{"Signer": "nope"}`, "invalid params"},
+		{"ChangeNumApprovalsThreshold", `{"NewThreshold": 1} {}`, "trailing data"},
+	} {
+		if _, _, err = EncodeParams(builtin.MultisigActorCodeID, bad.method, bad.params); err == nil ||
+			!strings.Contains(err.Error(), bad.err) {
+			t.Errorf("%s %s: got %v, want %s", bad.method, bad.params, err, bad.err)
+		}
+	}
+}
+
+// TestCallPropose builds multisig proposals from JSON params, by method name and by number.
+func TestCallPropose(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, a := e.newAccount(t, "10")
+	pkB, b := e.newAccount(t, "1")
+	_, d := e.newAccount(t, "0")
+	msig := e.newMultisig(t, "2", pkA, pkB)
+
+	methods, err := e.h.ActorMethods(testCtx, msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, method := range methods {
+		found = found || method.Name == "AddSigner" && strings.Contains(method.Params, `"Signer"`)
+	}
+	if !found {
+		t.Errorf("no AddSigner template in %+v", methods)
+	}
+
+	proposal := &Proposal{Msig: msig}
+	params := fmt.Sprintf(`{"Signer": %q, "Increase": false}`, d)
+	if _, err = e.h.Call(testCtx, pkA, msig, "AddSigner", params, "", proposal); err != nil {
+		t.Fatal(err)
+	}
+	info, err := e.h.GetProposal(testCtx, *proposal)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Method != "AddSigner" || info.To.String() != msig {
+		t.Errorf("proposal %s to %s", info.Method, info.To)
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	want := e.lookupID(t, a).String() + " " + e.lookupID(t, b).String() + " " + e.lookupID(t, d).String()
+	if got := signerIDs(e.msigInfo(t, msig)); got != want {
+		t.Errorf("signers %s, want %s", got, want)
+	}
+
+	if _, err = e.h.Call(testCtx, pkA, msig, "8", `{"NewThreshold": 3}`, "", proposal); err != nil {
+		t.Fatal(err)
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	if threshold := e.msigInfo(t, msig).Threshold; threshold != 3 {
+		t.Errorf("threshold %d, want 3", threshold)
+	}
+	e.checkPending(t, msig, 0)
+
+	if _, err = e.h.Call(testCtx, pkA, msig, "AddSigner", `{"Signer": "f0100", "Bogus": 1}`, "", proposal); err == nil {
+		t.Error("proposed with unknown params")
+	}
+	_, unknown := newKey(t, types.KTSecp256k1)
+	if _, err = e.h.Call(testCtx, pkA, unknown.String(), "Send", "", "1", proposal); err == nil {
+		t.Error("called an address without actor")
+	}
+}
+
+// TestCallDirect calls builtin actors directly, decoding the return value of the method when it has one.
+func TestCallDirect(t *testing.T) {
+	e := newTestEnv(t)
+	pk, a := e.newAccount(t, "10")
+	market := builtin.StorageMarketActorAddr.String()
+
+	if ret, err := e.h.Call(testCtx, pk, market, "AddBalance", fmt.Sprintf("%q", a), "3", nil); err != nil {
+		t.Fatal(err)
+	} else if ret != nil {
+		t.Errorf("AddBalance returned %v", ret)
+	}
+	checkBalance(t, e, a, "7")
+	if info, err := e.h.GetMarketBalance(testCtx, a.String()); err != nil || info.Escrow != "3 FIL" {
+		t.Errorf("escrow %+v, %v", info, err)
+	}
+
+	params := fmt.Sprintf(`{"Owner": %q, "Worker": %q, "WindowPoStProofType": %d, "Peer": null, "Multiaddrs": null}`,
+		a, a, abi.RegisteredPoStProof_StackedDrgWindow2KiBV1)
+	ret, err := e.h.Call(testCtx, pk, builtin.StoragePowerActorAddr.String(), "CreateMiner", params, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	created, ok := ret.(*power.CreateMinerReturn)
+	if !ok {
+		t.Fatalf("CreateMiner returned %v", ret)
+	}
+	if id := e.lookupID(t, created.RobustAddress); id != created.IDAddress {
+		t.Errorf("robust address %s of miner %s resolves to %s", created.RobustAddress, created.IDAddress, id)
+	}
+
+	if _, err = e.h.Call(testCtx, pk, market, "AddBalance", `"nope"`, "1", nil); err == nil {
+		t.Error("called with invalid params")
+	}
+	checkBalance(t, e, a, "7")
+}
@@ -0,0 +1,158 @@
+package common
+
+import (
+	"context"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/market"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"golang.org/x/xerrors"
+)
+
+type MarketInfo struct {
+	Address   string `json:"地址"`
+	Label     string `json:"标签,omitempty"`
+	Escrow    string `json:"托管余额"`
+	Locked    string `json:"锁定余额"`
+	Available string `json:"可用余额"`
+}
+
+// GetMarketBalance reads the storage market collateral of a provider or client, the available part is what
+// MarketWithdraw can take out.
+func (m *Handler) GetMarketBalance(ctx context.Context, addr string) (*MarketInfo, error) {
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	m.process(0.5)
+	bal, err := m.client.GetMarketBalance(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+	return &MarketInfo{
+		Address:   addr,
+		Label:     m.book.Label(addr),
+		Escrow:    fil(bal.Escrow),
+		Locked:    fil(bal.Locked),
+		Available: fil(big.Sub(orZero(bal.Escrow), orZero(bal.Locked))),
+	}, nil
+}
+
+// MarketAddBalance adds amount FIL to the storage market collateral of a provider or client, from the sender or,
+// with proposal, from a multisig.
+func (m *Handler) MarketAddBalance(ctx context.Context, pk, addr, amount string, proposal *Proposal) error {
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return err
+	}
+
+	amnt, err := types.ParseFIL(amount)
+	if err != nil {
+		return err
+	}
+
+	from, err := m.sender(pk)
+	if err != nil {
+		return err
+	}
+
+	enc, err := actors.SerializeParams(&a)
+	if err != nil {
+		return err
+	}
+
+	if proposal == nil {
+		_, err = m.messagePush(ctx, &types.Message{
+			From:       from.addr,
+			To:         builtin.StorageMarketActorAddr,
+			Value:      abi.TokenAmount(amnt),
+			Method:     builtin.MethodsMarket.AddBalance,
+			Params:     enc,
+		}, from, 0)
+		return err
+	} else {
+		msigAddr, err := address.NewFromString(proposal.Msig)
+		if err != nil {
+			return err
+		}
+
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
+			To: builtin.StorageMarketActorAddr,
+			Value: abi.TokenAmount(amnt),
+			Method: builtin.MethodsMarket.AddBalance,
+			Params: enc,
+		}, 0)
+		return err
+	}
+}
+
+// MarketWithdraw withdraws amount FIL, or all that is available when amount is empty, from the storage market
+// collateral of a provider or client. The collateral of a provider goes to its owner and is withdrawn by its owner or
+// worker, the collateral of a client by the client itself. With proposal the owner or client is a multisig.
+func (m *Handler) MarketWithdraw(ctx context.Context, pk, addr, amount string, proposal *Proposal) error {
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return err
+	}
+
+	from, err := m.sender(pk)
+	if err != nil {
+		return err
+	}
+
+	m.process(1 / float64(1 + 6))
+	bal, err := m.client.GetMarketBalance(ctx, a)
+	if err != nil {
+		return err
+	}
+	avail := big.Sub(orZero(bal.Escrow), orZero(bal.Locked))
+	amnt := avail
+	if amount != "" {
+		val, err := types.ParseFIL(amount)
+		if err != nil {
+			return err
+		}
+		amnt = abi.TokenAmount(val)
+	}
+	if !amnt.GreaterThan(big.Zero()) || avail.LessThan(amnt) {
+		return xerrors.Errorf("market available balance %s does not cover withdraw amount %s, %s of the escrow %s "+
+			"is locked by deals", fil(avail), fil(amnt), fil(bal.Locked), fil(bal.Escrow))
+	}
+
+	enc, err := actors.SerializeParams(&market.WithdrawBalanceParams{
+		ProviderOrClientAddress: a,
+		Amount:                  amnt,
+	})
+	if err != nil {
+		return err
+	}
+
+	if proposal == nil {
+		_, err = m.messagePush(ctx, &types.Message{
+			From:       from.addr,
+			To:         builtin.StorageMarketActorAddr,
+			Value:      abi.NewTokenAmount(0),
+			Method:     builtin.MethodsMarket.WithdrawBalance,
+			Params:     enc,
+		}, from, 1)
+		return err
+	} else {
+		msigAddr, err := address.NewFromString(proposal.Msig)
+		if err != nil {
+			return err
+		}
+
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
+			To: builtin.StorageMarketActorAddr,
+			Value: abi.NewTokenAmount(0),
+			Method: builtin.MethodsMarket.WithdrawBalance,
+			Params: enc,
+		}, 1)
+		return err
+	}
+}
@@ -0,0 +1,70 @@
+package common
+
+import (
+	"fil-assistant/chain"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"testing"
+)
+
+func checkMarket(t *testing.T, e *testEnv, addr, escrow, locked, available string) {
+	t.Helper()
+	info, err := e.h.GetMarketBalance(testCtx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Escrow != escrow+" FIL" || info.Locked != locked+" FIL" || info.Available != available+" FIL" {
+		t.Errorf("market balance of %s is %+v, want %s FIL escrow, %s FIL locked", addr, info, escrow, locked)
+	}
+}
+
+// TestMarketClient adds collateral for a client, of which deals then lock 1 FIL, and withdraws the rest.
+func TestMarketClient(t *testing.T) {
+	e := newTestEnv(t)
+	pk, client := e.newAccount(t, "10")
+	otherPk, _ := e.newAccount(t, "1")
+
+	if err := e.h.MarketAddBalance(testCtx, pk, client.String(), "4", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, client, "6")
+	checkMarket(t, e, client.String(), "4", "0", "4")
+
+	e.node.SetMarketBalance(client, &chain.MarketBalance{Escrow: mustFIL(t, "4"), Locked: mustFIL(t, "1")})
+	checkMarket(t, e, client.String(), "4", "1", "3")
+	if err := e.h.MarketWithdraw(testCtx, pk, client.String(), "3.5", nil); err == nil {
+		t.Error("withdrew collateral locked by deals")
+	}
+	if err := e.h.MarketWithdraw(testCtx, otherPk, client.String(), "1", nil); err == nil {
+		t.Error("withdrew the collateral of another client")
+	}
+	if err := e.h.MarketWithdraw(testCtx, pk, client.String(), "", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, client, "9")
+	checkMarket(t, e, client.String(), "1", "1", "0")
+	if err := e.h.MarketWithdraw(testCtx, pk, client.String(), "", nil); err == nil {
+		t.Error("withdrew without available collateral")
+	}
+}
+
+// TestMarketProvider withdraws the collateral of a provider to its owner, as its worker.
+func TestMarketProvider(t *testing.T) {
+	e := newTestEnv(t)
+	_, owner := e.newAccount(t, "0")
+	workerPk, worker := e.newAccount(t, "1")
+	otherPk, _ := e.newAccount(t, "1")
+	mID, st := e.newMiner(t, owner, "0", "0")
+	st.Info.Worker = e.lookupID(t, worker)
+	e.node.SetMarketBalance(mID, &chain.MarketBalance{Escrow: mustFIL(t, "5"), Locked: mustFIL(t, "2")})
+	e.node.SetBalance(builtin.StorageMarketActorAddr, mustFIL(t, "5"))
+
+	if err := e.h.MarketWithdraw(testCtx, otherPk, mID.String(), "1", nil); err == nil {
+		t.Error("withdrew the collateral of a provider as another address than its owner and worker")
+	}
+	if err := e.h.MarketWithdraw(testCtx, workerPk, mID.String(), "2", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, owner, "2")
+	checkBalance(t, e, worker, "1")
+	checkMarket(t, e, mID.String(), "3", "2", "1")
+}
@@ -0,0 +1,542 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fil-assistant/chain"
+	"fmt"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/miner"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/power"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"golang.org/x/xerrors"
+	"strconv"
+	"strings"
+)
+
+// MinerAddress is an address of a miner with its balance, Address is the key address of accounts.
+type MinerAddress struct {
+	Role    string `json:"角色"`
+	ID      string `json:"ID地址"`
+	Address string `json:"地址"`
+	Label   string `json:"标签,omitempty"`
+	Balance string `json:"余额"`
+}
+
+type MinerOverview struct {
+	Miner             string         `json:"矿工号"`
+	Owner             string         `json:"owner"`
+	Worker            string         `json:"worker"`
+	Controls          []string       `json:"control"`
+	NewWorker         string         `json:"待生效worker,omitempty"`
+	WorkerChangeEpoch abi.ChainEpoch `json:"worker生效高度,omitempty"`
+	PeerID            string         `json:"节点ID"`
+	Multiaddrs        []string       `json:"节点地址"`
+	SectorSize        string         `json:"扇区大小"`
+	RawPower          string         `json:"原值算力"`
+	QAPower           string         `json:"有效算力"`
+	HasMinPower       bool           `json:"达到最低算力"`
+	Balance           string         `json:"余额"`
+	Available         string         `json:"可用余额"`
+	Locked            string         `json:"锁定余额"`
+	Vesting           string         `json:"锁仓奖励"`
+	PreCommitDeposits string         `json:"预提交押金"`
+	InitialPledge     string         `json:"初始质押"`
+	FeeDebt           string         `json:"欠费"`
+	Addresses         []MinerAddress `json:"地址余额"`
+}
+
+// minerFunds are the fields of the miner state holding the funds of the miner.
+type minerFunds struct {
+	PreCommitDeposits abi.TokenAmount
+	LockedFunds       abi.TokenAmount
+	InitialPledge     abi.TokenAmount
+	FeeDebt           abi.TokenAmount
+}
+
+func (f *minerFunds) locked() abi.TokenAmount {
+	return big.Sum(orZero(f.LockedFunds), orZero(f.PreCommitDeposits), orZero(f.InitialPledge))
+}
+
+func (m *Handler) minerFunds(ctx context.Context, minerAddr address.Address) (*chain.ActorState, *minerFunds,
+	error) {
+	act, err := m.client.ReadState(ctx, minerAddr)
+	if err != nil {
+		return nil, nil, err
+	}
+	if act.Code != builtin.StorageMinerActorCodeID {
+		return nil, nil, xerrors.Errorf("%s is not a miner, actor code %s", minerAddr, act.Code)
+	}
+	funds := new(minerFunds)
+	if err = json.Unmarshal(act.State, funds); err != nil {
+		return nil, nil, xerrors.Errorf("failed to decode miner state: %w", err)
+	}
+	return act, funds, nil
+}
+
+// GetMinerOverview reads the addresses, the power and the funds of a miner, and the balances of its owner, worker
+// and control addresses.
+func (m *Handler) GetMinerOverview(ctx context.Context, minerID string) (*MinerOverview, error) {
+	minerAddr, err := address.NewFromString(minerID)
+	if err != nil {
+		return nil, err
+	}
+
+	m.process(0.1)
+	info, err := m.client.GetMinerInfo(ctx, minerAddr)
+	if err != nil {
+		return nil, err
+	}
+	power, err := m.client.GetMinerPower(ctx, minerAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	m.process(0.3)
+	act, funds, err := m.minerFunds(ctx, minerAddr)
+	if err != nil {
+		return nil, err
+	}
+	available, err := m.client.GetMinerAvailableBalance(ctx, minerAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	overview := &MinerOverview{
+		Miner:             minerID,
+		Owner:             info.Owner.String(),
+		Worker:            info.Worker.String(),
+		Controls:          make([]string, 0, len(info.ControlAddresses)),
+		SectorSize:        types.SizeStr(types.NewInt(uint64(info.SectorSize))),
+		RawPower:          types.SizeStr(power.MinerPower.RawBytePower),
+		QAPower:           types.SizeStr(power.MinerPower.QualityAdjPower),
+		HasMinPower:       power.HasMinPower,
+		Balance:           types.FIL(act.Balance).String(),
+		Available:         types.FIL(available).String(),
+		Locked:            fil(funds.locked()),
+		Vesting:           fil(funds.LockedFunds),
+		PreCommitDeposits: fil(funds.PreCommitDeposits),
+		InitialPledge:     fil(funds.InitialPledge),
+		FeeDebt:           fil(funds.FeeDebt),
+	}
+	for _, control := range info.ControlAddresses {
+		overview.Controls = append(overview.Controls, control.String())
+	}
+	if info.NewWorker != address.Undef {
+		overview.NewWorker = info.NewWorker.String()
+		overview.WorkerChangeEpoch = info.WorkerChangeEpoch
+	}
+	if info.PeerId != nil {
+		overview.PeerID = info.PeerId.String()
+	}
+	for _, val := range info.Multiaddrs {
+		if maddr, err := multiaddr.NewMultiaddrBytes(val); err == nil {
+			overview.Multiaddrs = append(overview.Multiaddrs, maddr.String())
+		}
+	}
+
+	roles := []string{"owner", "worker"}
+	addrs := []address.Address{info.Owner, info.Worker}
+	for _, control := range info.ControlAddresses {
+		roles, addrs = append(roles, "control"), append(addrs, control)
+	}
+	base := 0.5 / float64(len(addrs))
+	for i, addr := range addrs {
+		m.process(0.5 + float64(i) * base)
+		id, key := m.idAndKey(ctx, addr)
+		bal, err := m.client.GetBalance(ctx, addr)
+		if err != nil {
+			return nil, err
+		}
+		label := m.book.Label(id.String())
+		if label == "" {
+			label = m.book.Label(key.String())
+		}
+		overview.Addresses = append(overview.Addresses, MinerAddress{
+			Role:    roles[i],
+			ID:      id.String(),
+			Address: key.String(),
+			Label:   label,
+			Balance: types.FIL(bal).String(),
+		})
+	}
+	return overview, nil
+}
+
+// CreateMiner creates a miner through the power actor and returns its ID and robust addresses. The sector size of
+// sealProof, such as "32GiB", selects the V1_1 seal proof, a proof number is taken as is. owner defaults to the
+// sender, or the multisig proposing with proposal, and worker to the sender. The peer ID and the multiaddrs are
+// optional. With proposal the addresses are only known once the proposal is executed.
+func (m *Handler) CreateMiner(ctx context.Context, pk, owner, worker, sealProof, peerID string, addrs []string,
+	proposal *Proposal) (string, string, error) {
+	spt, err := sealProofParam(sealProof)
+	if err != nil {
+		return "", "", err
+	}
+	postProof, err := spt.RegisteredWindowPoStProof()
+	if err != nil {
+		return "", "", err
+	}
+
+	var pid abi.PeerID
+	if peerID != "" {
+		if pid, err = peerIDParam(peerID); err != nil {
+			return "", "", err
+		}
+	}
+
+	maddrs, err := multiaddrsParam(addrs)
+	if err != nil {
+		return "", "", err
+	}
+
+	from, err := m.sender(pk)
+	if err != nil {
+		return "", "", err
+	}
+
+	if owner == "" {
+		owner = from.addr.String()
+		if proposal != nil {
+			owner = proposal.Msig
+		}
+	}
+	ownerAddr, err := address.NewFromString(owner)
+	if err != nil {
+		return "", "", err
+	}
+
+	workerAddr := from.addr
+	if worker != "" {
+		if workerAddr, err = address.NewFromString(worker); err != nil {
+			return "", "", err
+		}
+	}
+
+	enc, err := actors.SerializeParams(&power.CreateMinerParams{
+		Owner:               ownerAddr,
+		Worker:              workerAddr,
+		WindowPoStProofType: postProof,
+		Peer:                pid,
+		Multiaddrs:          maddrs,
+	})
+	if err != nil {
+		return "", "", err
+	}
+
+	if proposal == nil {
+		res, err := m.messagePush(ctx, &types.Message{
+			From:       from.addr,
+			To:         builtin.StoragePowerActorAddr,
+			Value:      abi.NewTokenAmount(0),
+			Method:     builtin.MethodsPower.CreateMiner,
+			Params:     enc,
+		}, from, 0)
+		if err != nil {
+			return "", "", err
+		}
+		ret := new(power.CreateMinerReturn)
+		if err = ret.UnmarshalCBOR(bytes.NewReader(res)); err != nil {
+			return "", "", err
+		} else {
+			return ret.IDAddress.String(), ret.RobustAddress.String(), nil
+		}
+	} else {
+		msigAddr, err := address.NewFromString(proposal.Msig)
+		if err != nil {
+			return "", "", err
+		}
+
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
+			To: builtin.StoragePowerActorAddr,
+			Value: abi.NewTokenAmount(0),
+			Method: builtin.MethodsPower.CreateMiner,
+			Params: enc,
+		}, 0)
+		return "", "", err
+	}
+}
+
+// ChangePeerID sets the libp2p peer ID of a miner, sent by its owner, worker or a control address, or proposed by
+// the multisig owning it.
+func (m *Handler) ChangePeerID(ctx context.Context, pk, minerID, peerID string, proposal *Proposal) error {
+	mID, err := address.NewFromString(minerID)
+	if err != nil {
+		return err
+	}
+
+	pid, err := peerIDParam(peerID)
+	if err != nil {
+		return err
+	}
+
+	from, err := m.sender(pk)
+	if err != nil {
+		return err
+	}
+
+	enc, err := actors.SerializeParams(&miner.ChangePeerIDParams{
+		NewID: pid,
+	})
+	if err != nil {
+		return err
+	}
+
+	if proposal == nil {
+		_, err = m.messagePush(ctx, &types.Message{
+			From:       from.addr,
+			To:         mID,
+			Value:      abi.NewTokenAmount(0),
+			Method:     builtin.MethodsMiner.ChangePeerID,
+			Params:     enc,
+		}, from, 0)
+		return err
+	} else {
+		msigAddr, err := address.NewFromString(proposal.Msig)
+		if err != nil {
+			return err
+		}
+
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
+			To: mID,
+			Value: abi.NewTokenAmount(0),
+			Method: builtin.MethodsMiner.ChangePeerID,
+			Params: enc,
+		}, 0)
+		return err
+	}
+}
+
+// ChangeMultiaddrs replaces the multiaddrs a miner announces, e.g. "/ip4/1.2.3.4/tcp/24001". An empty list clears
+// them.
+func (m *Handler) ChangeMultiaddrs(ctx context.Context, pk, minerID string, addrs []string, proposal *Proposal) error {
+	mID, err := address.NewFromString(minerID)
+	if err != nil {
+		return err
+	}
+
+	maddrs, err := multiaddrsParam(addrs)
+	if err != nil {
+		return err
+	}
+
+	from, err := m.sender(pk)
+	if err != nil {
+		return err
+	}
+
+	enc, err := actors.SerializeParams(&miner.ChangeMultiaddrsParams{
+		NewMultiaddrs: maddrs,
+	})
+	if err != nil {
+		return err
+	}
+
+	if proposal == nil {
+		_, err = m.messagePush(ctx, &types.Message{
+			From:       from.addr,
+			To:         mID,
+			Value:      abi.NewTokenAmount(0),
+			Method:     builtin.MethodsMiner.ChangeMultiaddrs,
+			Params:     enc,
+		}, from, 0)
+		return err
+	} else {
+		msigAddr, err := address.NewFromString(proposal.Msig)
+		if err != nil {
+			return err
+		}
+
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
+			To: mID,
+			Value: abi.NewTokenAmount(0),
+			Method: builtin.MethodsMiner.ChangeMultiaddrs,
+			Params: enc,
+		}, 0)
+		return err
+	}
+}
+
+// WithdrawPreflight returns the amount a withdrawal of amount FIL from a miner would request, all of its available
+// balance when amount is empty. When the available balance does not cover it, the error tells whether fee debt or
+// locked funds are in the way.
+func (m *Handler) WithdrawPreflight(ctx context.Context, minerID, amount string) (abi.TokenAmount, error) {
+	mID, err := address.NewFromString(minerID)
+	if err != nil {
+		return big.Zero(), err
+	}
+	amnt := big.Zero()
+	if amount != "" {
+		val, err := types.ParseFIL(amount)
+		if err != nil {
+			return big.Zero(), err
+		}
+		amnt = abi.TokenAmount(val)
+	}
+
+	avail, err := m.client.GetMinerAvailableBalance(ctx, mID)
+	if err != nil {
+		return big.Zero(), err
+	}
+	if amount == "" && avail.GreaterThan(big.Zero()) {
+		return avail, nil
+	} else if amount != "" && !avail.LessThan(amnt) {
+		return amnt, nil
+	}
+
+	reason := fmt.Sprintf("avail balance %s is less than withdraw amount %s", types.FIL(avail), types.FIL(amnt))
+	if amount == "" {
+		reason = fmt.Sprintf("nothing to withdraw, avail balance is %s", types.FIL(avail))
+	}
+	act, funds, err := m.minerFunds(ctx, mID)
+	if err != nil {
+		return big.Zero(), xerrors.New(reason)
+	}
+	if debt := orZero(funds.FeeDebt); debt.GreaterThan(big.Zero()) {
+		return big.Zero(), xerrors.Errorf("%s: the miner owes %s of fee debt, repay it first", reason, types.FIL(debt))
+	}
+	return big.Zero(), xerrors.Errorf("%s: of the balance %s, %s are locked rewards, %s pre-commit deposits and %s "+
+		"initial pledge", reason, types.FIL(act.Balance), fil(funds.LockedFunds), fil(funds.PreCommitDeposits),
+		fil(funds.InitialPledge))
+}
+
+// RepayDebt pays the fee debt of a miner from its unlocked balance and amount FIL sent along. When amount is empty,
+// what the unlocked balance lacks to cover the debt is sent.
+func (m *Handler) RepayDebt(ctx context.Context, pk, minerID, amount string, proposal *Proposal) error {
+	mID, err := address.NewFromString(minerID)
+	if err != nil {
+		return err
+	}
+
+	from, err := m.sender(pk)
+	if err != nil {
+		return err
+	}
+
+	m.process(1 / float64(1 + 6))
+	act, funds, err := m.minerFunds(ctx, mID)
+	if err != nil {
+		return err
+	}
+	debt := orZero(funds.FeeDebt)
+	if debt.IsZero() {
+		return xerrors.Errorf("miner %s has no fee debt", minerID)
+	}
+	value := big.Max(big.Sub(debt, big.Sub(act.Balance, funds.locked())), big.Zero())
+	if amount != "" {
+		amnt, err := types.ParseFIL(amount)
+		if err != nil {
+			return err
+		}
+		value = abi.TokenAmount(amnt)
+	}
+
+	if proposal == nil {
+		_, err = m.messagePush(ctx, &types.Message{
+			From:       from.addr,
+			To:         mID,
+			Value:      value,
+			Method:     builtin.MethodsMiner.RepayDebt,
+		}, from, 1)
+		return err
+	} else {
+		msigAddr, err := address.NewFromString(proposal.Msig)
+		if err != nil {
+			return err
+		}
+
+		proposal.TxnID, err = m.propose(ctx, from, msigAddr, &multisig.ProposeParams{
+			To: mID,
+			Value: value,
+			Method: builtin.MethodsMiner.RepayDebt,
+		}, 1)
+		return err
+	}
+}
+
+// peerIDParam decodes a libp2p peer ID as the miner actor takes it.
+func peerIDParam(peerID string) (abi.PeerID, error) {
+	pid, err := peer.Decode(peerID)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid peer ID %s: %w", peerID, err)
+	}
+	if len(pid) > miner.MaxPeerIDLength {
+		return nil, xerrors.Errorf("peer ID %s exceeds %d bytes", peerID, miner.MaxPeerIDLength)
+	}
+	return abi.PeerID(pid), nil
+}
+
+// sealProofParam selects the seal proof of a sector size such as "32GiB", or parses a seal proof number.
+func sealProofParam(sealProof string) (abi.RegisteredSealProof, error) {
+	if num, err := strconv.ParseInt(sealProof, 10, 64); err == nil {
+		spt := abi.RegisteredSealProof(num)
+		if _, err = spt.SectorSize(); err != nil {
+			return 0, err
+		}
+		return spt, nil
+	}
+	for _, spt := range []abi.RegisteredSealProof{
+		abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+		abi.RegisteredSealProof_StackedDrg8MiBV1_1,
+		abi.RegisteredSealProof_StackedDrg512MiBV1_1,
+		abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+		abi.RegisteredSealProof_StackedDrg64GiBV1_1,
+	} {
+		if ssize, _ := spt.SectorSize(); strings.EqualFold(ssize.ShortString(), sealProof) {
+			return spt, nil
+		}
+	}
+	return 0, xerrors.Errorf("unknown sector size %s, expected 2KiB, 8MiB, 512MiB, 32GiB or 64GiB", sealProof)
+}
+
+// multiaddrsParam decodes multiaddrs as the miner actor takes them.
+func multiaddrsParam(addrs []string) ([]abi.Multiaddrs, error) {
+	var size int
+	maddrs := make([]abi.Multiaddrs, 0, len(addrs))
+	for _, addr := range addrs {
+		maddr, err := multiaddr.NewMultiaddr(addr)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid multiaddr %s: %w", addr, err)
+		}
+		size += len(maddr.Bytes())
+		maddrs = append(maddrs, maddr.Bytes())
+	}
+	if size > miner.MaxMultiaddrData {
+		return nil, xerrors.Errorf("multiaddrs take %d bytes, more than %d", size, miner.MaxMultiaddrData)
+	}
+	return maddrs, nil
+}
+
+// idAndKey returns the ID address of addr and, for accounts, its key address. Each falls back to addr when it can
+// not be looked up.
+func (m *Handler) idAndKey(ctx context.Context, addr address.Address) (address.Address, address.Address) {
+	id, key := addr, addr
+	if addr.Protocol() != address.ID {
+		if addrID, err := m.client.LookupID(ctx, addr); err == nil {
+			id = addrID
+		}
+	} else if addrKey, err := m.client.AccountKey(ctx, addr); err == nil {
+		key = addrKey
+	}
+	return id, key
+}
+
+// fil formats an amount of the miner state, nil when the state lacks it.
+func fil(val abi.TokenAmount) string {
+	return types.FIL(orZero(val)).String()
+}
+
+func orZero(val abi.TokenAmount) abi.TokenAmount {
+	if val.Nil() {
+		return big.Zero()
+	}
+	return val
+}
@@ -0,0 +1,271 @@
+package common
+
+import (
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/libp2p/go-libp2p-core/peer"
+	"github.com/multiformats/go-multiaddr"
+	"strings"
+	"testing"
+)
+
+const testPeerID = "12D3KooWGzxzKZYveHXtpG6AsrUJBcWxHBFS2HsEoGTxrMLvKXtf"
+
+func mustPeerID(t *testing.T, val string) *peer.ID {
+	t.Helper()
+	pid, err := peer.Decode(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &pid
+}
+
+func mustMultiaddr(t *testing.T, val string) []byte {
+	t.Helper()
+	maddr, err := multiaddr.NewMultiaddr(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return maddr.Bytes()
+}
+
+// TestGetMinerOverview reads a miner of 10 FIL, 6 FIL of them locked, whose owner, worker and control addresses
+// are three accounts.
+func TestGetMinerOverview(t *testing.T) {
+	e := newTestEnv(t)
+	_, owner := e.newAccount(t, "1")
+	_, worker := e.newAccount(t, "2")
+	_, control := e.newAccount(t, "3")
+	mID, st := e.newMiner(t, owner, "10", "2")
+	st.Info.Worker = e.lookupID(t, worker)
+	st.Info.ControlAddresses = []address.Address{e.lookupID(t, control)}
+	st.Info.SectorSize = abi.SectorSize(32 << 30)
+	st.Info.PeerId = mustPeerID(t, testPeerID)
+	st.Info.Multiaddrs = [][]byte{mustMultiaddr(t, "/ip4/1.2.3.4/tcp/1234")}
+	st.Power.RawBytePower = big.NewInt(1 << 40)
+	st.Power.QualityAdjPower = big.NewInt(10 << 40)
+	st.PreCommitDeposits = mustFIL(t, "1")
+	st.InitialPledge = mustFIL(t, "3")
+	e.h.SetAddressBook(newTestBook(t, AddressBookEntry{Address: owner.String(), Label: "owner"}))
+
+	overview, err := e.h.GetMinerOverview(testCtx, mID.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overview.Owner != st.Info.Owner.String() || overview.Worker != st.Info.Worker.String() ||
+		len(overview.Controls) != 1 || overview.Controls[0] != st.Info.ControlAddresses[0].String() {
+		t.Errorf("owner %s, worker %s, controls %v", overview.Owner, overview.Worker, overview.Controls)
+	}
+	if overview.PeerID != testPeerID || len(overview.Multiaddrs) != 1 ||
+		overview.Multiaddrs[0] != "/ip4/1.2.3.4/tcp/1234" {
+		t.Errorf("peer %s, multiaddrs %v", overview.PeerID, overview.Multiaddrs)
+	}
+	if overview.SectorSize != "32 GiB" || overview.RawPower != "1 TiB" || overview.QAPower != "10 TiB" ||
+		!overview.HasMinPower {
+		t.Errorf("sector size %s, power %s raw %s qa, min power %t", overview.SectorSize, overview.RawPower,
+			overview.QAPower, overview.HasMinPower)
+	}
+	if overview.Balance != "10 FIL" || overview.Available != "4 FIL" || overview.Locked != "6 FIL" ||
+		overview.Vesting != "2 FIL" || overview.PreCommitDeposits != "1 FIL" || overview.InitialPledge != "3 FIL" ||
+		overview.FeeDebt != "0 FIL" {
+		t.Errorf("funds %+v", overview)
+	}
+	if overview.NewWorker != "" {
+		t.Errorf("new worker %s without a pending change", overview.NewWorker)
+	}
+
+	want := []MinerAddress{
+		{Role: "owner", ID: st.Info.Owner.String(), Address: owner.String(), Label: "owner", Balance: "1 FIL"},
+		{Role: "worker", ID: st.Info.Worker.String(), Address: worker.String(), Balance: "2 FIL"},
+		{Role: "control", ID: st.Info.ControlAddresses[0].String(), Address: control.String(), Balance: "3 FIL"},
+	}
+	if len(overview.Addresses) != len(want) {
+		t.Fatalf("addresses %+v, want %+v", overview.Addresses, want)
+	}
+	for i := range want {
+		if overview.Addresses[i] != want[i] {
+			t.Errorf("address %+v, want %+v", overview.Addresses[i], want[i])
+		}
+	}
+
+	if _, err = e.h.GetMinerOverview(testCtx, owner.String()); err == nil {
+		t.Error("read an account as a miner")
+	}
+}
+
+// TestChangePeerInfo changes the peer ID and the multiaddrs of a miner as its worker.
+func TestChangePeerInfo(t *testing.T) {
+	e := newTestEnv(t)
+	_, owner := e.newAccount(t, "1")
+	workerPk, worker := e.newAccount(t, "1")
+	otherPk, _ := e.newAccount(t, "1")
+	mID, st := e.newMiner(t, owner, "0", "0")
+	st.Info.Worker = e.lookupID(t, worker)
+
+	if err := e.h.ChangePeerID(testCtx, workerPk, mID.String(), testPeerID, nil); err != nil {
+		t.Fatal(err)
+	}
+	if st.Info.PeerId == nil || st.Info.PeerId.String() != testPeerID {
+		t.Errorf("peer ID %v, want %s", st.Info.PeerId, testPeerID)
+	}
+	addrs := []string{"/ip4/1.2.3.4/tcp/1234", "/dns4/miner.example.com/tcp/24001"}
+	if err := e.h.ChangeMultiaddrs(testCtx, workerPk, mID.String(), addrs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Info.Multiaddrs) != len(addrs) {
+		t.Fatalf("multiaddrs %v, want %v", st.Info.Multiaddrs, addrs)
+	}
+	for i, val := range st.Info.Multiaddrs {
+		if maddr, err := multiaddr.NewMultiaddrBytes(val); err != nil || maddr.String() != addrs[i] {
+			t.Errorf("multiaddr %d is %v, want %s", i, maddr, addrs[i])
+		}
+	}
+
+	pushed := len(e.node.Pushed())
+	if err := e.h.ChangePeerID(testCtx, workerPk, mID.String(), "nope", nil); err == nil {
+		t.Error("changed to an invalid peer ID")
+	}
+	if err := e.h.ChangeMultiaddrs(testCtx, workerPk, mID.String(), []string{"1.2.3.4:1234"}, nil); err == nil {
+		t.Error("changed to an invalid multiaddr")
+	}
+	if n := len(e.node.Pushed()); n != pushed {
+		t.Errorf("%d messages pushed with invalid params", n-pushed)
+	}
+
+	if err := e.h.ChangeMultiaddrs(testCtx, otherPk, mID.String(), nil, nil); err == nil {
+		t.Error("changed the multiaddrs as another address than the owner, worker and controls")
+	}
+	if len(st.Info.Multiaddrs) != len(addrs) {
+		t.Errorf("multiaddrs %v changed by a forbidden message", st.Info.Multiaddrs)
+	}
+}
+
+// TestRepayDebt repays the fee debt of a miner which blocks its withdrawals, its unlocked balance covering 6 FIL of
+// the 8 FIL owed.
+func TestRepayDebt(t *testing.T) {
+	e := newTestEnv(t)
+	pk, owner := e.newAccount(t, "5")
+	mID, st := e.newMiner(t, owner, "10", "4")
+	st.FeeDebt = mustFIL(t, "8")
+
+	if _, err := e.h.WithdrawPreflight(testCtx, mID.String(), "1"); err == nil ||
+		!strings.Contains(err.Error(), "fee debt") {
+		t.Errorf("preflight with a fee debt: %v", err)
+	}
+
+	if err := e.h.RepayDebt(testCtx, pk, mID.String(), "", nil); err != nil {
+		t.Fatal(err)
+	}
+	if !st.FeeDebt.IsZero() {
+		t.Errorf("fee debt %s left", types.FIL(st.FeeDebt))
+	}
+	checkBalance(t, e, owner, "3")
+	checkBalance(t, e, mID, "4")
+
+	if _, err := e.h.WithdrawPreflight(testCtx, mID.String(), ""); err == nil ||
+		!strings.Contains(err.Error(), "4 FIL are locked rewards") {
+		t.Errorf("preflight without available balance: %v", err)
+	}
+	e.node.SetBalance(mID, mustFIL(t, "7"))
+	if amount, err := e.h.WithdrawPreflight(testCtx, mID.String(), ""); err != nil || !amount.Equals(mustFIL(t, "3")) {
+		t.Errorf("preflight of the available balance: %s %v", types.FIL(amount), err)
+	}
+	if amount, err := e.h.WithdrawPreflight(testCtx, mID.String(), "2"); err != nil || !amount.Equals(mustFIL(t, "2")) {
+		t.Errorf("preflight of 2 FIL: %s %v", types.FIL(amount), err)
+	}
+
+	if err := e.h.RepayDebt(testCtx, pk, mID.String(), "", nil); err == nil {
+		t.Error("repaid a miner without fee debt")
+	}
+}
+
+// TestCreateMiner creates a miner owned by the sender, with another worker.
+func TestCreateMiner(t *testing.T) {
+	e := newTestEnv(t)
+	pk, owner := e.newAccount(t, "1")
+	_, worker := e.newAccount(t, "0")
+	addrs := []string{"/ip4/1.2.3.4/tcp/1234"}
+
+	id, robust, err := e.h.CreateMiner(testCtx, pk, "", worker.String(), "32GiB", testPeerID, addrs, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	robustAddr, err := address.NewFromString(robust)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if robustAddr.Protocol() != address.Actor || e.lookupID(t, robustAddr).String() != id {
+		t.Errorf("robust address %s of miner %s resolves to %s", robust, id, e.lookupID(t, robustAddr))
+	}
+	overview, err := e.h.GetMinerOverview(testCtx, id)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if overview.Owner != e.lookupID(t, owner).String() || overview.Worker != e.lookupID(t, worker).String() {
+		t.Errorf("owner %s, worker %s", overview.Owner, overview.Worker)
+	}
+	if overview.SectorSize != "32 GiB" || overview.PeerID != testPeerID || len(overview.Multiaddrs) != 1 ||
+		overview.Multiaddrs[0] != addrs[0] {
+		t.Errorf("sector size %s, peer %s, multiaddrs %v", overview.SectorSize, overview.PeerID, overview.Multiaddrs)
+	}
+
+	pushed := len(e.node.Pushed())
+	for _, bad := range []struct{ sealProof, peerID, addr string }{
+		{"16GiB", "", addrs[0]},
+		{"32GiB", "nope", addrs[0]},
+		{"32GiB", "", "1.2.3.4:1234"},
+	} {
+		if _, _, err = e.h.CreateMiner(testCtx, pk, "", "", bad.sealProof, bad.peerID, []string{bad.addr}, nil); err == nil {
+			t.Errorf("created a miner of %s, peer %q at %s", bad.sealProof, bad.peerID, bad.addr)
+		}
+	}
+	if n := len(e.node.Pushed()); n != pushed {
+		t.Errorf("%d messages pushed with invalid params", n-pushed)
+	}
+}
+
+func TestSealProofParam(t *testing.T) {
+	for val, want := range map[string]abi.RegisteredSealProof{
+		"32GiB": abi.RegisteredSealProof_StackedDrg32GiBV1_1,
+		"64gib": abi.RegisteredSealProof_StackedDrg64GiBV1_1,
+		"2KiB":  abi.RegisteredSealProof_StackedDrg2KiBV1_1,
+		"3":     abi.RegisteredSealProof_StackedDrg32GiBV1,
+	} {
+		if spt, err := sealProofParam(val); err != nil || spt != want {
+			t.Errorf("%s: got %d %v, want %d", val, spt, err, want)
+		}
+	}
+	for _, val := range []string{"16GiB", "999", "-1", ""} {
+		if _, err := sealProofParam(val); err == nil {
+			t.Errorf("seal proof %q accepted", val)
+		}
+	}
+}
+
+func TestMultiaddrsParam(t *testing.T) {
+	addrs := []string{"/ip4/1.2.3.4/tcp/1234", "/ip6/::1/udp/1234/quic"}
+	maddrs, err := multiaddrsParam(addrs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, val := range maddrs {
+		if maddr, err := multiaddr.NewMultiaddrBytes(val); err != nil || maddr.String() != addrs[i] {
+			t.Errorf("multiaddr %d decoded as %v, want %s", i, maddr, addrs[i])
+		}
+	}
+	if maddrs, err = multiaddrsParam(nil); err != nil || len(maddrs) != 0 {
+		t.Errorf("no multiaddrs: %v %v", maddrs, err)
+	}
+
+	long := make([]string, 0, 64)
+	for len(long) < cap(long) {
+		long = append(long, "/dns4/miner.example.com/tcp/24001")
+	}
+	for _, bad := range [][]string{{"1.2.3.4:1234"}, {addrs[0], "/ip4/nope"}, long} {
+		if _, err = multiaddrsParam(bad); err == nil {
+			t.Errorf("multiaddrs %v accepted", bad)
+		}
+	}
+}
@@ -0,0 +1,145 @@
+package common
+
+import (
+	"context"
+	"fil-assistant/chain"
+	"fil-assistant/lib"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/ipfs/go-cid"
+	"golang.org/x/xerrors"
+	"strconv"
+)
+
+// Replace re-prices a message stuck in the mpool and pushes it again with the same nonce. target is the message cid
+// or its nonce, feeCap is an optional GasFeeCap in attoFIL. It returns the cid of the replacing message.
+func (m *Handler) Replace(ctx context.Context, pk, target, feeCap string) (string, error) {
+	from, err := m.sender(pk)
+	if err != nil {
+		return "", err
+	}
+
+	m.process(1 / float64(5))
+	msg, err := m.pendingMessage(ctx, from.addr, target)
+	if err != nil {
+		return "", err
+	}
+	if err = m.reprice(msg, feeCap); err != nil {
+		return "", err
+	}
+	return m.replacePush(ctx, msg, from)
+}
+
+// Cancel replaces a message stuck in the mpool by a zero value send to the sender itself.
+func (m *Handler) Cancel(ctx context.Context, pk, target, feeCap string) (string, error) {
+	from, err := m.sender(pk)
+	if err != nil {
+		return "", err
+	}
+
+	m.process(1 / float64(5))
+	old, err := m.pendingMessage(ctx, from.addr, target)
+	if err != nil {
+		return "", err
+	}
+
+	msg := &types.Message{
+		From:       old.From,
+		To:         old.From,
+		Nonce:      old.Nonce,
+		Value:      abi.NewTokenAmount(0),
+		Method:     builtin.MethodSend,
+		GasLimit:   old.GasLimit,
+		GasFeeCap:  old.GasFeeCap,
+		GasPremium: old.GasPremium,
+	}
+	if err = m.reprice(msg, feeCap); err != nil {
+		return "", err
+	}
+	return m.replacePush(ctx, msg, from)
+}
+
+// pendingMessage looks up a message of from in the mpool, by its cid or by its nonce.
+func (m *Handler) pendingMessage(ctx context.Context, from address.Address, target string) (*types.Message, error) {
+	c, cerr := cid.Decode(target)
+	nonce, nerr := strconv.ParseUint(target, 10, 64)
+	if cerr != nil && nerr != nil {
+		return nil, xerrors.Errorf("%s is neither a message cid nor a nonce", target)
+	}
+
+	pending, err := m.client.PendingMessages(ctx)
+	if err != nil {
+		return nil, err
+	}
+	for _, smsg := range pending {
+		if cerr == nil && (smsg.Cid() == c || smsg.Message.Cid() == c) {
+			if smsg.Message.From != from {
+				return nil, xerrors.Errorf("message %s is sent from %s instead of %s", target, smsg.Message.From, from)
+			}
+			return &smsg.Message, nil
+		}
+		if nerr == nil && smsg.Message.From == from && smsg.Message.Nonce == nonce {
+			return &smsg.Message, nil
+		}
+	}
+	return nil, xerrors.Errorf("message %s of %s is not in the mpool, it may be on chain already", target, from)
+}
+
+// reprice raises the premium of msg to the minimum the mpool accepts for a replacement. The fee cap is feeCap when
+// given, otherwise the highest of the old one and the configured one, always bounded by MaxFee.
+func (m *Handler) reprice(msg *types.Message, feeCap string) error {
+	premium := chain.MinRBF(msg.GasPremium)
+
+	var newCap types.BigInt
+	if feeCap != "" {
+		var err error
+		if newCap, err = types.BigFromString(feeCap); err != nil {
+			return err
+		}
+		if newCap.LessThan(premium) {
+			return xerrors.Errorf("fee cap %s is lower than the premium %s needed to replace the message", newCap, premium)
+		}
+	} else {
+		newCap = big.Max(big.Max(msg.GasFeeCap, m.gasFeeCap), premium)
+	}
+
+	if msg.GasLimit > 0 {
+		maxCap := types.BigDiv(m.maxFee, types.NewInt(uint64(msg.GasLimit)))
+		if newCap.GreaterThan(maxCap) {
+			if feeCap != "" || premium.GreaterThan(maxCap) {
+				return xerrors.Errorf("replacing the message costs more than MaxFee %s", types.FIL(m.maxFee))
+			}
+			newCap = maxCap
+		}
+	}
+
+	msg.GasPremium = premium
+	msg.GasFeeCap = newCap
+	return nil
+}
+
+func (m *Handler) replacePush(ctx context.Context, msg *types.Message, from *sender) (string, error) {
+	if from.key == nil {
+		m.process(1)
+		return "", &PreparedError{Message: msg}
+	}
+
+	m.process(2 / float64(5))
+	signedMsg, err := lib.SignMessage(from.signer, from.key.PrivateKey, msg)
+	if err != nil {
+		return "", err
+	}
+
+	m.process(3 / float64(5))
+	c, err := m.push(ctx, signedMsg)
+	if err != nil {
+		return "", err
+	}
+
+	m.process(4 / float64(5))
+	_, err = m.wait(ctx, c, msg)
+	return c.String(), err
+}
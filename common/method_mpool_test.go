@@ -0,0 +1,106 @@
+package common
+
+import (
+	"fil-assistant/chain"
+	"fil-assistant/lib"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/ipfs/go-cid"
+	"testing"
+)
+
+// stuckSend sends amount FIL from pk to a new address and leaves the message in the mpool.
+func (e *testEnv) stuckSend(t *testing.T, pk, amount string) (*types.SignedMessage, address.Address) {
+	t.Helper()
+	_, to := newKey(t, types.KTSecp256k1)
+	e.node.Stall = true
+	if err := e.h.Send(testCtx, pk, to.String(), amount, nil); err == nil {
+		t.Fatal("stalled message was executed")
+	}
+	e.node.Stall = false
+
+	pushed := e.node.Pushed()
+	return pushed[len(pushed)-1], to
+}
+
+// replacedBy checks that old was replaced by the last pushed message, which got executed, and returns it.
+func (e *testEnv) replacedBy(t *testing.T, old *types.SignedMessage, replacement string) *types.Message {
+	t.Helper()
+	pushed := e.node.Pushed()
+	last := pushed[len(pushed)-1]
+	if last.Cid().String() != replacement {
+		t.Fatalf("replacement %s, last pushed %s", replacement, last.Cid())
+	}
+	lookup, err := e.node.Client().WaitMsgLookup(testCtx, old.Cid(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if lookup.Message != last.Cid() || lookup.Receipt.ExitCode != 0 {
+		t.Fatalf("%s executed as %s with exit code %d", old.Cid(), lookup.Message, lookup.Receipt.ExitCode)
+	}
+	return &last.Message
+}
+
+func TestReplace(t *testing.T) {
+	e := newTestEnv(t)
+	pk, _ := e.newAccount(t, "10")
+	stuck, to := e.stuckSend(t, pk, "1")
+	minPremium := chain.MinRBF(stuck.Message.GasPremium)
+
+	key, err := parsePrivateKey(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	low := stuck.Message
+	low.GasPremium = big.Sub(minPremium, big.NewInt(1))
+	lowMsg, err := lib.SignMessage(new(lib.Secp256Signer), key.PrivateKey, &low)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.node.Client().PushMsg(testCtx, lowMsg); err == nil {
+		t.Fatal("replacement below the minimum premium accepted")
+	}
+
+	if _, err = e.h.Replace(testCtx, pk, "0", "1"); err == nil {
+		t.Fatal("replaced with a fee cap below the premium")
+	}
+
+	c, err := e.h.Replace(testCtx, pk, "0", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := e.replacedBy(t, stuck, c)
+	if !msg.GasPremium.Equals(minPremium) || msg.GasFeeCap.LessThan(minPremium) {
+		t.Errorf("replacement premium %s fee cap %s, want premium %s", msg.GasPremium, msg.GasFeeCap, minPremium)
+	}
+	if msg.Nonce != stuck.Message.Nonce || msg.To != to || !msg.Value.Equals(stuck.Message.Value) {
+		t.Errorf("replacement %+v differs from %+v", msg, stuck.Message)
+	}
+	checkBalance(t, e, to, "1")
+}
+
+func TestCancel(t *testing.T) {
+	e := newTestEnv(t)
+	pk, from := e.newAccount(t, "10")
+	stuck, to := e.stuckSend(t, pk, "1")
+
+	if _, err := e.h.Cancel(testCtx, pk, cid.Undef.String(), ""); err == nil {
+		t.Fatal("cancelled an unknown message")
+	}
+
+	c, err := e.h.Cancel(testCtx, pk, stuck.Cid().String(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	msg := e.replacedBy(t, stuck, c)
+	if msg.To != from || !msg.Value.IsZero() || msg.Nonce != stuck.Message.Nonce {
+		t.Errorf("cancellation sends %s to %s with nonce %d, want 0 to itself with nonce %d", msg.Value, msg.To,
+			msg.Nonce, stuck.Message.Nonce)
+	}
+	if !msg.GasPremium.Equals(chain.MinRBF(stuck.Message.GasPremium)) {
+		t.Errorf("cancellation premium %s, want %s", msg.GasPremium, chain.MinRBF(stuck.Message.GasPremium))
+	}
+	checkBalance(t, e, to, "0")
+	checkBalance(t, e, from, "10")
+}
@@ -3,9 +3,12 @@ package common
 import (
 	"bytes"
 	"context"
+	"encoding/csv"
+	"encoding/hex"
 	"encoding/json"
-	"fil-assistant/lib"
+	"fil-assistant/chain"
 	"fil-assistant/utils"
+	"fmt"
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/abi"
 	"github.com/filecoin-project/lotus/chain/actors"
@@ -13,34 +16,64 @@ import (
 	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
 	init_ "github.com/filecoin-project/specs-actors/v6/actors/builtin/init"
 	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
-	cbg "github.com/whyrusleeping/cbor-gen"
+	"github.com/minio/blake2b-simd"
 	"golang.org/x/xerrors"
+	"io"
+	"sort"
 	"strconv"
+	"strings"
 )
 
 type Proposal struct {
 	Msig string
 	TxnID string
+	// Hash is the hex encoded proposal hash of the transaction the signer reviewed, the chain rejects the approval
+	// or cancellation of a different transaction.
+	Hash string
 }
 
-type msigInfo struct {
-	ID 			int64 				`json:"提案号"`
+// CallInfo is a call of an actor method decoded for review. Params holds the decoded params, or their hex when the
+// actor or the method is unknown, Note tells why. Inner is the call a multisig Propose, Approve or Cancel carries.
+type CallInfo struct {
 	To     		address.Address		`json:"接收方"`
 	Value  		abi.TokenAmount		`json:"金额"`
 	Method 		string				`json:"方法"`
-	Params 		cbg.CBORUnmarshaler	`json:"参数"`
+	Params 		interface{}			`json:"参数"`
+	Note 		string				`json:"说明,omitempty"`
+	Inner 		*CallInfo			`json:"内层调用,omitempty"`
+}
+
+// maxCallDepth bounds the decoding of calls nested in multisig proposals.
+const maxCallDepth = 4
+
+// MethodChain returns the method of the call followed by the methods of the calls nested in it.
+func (c *CallInfo) MethodChain() string {
+	if c.Inner == nil {
+		return c.Method
+	}
+	return c.Method + " -> " + c.Inner.MethodChain()
+}
+
+func (c *CallInfo) addresses() []address.Address {
+	if c.Inner == nil {
+		return []address.Address{c.To}
+	}
+	return append(c.Inner.addresses(), c.To)
+}
+
+// ProposalInfo is a pending transaction of a multisig, decoded for review.
+type ProposalInfo struct {
+	ID 			int64 				`json:"提案号"`
+	CallInfo
 	Approved 	[]address.Address	`json:"已赞成signer"`
+	Threshold 	uint64				`json:"投票阈值"`
+	Labels 		map[string]string	`json:"地址簿标签,omitempty"`
+	Hash 		string				`json:"提案哈希"`
 }
 
 func (m *Handler) CreateMultisig(ctx context.Context, addresses []string, pk, threshold, duration,
 	initAmount string) (string, error) {
-	pki, err := parsePrivateKey(pk)
-	if err != nil {
-		return "", err
-	}
-
-	signer := lib.ChooseSigner(pki.Type)
-	from, err := signer.ToAddress(pki.PrivateKey)
+	from, err := m.sender(pk)
 	if err != nil {
 		return "", err
 	}
@@ -99,13 +132,16 @@ func (m *Handler) CreateMultisig(ctx context.Context, addresses []string, pk, th
 	}
 
 	rawMsg := &types.Message{
-		From:       from,
+		From:       from.addr,
 		To:         builtin.InitActorAddr,
 		Value:      abi.TokenAmount(amount),
 		Method: 	builtin.MethodsInit.Exec,
 		Params: 	enc,
 	}
-	res, err := m.messagePush(ctx, rawMsg, pki, signer, 0)
+	res, err := m.messagePush(ctx, rawMsg, from, 0)
+	if err != nil {
+		return "", err
+	}
 	execreturn := new(init_.ExecReturn)
 	if err = execreturn.UnmarshalCBOR(bytes.NewReader(res)); err != nil {
 		return "", err
@@ -123,7 +159,7 @@ func (m *Handler) ProposeAddSigner(ctx context.Context, pk, newAddr string, incr
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -141,7 +177,7 @@ func (m *Handler) ProposeAddSigner(ctx context.Context, pk, newAddr string, incr
 		return err
 	}
 
-	proposal.TxnID, err = m.propose(ctx, pki, msig, &multisig.ProposeParams{
+	proposal.TxnID, err = m.propose(ctx, from, msig, &multisig.ProposeParams{
 		To: msig,
 		Value: abi.NewTokenAmount(0),
 		Method: builtin.MethodsMultisig.AddSigner,
@@ -159,7 +195,7 @@ func (m *Handler) ProposeSwapSigner(ctx context.Context, pk, old, new string, pr
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -182,7 +218,7 @@ func (m *Handler) ProposeSwapSigner(ctx context.Context, pk, old, new string, pr
 		return err
 	}
 
-	proposal.TxnID, err = m.propose(ctx, pki, msig, &multisig.ProposeParams{
+	proposal.TxnID, err = m.propose(ctx, from, msig, &multisig.ProposeParams{
 		To: msig,
 		Value: abi.NewTokenAmount(0),
 		Method: builtin.MethodsMultisig.SwapSigner,
@@ -200,7 +236,7 @@ func (m *Handler) ProposeRemoveSigner(ctx context.Context, pk, toRemove string,
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -218,7 +254,7 @@ func (m *Handler) ProposeRemoveSigner(ctx context.Context, pk, toRemove string,
 		return err
 	}
 
-	proposal.TxnID, err = m.propose(ctx, pki, msig, &multisig.ProposeParams{
+	proposal.TxnID, err = m.propose(ctx, from, msig, &multisig.ProposeParams{
 		To: msig,
 		Value: abi.NewTokenAmount(0),
 		Method: builtin.MethodsMultisig.RemoveSigner,
@@ -236,7 +272,7 @@ func (m *Handler) ProposeChangeThreshold(ctx context.Context, pk, threshold stri
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -253,7 +289,7 @@ func (m *Handler) ProposeChangeThreshold(ctx context.Context, pk, threshold stri
 		return err
 	}
 
-	proposal.TxnID, err = m.propose(ctx, pki, msig, &multisig.ProposeParams{
+	proposal.TxnID, err = m.propose(ctx, from, msig, &multisig.ProposeParams{
 		To: msig,
 		Value: abi.NewTokenAmount(0),
 		Method: builtin.MethodsMultisig.ChangeNumApprovalsThreshold,
@@ -271,7 +307,7 @@ func (m *Handler) ProposeLockBalance(ctx context.Context, pk, start, duration, a
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
@@ -300,7 +336,7 @@ func (m *Handler) ProposeLockBalance(ctx context.Context, pk, start, duration, a
 		return err
 	}
 
-	proposal.TxnID, err = m.propose(ctx, pki, msig, &multisig.ProposeParams{
+	proposal.TxnID, err = m.propose(ctx, from, msig, &multisig.ProposeParams{
 		To: msig,
 		Value: abi.NewTokenAmount(0),
 		Method: builtin.MethodsMultisig.LockBalance,
@@ -309,29 +345,33 @@ func (m *Handler) ProposeLockBalance(ctx context.Context, pk, start, duration, a
 	return err
 }
 
+// ApproveOrCancel approves or cancels a pending proposal. The proposal hash is sent along, so that the chain only
+// applies it to the transaction in proposal.Hash, as GetProposal showed it to the signer.
 func (m *Handler) ApproveOrCancel(ctx context.Context, pk string, approve bool, proposal Proposal) error {
 	msigAddr, err := address.NewFromString(proposal.Msig)
 	if err != nil {
 		return err
 	}
 
-	pki, err := parsePrivateKey(pk)
+	from, err := m.sender(pk)
 	if err != nil {
 		return err
 	}
 
-	signer := lib.ChooseSigner(pki.Type)
-	from, err := signer.ToAddress(pki.PrivateKey)
+	txnid, err := strconv.ParseInt(proposal.TxnID, 10, 64)
 	if err != nil {
 		return err
 	}
 
-	txnid, err := strconv.ParseInt(proposal.TxnID, 10, 64)
+	if proposal.Hash == "" {
+		return xerrors.Errorf("no hash of proposal %d, review it first", txnid)
+	}
+	hash, err := hex.DecodeString(proposal.Hash)
 	if err != nil {
-		return err
+		return xerrors.Errorf("invalid proposal hash %s: %w", proposal.Hash, err)
 	}
 
-	enc, err := actors.SerializeParams(&multisig.TxnIDParams{ID: multisig.TxnID(txnid)})
+	enc, err := actors.SerializeParams(&multisig.TxnIDParams{ID: multisig.TxnID(txnid), ProposalHash: hash})
 	if err != nil {
 		return err
 	}
@@ -344,35 +384,198 @@ func (m *Handler) ApproveOrCancel(ctx context.Context, pk string, approve bool,
 	}
 	rawMsg := &types.Message{
 		To:     msigAddr,
-		From:   from,
+		From:   from.addr,
 		Value:  abi.NewTokenAmount(0),
 		Method: method,
 		Params: enc,
 	}
-	_, err = m.messagePush(ctx, rawMsg, pki, signer, 0)
+	_, err = m.messagePush(ctx, rawMsg, from, 0)
 	return err
 }
 
-func (m *Handler) propose(ctx context.Context, pki *types.KeyInfo, msig address.Address, params *multisig.ProposeParams,
-	start int) (string, error) {
-	signer := lib.ChooseSigner(pki.Type)
-	from, err := signer.ToAddress(pki.PrivateKey)
+// GetProposal fetches the pending transaction proposal.TxnID of proposal.Msig.
+func (m *Handler) GetProposal(ctx context.Context, proposal Proposal) (*ProposalInfo, error) {
+	msigAddr, err := address.NewFromString(proposal.Msig)
 	if err != nil {
-		return "", err
+		return nil, err
+	}
+	txnid, err := strconv.ParseInt(proposal.TxnID, 10, 64)
+	if err != nil {
+		return nil, err
+	}
+
+	trxs, err := m.client.GetPendingMsigTrxs(ctx, msigAddr)
+	if err != nil {
+		return nil, err
+	}
+	for _, trx := range trxs {
+		if trx.ID == txnid {
+			threshold, err := m.threshold(ctx, msigAddr)
+			if err != nil {
+				return nil, err
+			}
+			info, err := m.decodeProposal(ctx, trx, threshold)
+			if err == nil && info.Hash == "" {
+				return nil, xerrors.Errorf("proposal %d of %s has no approval left, it can not be approved", txnid,
+					proposal.Msig)
+			}
+			return info, err
+		}
+	}
+	return nil, xerrors.Errorf("proposal %d of %s is not pending", txnid, proposal.Msig)
+}
+
+// DescribeProposal formats info for the signer to review, with FIL amounts and the labels of the address book.
+func (m *Handler) DescribeProposal(info *ProposalInfo) string {
+	params, _ := json.MarshalIndent(info.Params, "", "  ")
+	approved := make([]string, 0, len(info.Approved))
+	for _, addr := range info.Approved {
+		approved = append(approved, m.Describe(addr.String()))
+	}
+	res := fmt.Sprintf("提案号: %d\n接收方: %s\n金额: %s\n方法: %s\n参数: %s\n已赞成(%d/%d): %s\n提案哈希: %s",
+		info.ID, m.Describe(info.To.String()), types.FIL(info.Value), info.Method, params, len(info.Approved),
+		info.Threshold, strings.Join(approved, ", "), info.Hash)
+	if info.Note != "" {
+		res += "\n说明: " + info.Note
+	}
+	for inner := info.Inner; inner != nil; inner = inner.Inner {
+		params, _ := json.Marshal(inner.Params)
+		res += fmt.Sprintf("\n内层调用: %s 金额 %s 方法 %s 参数 %s", m.Describe(inner.To.String()), types.FIL(inner.Value),
+			inner.Method, params)
+		if inner.Note != "" {
+			res += " (" + inner.Note + ")"
+		}
+	}
+	return res
+}
+
+// threshold reads the number of approvals the proposals of msig need.
+func (m *Handler) threshold(ctx context.Context, msig address.Address) (uint64, error) {
+	act, err := m.client.ReadState(ctx, msig)
+	if err != nil {
+		return 0, err
+	}
+	if act.Code != builtin.MultisigActorCodeID {
+		return 0, xerrors.Errorf("%s is not a multisig, actor code %s", msig, act.Code)
+	}
+	st := new(multisig.State)
+	if err = json.Unmarshal(act.State, st); err != nil {
+		return 0, xerrors.Errorf("failed to decode multisig state: %w", err)
+	}
+	return st.NumApprovalsThreshold, nil
+}
+
+// decodeProposal leaves Hash empty, with a note, when no signer approves trx any more: the hash covers the first
+// approval, the proposer's, so such a transaction can only be reviewed.
+func (m *Handler) decodeProposal(ctx context.Context, trx chain.MsigTransaction, threshold uint64) (*ProposalInfo,
+	error) {
+	call := m.decodeCall(ctx, trx.To, trx.Value, trx.Method, trx.Params, 0)
+	info := &ProposalInfo{
+		ID: trx.ID,
+		CallInfo: *call,
+		Approved: trx.Approved,
+		Threshold: threshold,
+		Labels: m.labels(append(call.addresses(), trx.Approved...)...),
+	}
+	if len(trx.Approved) == 0 {
+		if info.Note != "" {
+			info.Note += "; "
+		}
+		info.Note += "提案已没有赞成者, 无法计算提案哈希"
+		return info, nil
+	}
+
+	hash, err := multisig.ComputeProposalHash(&multisig.Transaction{
+		To:       trx.To,
+		Value:    trx.Value,
+		Method:   trx.Method,
+		Params:   trx.Params,
+		Approved: trx.Approved,
+	}, blake2b.Sum256)
+	if err != nil {
+		return nil, err
+	}
+	info.Hash = hex.EncodeToString(hash)
+	return info, nil
+}
+
+// decodeCall decodes the params of a call for review. It does not fail: what can not be decoded is kept as hex with
+// a note, so that a single odd proposal does not hide the others.
+func (m *Handler) decodeCall(ctx context.Context, to address.Address, value abi.TokenAmount, method abi.MethodNum,
+	params []byte, depth int) *CallInfo {
+	call := &CallInfo{
+		To:     to,
+		Value:  value,
+		Method: strconv.FormatUint(uint64(method), 10),
+		Params: hex.EncodeToString(params),
+	}
+	if method == builtin.MethodSend && len(params) == 0 {
+		call.Method, call.Params = "Send", abi.Empty
+	}
+
+	code, err := m.client.StateGetActorCode(ctx, to)
+	if err != nil {
+		if xerrors.Is(err, chain.ErrActorNotFound) {
+			call.Note = "接收地址尚不存在"
+		} else {
+			call.Note = err.Error()
+		}
+		return call
 	}
+	name, val, found := utils.LookupMethod(code, method)
+	if !found {
+		call.Note = fmt.Sprintf("未知的方法, actor代码 %s", code)
+		return call
+	}
+	call.Method = name
+	if err = val.UnmarshalCBOR(bytes.NewReader(params)); err != nil {
+		call.Note = fmt.Sprintf("参数解码失败: %s", err)
+		return call
+	}
+	call.Params = val
+
+	if code != builtin.MultisigActorCodeID || depth+1 >= maxCallDepth {
+		return call
+	}
+	switch p := val.(type) {
+	case *multisig.ProposeParams:
+		call.Inner = m.decodeCall(ctx, p.To, p.Value, p.Method, p.Params, depth+1)
+	case *multisig.TxnIDParams:
+		// approving or cancelling a proposal of another multisig, show what that proposal does
+		trxs, err := m.client.GetPendingMsigTrxs(ctx, to)
+		if err != nil {
+			call.Note = err.Error()
+			return call
+		}
+		call.Note = fmt.Sprintf("提案%d已不是%s的待定提案", p.ID, to)
+		for _, trx := range trxs {
+			if trx.ID == int64(p.ID) {
+				call.Note = ""
+				call.Inner = m.decodeCall(ctx, trx.To, trx.Value, trx.Method, trx.Params, depth+1)
+			}
+		}
+	}
+	return call
+}
 
+func (m *Handler) propose(ctx context.Context, from *sender, msig address.Address, params *multisig.ProposeParams,
+	start int) (string, error) {
+	var err error // not the aerrors.ActorError of SerializeParams, messagePush returns a plain error
 	enc, err := actors.SerializeParams(params)
 	if err != nil {
 		return "", err
 	}
 	rawMsg := &types.Message{
 		To:     msig,
-		From:   from,
+		From:   from.addr,
 		Value:  abi.NewTokenAmount(0),
 		Method: builtin.MethodsMultisig.Propose,
 		Params: enc,
 	}
-	ret, err := m.messagePush(ctx, rawMsg, pki, signer, start)
+	ret, err := m.messagePush(ctx, rawMsg, from, start)
+	if err != nil {
+		return "", err
+	}
 	retval := new(multisig.ProposeReturn)
 	if err = retval.UnmarshalCBOR(bytes.NewReader(ret)); err != nil {
 		return "", xerrors.Errorf("failed to unmarshal propose return value: %w", err)
@@ -384,7 +587,8 @@ func (m *Handler) propose(ctx context.Context, pki *types.KeyInfo, msig address.
 	}
 }
 
-func (m *Handler) GetPendingProposals(ctx context.Context, addr string) ([]byte, error) {
+// GetPendingProposals returns the pending transactions of a multisig, ordered by ID.
+func (m *Handler) GetPendingProposals(ctx context.Context, addr string) ([]*ProposalInfo, error) {
 	msigAddr, err := address.NewFromString(addr)
 	if err != nil {
 		return nil, err
@@ -395,42 +599,141 @@ func (m *Handler) GetPendingProposals(ctx context.Context, addr string) ([]byte,
 	if err != nil {
 		return nil, err
 	}
+	threshold, err := m.threshold(ctx, msigAddr)
+	if err != nil {
+		return nil, err
+	}
 
 	base := 0.5 / float64(len(trxs))
-	var res bytes.Buffer
+	res := make([]*ProposalInfo, 0, len(trxs))
 	for i, trx := range trxs {
 		m.process(0.5 + float64(i) * base)
-		code, err := m.client.StateGetActorCode(ctx, trx.To)
+		info, err := m.decodeProposal(ctx, trx, threshold)
 		if err != nil {
 			return nil, err
 		}
+		res = append(res, info)
+	}
+	sort.Slice(res, func(i, j int) bool {
+		return res[i].ID < res[j].ID
+	})
 
-		m, found := utils.MethodsMap[code][trx.Method]
-		if !found {
-			return nil, xerrors.Errorf("unknown method %d for actor %s", trx.Method, code)
-		}
-
-		if err = m.Params.UnmarshalCBOR(bytes.NewReader(trx.Params)); err != nil {
-			return nil, err
-		}
+	return res, nil
+}
 
-		mi, err := json.Marshal(msigInfo{
-			ID: trx.ID,
-			To: trx.To,
-			Value: trx.Value,
-			Method: m.Name,
-			Params: m.Params,
-			Approved: trx.Approved,
-		})
+// WriteProposalsCSV writes one line per proposal, amounts in FIL and params as JSON.
+func WriteProposalsCSV(w io.Writer, infos []*ProposalInfo) error {
+	writer := csv.NewWriter(w)
+	header := []string{"id", "to", "label", "value", "method", "params", "note", "approvals", "threshold",
+		"approved", "hash"}
+	if err := writer.Write(header); err != nil {
+		return err
+	}
+	for _, info := range infos {
+		params, err := json.Marshal(info.Params)
 		if err != nil {
-			return nil, err
+			return err
 		}
-		err = json.Indent(&res, mi, "", "\t")
-		if err != nil {
-			return nil, err
+		approved := make([]string, 0, len(info.Approved))
+		for _, addr := range info.Approved {
+			approved = append(approved, addr.String())
 		}
-		res.WriteByte('\n')
+		record := []string{strconv.FormatInt(info.ID, 10), info.To.String(), info.Labels[info.To.String()],
+			types.FIL(info.Value).Unitless(), info.MethodChain(), string(params), info.Note,
+			strconv.Itoa(len(info.Approved)), strconv.FormatUint(info.Threshold, 10), strings.Join(approved, " "),
+			info.Hash}
+		if err = writer.Write(record); err != nil {
+			return err
+		}
+	}
+	writer.Flush()
+	return writer.Error()
+}
+
+func WriteProposalsJSON(w io.Writer, infos []*ProposalInfo) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(infos)
+}
+
+type MultisigSigner struct {
+	ID      string `json:"ID地址"`
+	Address string `json:"地址"`
+	Label   string `json:"地址簿标签,omitempty"`
+}
+
+type MultisigInfo struct {
+	Address        string           `json:"多签账号"`
+	ID             string           `json:"ID地址"`
+	Balance        string           `json:"余额"`
+	Spendable      string           `json:"可用余额"`
+	Locked         string           `json:"锁定余额"`
+	InitialBalance string           `json:"初始锁定余额"`
+	StartEpoch     abi.ChainEpoch   `json:"起始高度"`
+	UnlockDuration abi.ChainEpoch   `json:"锁定期限"`
+	Threshold      uint64           `json:"投票阈值"`
+	NextTxnID      int64            `json:"下一提案号"`
+	Signers        []MultisigSigner `json:"signers"`
+}
+
+// GetMultisigInfo reads the configuration and the balances of a multisig. Signers are stored as ID addresses, their
+// key address is looked up too when they are accounts.
+func (m *Handler) GetMultisigInfo(ctx context.Context, addr string) (*MultisigInfo, error) {
+	msigAddr, err := address.NewFromString(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	m.process(0.1)
+	id, err := m.client.LookupID(ctx, msigAddr)
+	if err != nil {
+		return nil, err
+	}
+	act, err := m.client.ReadState(ctx, msigAddr)
+	if err != nil {
+		return nil, err
+	}
+	if act.Code != builtin.MultisigActorCodeID {
+		return nil, xerrors.Errorf("%s is not a multisig, actor code %s", addr, act.Code)
+	}
+	st := new(multisig.State)
+	if err = json.Unmarshal(act.State, st); err != nil {
+		return nil, xerrors.Errorf("failed to decode multisig state: %w", err)
+	}
+
+	m.process(0.3)
+	spendable, err := m.client.GetMsigAvailableBalance(ctx, msigAddr)
+	if err != nil {
+		return nil, err
+	}
+	vesting, err := m.client.GetMsigVesting(ctx, msigAddr)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &MultisigInfo{
+		Address:        addr,
+		ID:             id.String(),
+		Balance:        types.FIL(act.Balance).String(),
+		Spendable:      types.FIL(spendable).String(),
+		Locked:         types.FIL(types.BigSub(act.Balance, spendable)).String(),
+		InitialBalance: types.FIL(vesting.InitialBalance).String(),
+		StartEpoch:     vesting.StartEpoch,
+		UnlockDuration: vesting.UnlockDuration,
+		Threshold:      st.NumApprovalsThreshold,
+		NextTxnID:      int64(st.NextTxnID),
 	}
 
-	return res.Bytes(), nil
-}
\ No newline at end of file
+	base := 0.5 / float64(len(st.Signers))
+	for i, signer := range st.Signers {
+		m.process(0.5 + float64(i) * base)
+		id, key := m.idAndKey(ctx, signer)
+		s := MultisigSigner{ID: id.String(), Address: key.String()}
+		s.Label = m.book.Label(s.ID)
+		if s.Label == "" {
+			s.Label = m.book.Label(s.Address)
+		}
+		info.Signers = append(info.Signers, s)
+	}
+	return info, nil
+}
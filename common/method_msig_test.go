@@ -0,0 +1,466 @@
+package common
+
+import (
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newMultisig creates a multisig of the signers holding 5 FIL through the Handler, proposed by the first signer.
+func (e *testEnv) newMultisig(t *testing.T, threshold string, pks ...string) string {
+	t.Helper()
+	signers := make([]string, 0, len(pks))
+	for _, pk := range pks {
+		from, err := e.h.sender(pk)
+		if err != nil {
+			t.Fatal(err)
+		}
+		signers = append(signers, from.addr.String())
+	}
+	msig, err := e.h.CreateMultisig(testCtx, signers, pks[0], threshold, "0", "5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	return msig
+}
+
+func (e *testEnv) msigInfo(t *testing.T, msig string) *MultisigInfo {
+	t.Helper()
+	info, err := e.h.GetMultisigInfo(testCtx, msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return info
+}
+
+// approve approves txnID after reviewing it, as the signer would.
+func (e *testEnv) approve(t *testing.T, pk, msig, txnID string) {
+	t.Helper()
+	info, err := e.h.GetProposal(testCtx, Proposal{Msig: msig, TxnID: txnID})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.h.ApproveOrCancel(testCtx, pk, true, Proposal{Msig: msig, TxnID: txnID, Hash: info.Hash}); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func (e *testEnv) checkPending(t *testing.T, msig string, want int) {
+	t.Helper()
+	infos, err := e.h.GetPendingProposals(testCtx, msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != want {
+		t.Fatalf("%d pending proposals, want %d", len(infos), want)
+	}
+}
+
+func signerIDs(info *MultisigInfo) string {
+	ids := make([]string, 0, len(info.Signers))
+	for _, s := range info.Signers {
+		ids = append(ids, s.ID)
+	}
+	return strings.Join(ids, " ")
+}
+
+func TestCreateMultisig(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, a := e.newAccount(t, "10")
+	pkB, b := e.newAccount(t, "1")
+
+	msig := e.newMultisig(t, "2", pkA, pkB)
+	info := e.msigInfo(t, msig)
+	if want := e.lookupID(t, a).String() + " " + e.lookupID(t, b).String(); signerIDs(info) != want {
+		t.Errorf("signers %s, want %s", signerIDs(info), want)
+	}
+	if info.Threshold != 2 {
+		t.Errorf("threshold %d, want 2", info.Threshold)
+	}
+	msigAddr, _ := address.NewFromString(msig)
+	checkBalance(t, e, msigAddr, "5")
+	checkBalance(t, e, a, "5")
+
+	if _, err := e.h.CreateMultisig(testCtx, []string{a.String()}, pkA, "2", "0", "1"); err == nil {
+		t.Error("created a multisig with a threshold above its signers")
+	}
+}
+
+func newTestBook(t *testing.T, entries ...AddressBookEntry) *AddressBook {
+	t.Helper()
+	book, err := OpenAddressBook(filepath.Join(t.TempDir(), "addressbook.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = book.Import(entries); err != nil {
+		t.Fatal(err)
+	}
+	return book
+}
+
+// TestGetMultisigInfo reads a multisig vesting 10 FIL over 100 epochs, a quarter of the way through.
+func TestGetMultisigInfo(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, a := e.newAccount(t, "20")
+	_, b := e.newAccount(t, "1")
+	_, to := newKey(t, types.KTSecp256k1)
+	idA, idB := e.lookupID(t, a), e.lookupID(t, b)
+	e.h.SetAddressBook(newTestBook(t,
+		AddressBookEntry{Address: a.String(), Label: "alice"},
+		AddressBookEntry{Address: idB.String(), Label: "bob"}))
+
+	msig, err := e.h.CreateMultisig(testCtx, []string{a.String(), b.String()}, pkA, "2", "100", "10")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = e.h.Send(testCtx, pkA, to.String(), "1", &Proposal{Msig: msig}); err != nil {
+		t.Fatal(err)
+	}
+	e.node.SetHeight(25)
+
+	info := e.msigInfo(t, msig)
+	if info.Balance != "10 FIL" || info.Spendable != "2.5 FIL" || info.Locked != "7.5 FIL" {
+		t.Errorf("balance %s, spendable %s, locked %s", info.Balance, info.Spendable, info.Locked)
+	}
+	if info.InitialBalance != "10 FIL" || info.StartEpoch != 0 || info.UnlockDuration != 100 {
+		t.Errorf("vesting %s from %d for %d", info.InitialBalance, info.StartEpoch, info.UnlockDuration)
+	}
+	if info.Threshold != 2 || info.NextTxnID != 1 {
+		t.Errorf("threshold %d, next proposal %d", info.Threshold, info.NextTxnID)
+	}
+	want := []MultisigSigner{
+		{ID: idA.String(), Address: a.String(), Label: "alice"},
+		{ID: idB.String(), Address: b.String(), Label: "bob"},
+	}
+	if len(info.Signers) != len(want) || info.Signers[0] != want[0] || info.Signers[1] != want[1] {
+		t.Errorf("signers %+v, want %+v", info.Signers, want)
+	}
+
+	if _, err = e.h.GetMultisigInfo(testCtx, a.String()); err == nil {
+		t.Error("read an account as a multisig")
+	}
+}
+
+func TestProposeSend(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, _ := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	_, to := newKey(t, types.KTSecp256k1)
+	msig := e.newMultisig(t, "2", pkA, pkB)
+
+	proposal := &Proposal{Msig: msig}
+	if err := e.h.Send(testCtx, pkA, to.String(), "1", proposal); err != nil {
+		t.Fatal(err)
+	}
+	if proposal.TxnID != "0" {
+		t.Fatalf("proposal %s, want 0", proposal.TxnID)
+	}
+	checkBalance(t, e, to, "0")
+
+	e.approve(t, pkB, msig, proposal.TxnID)
+	checkBalance(t, e, to, "1")
+	e.checkPending(t, msig, 0)
+}
+
+// TestProposeSigners runs every multisig configuration proposal through to its approval.
+func TestProposeSigners(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, a := e.newAccount(t, "10")
+	pkB, b := e.newAccount(t, "1")
+	pkC, c := e.newAccount(t, "1")
+	_, d := e.newAccount(t, "0")
+	_, f := e.newAccount(t, "0")
+	msig := e.newMultisig(t, "2", pkA, pkB, pkC)
+	ids := func(addrs ...address.Address) string {
+		res := make([]string, 0, len(addrs))
+		for _, addr := range addrs {
+			res = append(res, e.lookupID(t, addr).String())
+		}
+		return strings.Join(res, " ")
+	}
+
+	proposal := &Proposal{Msig: msig}
+	if err := e.h.ProposeAddSigner(testCtx, pkA, d.String(), false, proposal); err != nil {
+		t.Fatal(err)
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	if info := e.msigInfo(t, msig); signerIDs(info) != ids(a, b, c, d) || info.Threshold != 2 {
+		t.Fatalf("after AddSigner: signers %s threshold %d", signerIDs(info), info.Threshold)
+	}
+
+	if err := e.h.ProposeSwapSigner(testCtx, pkA, d.String(), f.String(), proposal); err != nil {
+		t.Fatal(err)
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	if info := e.msigInfo(t, msig); signerIDs(info) != ids(a, b, c, f) {
+		t.Fatalf("after SwapSigner: signers %s", signerIDs(info))
+	}
+
+	if err := e.h.ProposeRemoveSigner(testCtx, pkA, f.String(), false, proposal); err != nil {
+		t.Fatal(err)
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	if info := e.msigInfo(t, msig); signerIDs(info) != ids(a, b, c) {
+		t.Fatalf("after RemoveSigner: signers %s", signerIDs(info))
+	}
+
+	if err := e.h.ProposeChangeThreshold(testCtx, pkA, "3", proposal); err != nil {
+		t.Fatal(err)
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	if info := e.msigInfo(t, msig); info.Threshold != 3 {
+		t.Fatalf("after ChangeNumApprovalsThreshold: threshold %d", info.Threshold)
+	}
+
+	if err := e.h.ProposeLockBalance(testCtx, pkA, "10", "100", "2", proposal); err != nil {
+		t.Fatal(err)
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	if info := e.msigInfo(t, msig); info.UnlockDuration != 0 {
+		t.Fatal("locked the balance with 2 of 3 approvals")
+	}
+	e.approve(t, pkC, msig, proposal.TxnID)
+	info := e.msigInfo(t, msig)
+	if info.StartEpoch != 10 || info.UnlockDuration != 100 || info.InitialBalance != "2 FIL" {
+		t.Fatalf("after LockBalance: %s from %d for %d", info.InitialBalance, info.StartEpoch, info.UnlockDuration)
+	}
+	e.checkPending(t, msig, 0)
+
+	if err := e.h.ProposeAddSigner(testCtx, pkA, d.String(), false, nil); err == nil {
+		t.Error("proposed without a multisig")
+	}
+}
+
+func TestProposeMinerOwner(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, a := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	msig := e.newMultisig(t, "2", pkA, pkB)
+	msigID, _ := address.NewFromString(msig)
+	mID, st := e.newMiner(t, a, "0", "0")
+
+	if err := e.h.ChangeOwner1(testCtx, pkA, msig, mID.String(), nil); err != nil {
+		t.Fatal(err)
+	}
+	proposal := &Proposal{Msig: msig}
+	if err := e.h.ChangeOwner2(testCtx, pkA, mID.String(), proposal); err != nil {
+		t.Fatal(err)
+	}
+	if st.Info.Owner == msigID {
+		t.Fatal("owner changed before the proposal was approved")
+	}
+	e.approve(t, pkB, msig, proposal.TxnID)
+	if st.Info.Owner != msigID {
+		t.Fatalf("owner %s, want %s", st.Info.Owner, msig)
+	}
+}
+
+func TestApproveOrCancel(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, _ := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	_, to := newKey(t, types.KTSecp256k1)
+	msig := e.newMultisig(t, "2", pkA, pkB)
+
+	first, second := &Proposal{Msig: msig}, &Proposal{Msig: msig}
+	if err := e.h.Send(testCtx, pkA, to.String(), "1", first); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.h.Send(testCtx, pkA, to.String(), "2", second); err != nil {
+		t.Fatal(err)
+	}
+	info, err := e.h.GetProposal(testCtx, *second)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	pushed := len(e.node.Pushed())
+	if err = e.h.ApproveOrCancel(testCtx, pkB, true, *first); err == nil {
+		t.Fatal("approved without the hash of the reviewed proposal")
+	}
+	if len(e.node.Pushed()) != pushed {
+		t.Fatal("approval without hash pushed")
+	}
+
+	// the hash of another proposal is rejected by the chain
+	stale := Proposal{Msig: msig, TxnID: first.TxnID, Hash: info.Hash}
+	if err = e.h.ApproveOrCancel(testCtx, pkB, true, stale); err == nil {
+		t.Fatal("approved with the hash of another proposal")
+	}
+	checkBalance(t, e, to, "0")
+
+	// only the proposer cancels
+	second.Hash = info.Hash
+	if err = e.h.ApproveOrCancel(testCtx, pkB, false, *second); err == nil {
+		t.Fatal("cancelled by another signer than the proposer")
+	}
+	if err = e.h.ApproveOrCancel(testCtx, pkA, false, *second); err != nil {
+		t.Fatal(err)
+	}
+	e.checkPending(t, msig, 1)
+
+	e.approve(t, pkB, msig, first.TxnID)
+	checkBalance(t, e, to, "1")
+	e.checkPending(t, msig, 0)
+}
+
+func TestGetPendingProposals(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, a := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	_, d := e.newAccount(t, "0")
+	_, to := newKey(t, types.KTSecp256k1)
+	msig := e.newMultisig(t, "2", pkA, pkB)
+
+	if err := e.h.Send(testCtx, pkA, to.String(), "1.5", &Proposal{Msig: msig}); err != nil {
+		t.Fatal(err)
+	}
+	if err := e.h.ProposeAddSigner(testCtx, pkA, d.String(), true, &Proposal{Msig: msig}); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := e.h.GetPendingProposals(testCtx, msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 2 || infos[0].ID != 0 || infos[1].ID != 1 {
+		t.Fatalf("got %d proposals, want 0 and 1", len(infos))
+	}
+
+	send := infos[0]
+	if send.Method != "Send" || send.To != to || !send.Value.Equals(mustFIL(t, "1.5")) || send.Threshold != 2 {
+		t.Errorf("first proposal %s %s %s threshold %d", send.Method, send.To, types.FIL(send.Value), send.Threshold)
+	}
+	if len(send.Approved) != 1 || send.Approved[0] != e.lookupID(t, a) {
+		t.Errorf("first proposal approved by %v, want %s", send.Approved, a)
+	}
+
+	add := infos[1]
+	params, ok := add.Params.(*multisig.AddSignerParams)
+	if add.Method != "AddSigner" || !ok || params.Signer != d || !params.Increase {
+		t.Errorf("second proposal %s %+v", add.Method, add.Params)
+	}
+	if add.Hash == "" || add.Hash == send.Hash {
+		t.Errorf("proposal hashes %q and %q", send.Hash, add.Hash)
+	}
+
+	// a proposal nobody approves any more, e.g. once its proposer is removed, has no hash but hides no other
+	msigAddr, _ := address.NewFromString(msig)
+	orphan := e.node.Pending(msigAddr)[0]
+	orphan.ID, orphan.Approved = 2, nil
+	e.node.SetPending(msigAddr, append(e.node.Pending(msigAddr), orphan))
+	if infos, err = e.h.GetPendingProposals(testCtx, msig); err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 3 || infos[0].Hash != send.Hash {
+		t.Fatalf("got %d proposals, want the 2 others kept", len(infos))
+	}
+	if last := infos[2]; last.Hash != "" || last.Note == "" || last.Method != "Send" {
+		t.Errorf("proposal without approval %+v", last)
+	}
+	if _, err = e.h.GetProposal(testCtx, Proposal{Msig: msig, TxnID: "2"}); err == nil {
+		t.Error("got a proposal without approval for review")
+	}
+}
+
+// proposeCall proposes a raw call through the Handler, whatever its params are.
+func (e *testEnv) proposeCall(t *testing.T, pk, msig string, params *multisig.ProposeParams) {
+	t.Helper()
+	from, err := e.h.sender(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	msigAddr, err := address.NewFromString(msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = e.h.propose(testCtx, from, msigAddr, params, 0); err != nil {
+		t.Fatal(err)
+	}
+}
+
+// TestPendingProposalsFallback checks that each proposal which can not be decoded keeps its params as hex with a
+// note, without hiding the others.
+func TestPendingProposalsFallback(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, a := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	_, to := newKey(t, types.KTSecp256k1)
+	mID, _ := e.newMiner(t, a, "0", "0")
+	msig := e.newMultisig(t, "2", pkA, pkB)
+
+	e.proposeCall(t, pkA, msig, &multisig.ProposeParams{To: to, Value: abi.NewTokenAmount(0), Method: 2,
+		Params: []byte{0xab}})
+	e.proposeCall(t, pkA, msig, &multisig.ProposeParams{To: mID, Value: abi.NewTokenAmount(0), Method: 99,
+		Params: []byte{0xcd}})
+	e.proposeCall(t, pkA, msig, &multisig.ProposeParams{To: mID, Value: abi.NewTokenAmount(0),
+		Method: builtin.MethodsMiner.ChangeWorkerAddress, Params: []byte{0xef}})
+	if err := e.h.Send(testCtx, pkA, a.String(), "1", &Proposal{Msig: msig}); err != nil {
+		t.Fatal(err)
+	}
+
+	infos, err := e.h.GetPendingProposals(testCtx, msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 4 {
+		t.Fatalf("%d proposals, want 4", len(infos))
+	}
+	for i, want := range []struct{ method, params, note string }{
+		{"2", "ab", "接收地址尚不存在"},
+		{"99", "cd", "未知的方法"},
+		{"ChangeWorkerAddress", "ef", "参数解码失败"},
+	} {
+		call := infos[i]
+		if call.Method != want.method || call.Params != want.params || !strings.HasPrefix(call.Note, want.note) {
+			t.Errorf("proposal %d: %s %v %q, want %s %s %q", i, call.Method, call.Params, call.Note, want.method,
+				want.params, want.note)
+		}
+	}
+	if send := infos[3]; send.Method != "Send" || send.Note != "" || !send.Value.Equals(mustFIL(t, "1")) {
+		t.Errorf("proposal 3: %s %s %q, want Send", send.Method, types.FIL(send.Value), send.Note)
+	}
+}
+
+// TestPendingProposalsDepth nests a proposal in more multisigs than maxCallDepth and checks where decoding stops.
+func TestPendingProposalsDepth(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, _ := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	_, to := newKey(t, types.KTSecp256k1)
+	msig := e.newMultisig(t, "2", pkA, pkB)
+
+	params := &multisig.ProposeParams{To: to, Value: abi.NewTokenAmount(0), Method: builtin.MethodSend}
+	for i := 0; i < maxCallDepth; i++ {
+		inner := e.newID(t)
+		e.node.SetActor(inner, builtin.MultisigActorCodeID)
+		enc, err := actors.SerializeParams(params)
+		if err != nil {
+			t.Fatal(err)
+		}
+		params = &multisig.ProposeParams{To: inner, Value: abi.NewTokenAmount(0),
+			Method: builtin.MethodsMultisig.Propose, Params: enc}
+	}
+	e.proposeCall(t, pkA, msig, params)
+
+	infos, err := e.h.GetPendingProposals(testCtx, msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(infos) != 1 {
+		t.Fatalf("%d proposals, want 1", len(infos))
+	}
+	if chain := infos[0].MethodChain(); chain != "Propose -> Propose -> Propose -> Propose" {
+		t.Fatalf("decoded %s, want %d calls", chain, maxCallDepth)
+	}
+	last := infos[0].Inner.Inner.Inner
+	if p, ok := last.Params.(*multisig.ProposeParams); !ok || p.To != to || last.Note != "" {
+		t.Errorf("deepest call %+v, want its params decoded but not followed", last)
+	}
+}
@@ -0,0 +1,313 @@
+package common
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fil-assistant/chain"
+	"fil-assistant/chain/mock"
+	"fil-assistant/lib"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/miner"
+	"testing"
+)
+
+var testCtx = context.Background()
+
+// testEnv is a Handler running against a mock node, accounts get ID addresses from 100 on.
+type testEnv struct {
+	node   *mock.Node
+	h      *Handler
+	nextID uint64
+}
+
+func newTestEnv(t *testing.T) *testEnv {
+	t.Helper()
+	node := mock.NewNode(nil)
+	h := NewHandler(node.Client(), abi.TokenAmount(mustFIL(t, "0.1")), types.NewInt(0), 0, nil, nil)
+	return &testEnv{node: node, h: h, nextID: 100}
+}
+
+// newKey returns a new private key the way the Handler takes it, a hex encoded KeyInfo, and its address.
+func newKey(t *testing.T, keyType types.KeyType) (string, address.Address) {
+	t.Helper()
+	signer := lib.ChooseSigner(keyType)
+	pk, err := signer.GenPriKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	addr, err := signer.ToAddress(pk)
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := json.Marshal(&types.KeyInfo{Type: keyType, PrivateKey: pk})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hex.EncodeToString(val), addr
+}
+
+// newAccount creates a secp256k1 account actor holding balance FIL.
+func (e *testEnv) newAccount(t *testing.T, balance string) (string, address.Address) {
+	t.Helper()
+	pk, addr := newKey(t, types.KTSecp256k1)
+	e.node.SetID(addr, e.newID(t))
+	e.node.SetBalance(addr, mustFIL(t, balance))
+	return pk, addr
+}
+
+func (e *testEnv) newID(t *testing.T) address.Address {
+	t.Helper()
+	id, err := address.NewIDAddress(e.nextID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.nextID++
+	return id
+}
+
+func (e *testEnv) lookupID(t *testing.T, addr address.Address) address.Address {
+	t.Helper()
+	id, err := e.node.Client().LookupID(testCtx, addr)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+// newMiner scripts a miner owned by owner, also its worker, holding balance FIL of which locked FIL are locked.
+func (e *testEnv) newMiner(t *testing.T, owner address.Address, balance, locked string) (address.Address,
+	*mock.MinerState) {
+	t.Helper()
+	id := e.newID(t)
+	st := &mock.MinerState{
+		Info: chain.MinerInfo{
+			Owner:             e.lookupID(t, owner),
+			Worker:            e.lookupID(t, owner),
+			WorkerChangeEpoch: -1,
+		},
+		LockedFunds: mustFIL(t, locked),
+	}
+	e.node.SetMiner(id, st)
+	e.node.SetBalance(id, mustFIL(t, balance))
+	return id, st
+}
+
+func mustFIL(t *testing.T, val string) types.BigInt {
+	t.Helper()
+	amount, err := types.ParseFIL(val)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return types.BigInt(amount)
+}
+
+func checkBalance(t *testing.T, e *testEnv, addr address.Address, want string) {
+	t.Helper()
+	if bal := e.node.Balance(addr); !bal.Equals(mustFIL(t, want)) {
+		t.Errorf("balance of %s is %s, want %s FIL", addr, types.FIL(bal), want)
+	}
+}
+
+func TestSend(t *testing.T) {
+	e := newTestEnv(t)
+	pk, from := e.newAccount(t, "10")
+	_, to := newKey(t, types.KTSecp256k1)
+
+	if err := e.h.Send(testCtx, pk, to.String(), "1.5", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, to, "1.5")
+	checkBalance(t, e, from, "8.5")
+
+	if err := e.h.Send(testCtx, pk, to.String(), "9", nil); err == nil {
+		t.Error("sent more than the balance and the max fee")
+	}
+	if pushed := len(e.node.Pushed()); pushed != 1 {
+		t.Errorf("%d messages pushed, want 1", pushed)
+	}
+}
+
+// TestSendOffline gives only the address of the sender, the message is prepared for offline signing.
+// TestSendOffline prepares a message, signs it without a Handler as on an offline machine and broadcasts it.
+func TestSendOffline(t *testing.T) {
+	e := newTestEnv(t)
+	pk, from := e.newAccount(t, "10")
+	_, to := newKey(t, types.KTSecp256k1)
+
+	err := e.h.Send(testCtx, from.String(), to.String(), "1", nil)
+	var prepared *PreparedError
+	if !errors.As(err, &prepared) {
+		t.Fatalf("got %v, want a prepared message", err)
+	}
+	if prepared.Message.From != from || prepared.Message.To != to || prepared.Message.GasLimit == 0 {
+		t.Errorf("prepared message %+v", prepared.Message)
+	}
+	if pushed := len(e.node.Pushed()); pushed != 0 {
+		t.Errorf("%d messages pushed, want none", pushed)
+	}
+
+	unsigned, err := json.Marshal(prepared.Message)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = SignMessage(nil, from.String(), unsigned); err == nil {
+		t.Fatal("signed without the private key")
+	}
+	other, _ := newKey(t, types.KTSecp256k1)
+	if _, err = SignMessage(nil, other, unsigned); err == nil {
+		t.Fatal("signed with the key of another address")
+	}
+	signed, err := SignMessage(nil, pk, unsigned)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, err = e.h.Broadcast(testCtx, signed); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, to, "1")
+}
+
+func TestWithdraw(t *testing.T) {
+	e := newTestEnv(t)
+	pk, owner := e.newAccount(t, "1")
+	mID, _ := e.newMiner(t, owner, "10", "4")
+
+	if err := e.h.Withdraw(testCtx, pk, mID.String(), "3", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, mID, "7")
+	checkBalance(t, e, owner, "4")
+
+	if err := e.h.Withdraw(testCtx, pk, mID.String(), "", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, mID, "4")
+	checkBalance(t, e, owner, "7")
+
+	if err := e.h.Withdraw(testCtx, pk, mID.String(), "1", nil); err == nil {
+		t.Error("withdrew locked funds")
+	}
+
+	otherPk, _ := e.newAccount(t, "1")
+	e.node.SetBalance(mID, mustFIL(t, "10"))
+	if err := e.h.Withdraw(testCtx, otherPk, mID.String(), "1", nil); err == nil {
+		t.Error("withdrew as another address than the owner")
+	}
+}
+
+func TestChangeOwner(t *testing.T) {
+	e := newTestEnv(t)
+	pk, owner := e.newAccount(t, "1")
+	newPk, newOwner := e.newAccount(t, "1")
+	mID, st := e.newMiner(t, owner, "0", "0")
+
+	if err := e.h.ChangeOwner2(testCtx, newPk, mID.String(), nil); err == nil {
+		t.Fatal("took over a miner without being nominated")
+	}
+
+	if err := e.h.ChangeOwner1(testCtx, pk, newOwner.String(), mID.String(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := e.lookupID(t, newOwner); st.PendingOwner != want || st.Info.Owner != e.lookupID(t, owner) {
+		t.Fatalf("owner %s pending %s, want %s pending", st.Info.Owner, st.PendingOwner, want)
+	}
+
+	if err := e.h.ChangeOwner2(testCtx, newPk, mID.String(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := e.lookupID(t, newOwner); st.Info.Owner != want || st.PendingOwner != address.Undef {
+		t.Fatalf("owner %s pending %s, want %s", st.Info.Owner, st.PendingOwner, want)
+	}
+}
+
+func TestChangeWorker(t *testing.T) {
+	e := newTestEnv(t)
+	pk, owner := e.newAccount(t, "1")
+	_, worker := e.newAccount(t, "0")
+	_, control := e.newAccount(t, "0")
+	mID, st := e.newMiner(t, owner, "0", "0")
+
+	err := e.h.ProposeChangeWorker(testCtx, pk, mID.String(), worker.String(), []string{control.String()}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(st.Info.ControlAddresses) != 1 || st.Info.ControlAddresses[0] != e.lookupID(t, control) {
+		t.Errorf("control addresses %v, want %s", st.Info.ControlAddresses, control)
+	}
+	if st.Info.NewWorker != e.lookupID(t, worker) || st.Info.WorkerChangeEpoch != miner.WorkerKeyChangeDelay {
+		t.Fatalf("new worker %s at %d, want %s at %d", st.Info.NewWorker, st.Info.WorkerChangeEpoch, worker,
+			miner.WorkerKeyChangeDelay)
+	}
+
+	if err = e.h.ConfirmChangeWorker(testCtx, pk, mID.String(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if st.Info.Worker != e.lookupID(t, owner) {
+		t.Fatalf("worker changed to %s before its epoch", st.Info.Worker)
+	}
+
+	e.node.SetHeight(st.Info.WorkerChangeEpoch)
+	if err = e.h.ConfirmChangeWorker(testCtx, pk, mID.String(), nil); err != nil {
+		t.Fatal(err)
+	}
+	if st.Info.Worker != e.lookupID(t, worker) || st.Info.NewWorker != address.Undef {
+		t.Fatalf("worker %s new worker %s, want %s", st.Info.Worker, st.Info.NewWorker, worker)
+	}
+}
+
+func TestEncryptDecrypt(t *testing.T) {
+	pk, addr := newKey(t, types.KTSecp256k1)
+	h := newTestEnv(t).h
+
+	encAddr, ks, err := h.Encrypt(pk, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if encAddr != addr.String() {
+		t.Errorf("encrypted key of %s, want %s", encAddr, addr)
+	}
+
+	decAddr, decPk, err := h.Decrypt(ks, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if decAddr != addr.String() || decPk != pk {
+		t.Errorf("decrypted %s %s, want %s %s", decAddr, decPk, addr, pk)
+	}
+
+	if _, _, err = h.Decrypt(ks, "other"); err == nil {
+		t.Error("decrypted with a wrong passphrase")
+	}
+}
+
+func TestSignVerify(t *testing.T) {
+	h := newTestEnv(t).h
+	msg := hex.EncodeToString([]byte("fil-assistant"))
+	_, other := newKey(t, types.KTSecp256k1)
+
+	for _, keyType := range []types.KeyType{types.KTSecp256k1, types.KTBLS} {
+		pk, addr := newKey(t, keyType)
+		sig, err := h.Sign(pk, msg)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err = h.Verify(addr.String(), msg, sig); err != nil {
+			t.Errorf("%s: %s", keyType, err)
+		}
+		if err = h.Verify(other.String(), msg, sig); err == nil {
+			t.Errorf("%s: signature verified for another address", keyType)
+		}
+		if err = h.Verify(addr.String(), hex.EncodeToString([]byte("other")), sig); err == nil {
+			t.Errorf("%s: signature verified for another message", keyType)
+		}
+	}
+
+	_, addr := newKey(t, types.KTSecp256k1)
+	if _, err := h.Sign(addr.String(), msg); err == nil {
+		t.Error("signed without a private key")
+	}
+}
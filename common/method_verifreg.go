@@ -0,0 +1,141 @@
+package common
+
+import (
+	"context"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/abi"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/actors"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/multisig"
+	"github.com/filecoin-project/specs-actors/v6/actors/builtin/verifreg"
+	"golang.org/x/xerrors"
+	"strconv"
+	"strings"
+)
+
+// DataCapInfo is what the verified registry holds for an address, DataCap when it is a verified client and
+// Allowance when it is a verifier.
+type DataCapInfo struct {
+	Address   string `json:"地址"`
+	Label     string `json:"标签,omitempty"`
+	DataCap   string `json:"剩余DataCap,omitempty"`
+	Allowance string `json:"公证人剩余额度,omitempty"`
+}
+
+// GetDataCap reads the DataCap left to a verified client and the allowance left to a verifier.
+func (m *Handler) GetDataCap(ctx context.Context, addr string) (*DataCapInfo, error) {
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return nil, err
+	}
+
+	m.process(1 / float64(3))
+	dcap, err := m.client.GetVerifiedClientStatus(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	m.process(2 / float64(3))
+	allowance, err := m.client.GetVerifierStatus(ctx, a)
+	if err != nil {
+		return nil, err
+	}
+
+	info := &DataCapInfo{Address: addr, Label: m.book.Label(addr)}
+	if dcap != nil {
+		info.DataCap = types.SizeStr(*dcap)
+	}
+	if allowance != nil {
+		info.Allowance = types.SizeStr(*allowance)
+	}
+	return info, nil
+}
+
+// AddVerifiedClient grants allowance DataCap, such as "32GiB" or a number of bytes, to a client. The verifier is the
+// sender or, with proposal, the multisig, its allowance is checked before sending.
+func (m *Handler) AddVerifiedClient(ctx context.Context, pk, client, allowance string, proposal *Proposal) error {
+	clientAddr, err := address.NewFromString(client)
+	if err != nil {
+		return err
+	}
+
+	dcap, err := parseDataCap(allowance)
+	if err != nil {
+		return err
+	}
+	if dcap.LessThan(verifreg.MinVerifiedDealSize) {
+		return xerrors.Errorf("allowance %s is less than the minimum %s", types.SizeStr(dcap),
+			types.SizeStr(verifreg.MinVerifiedDealSize))
+	}
+
+	from, err := m.sender(pk)
+	if err != nil {
+		return err
+	}
+
+	verifier := from.addr
+	if proposal != nil {
+		if verifier, err = address.NewFromString(proposal.Msig); err != nil {
+			return err
+		}
+	}
+	m.process(1 / float64(1 + 6))
+	left, err := m.client.GetVerifierStatus(ctx, verifier)
+	if err != nil {
+		return err
+	}
+	if left == nil {
+		return xerrors.Errorf("%s is not a verifier", verifier)
+	}
+	if left.LessThan(dcap) {
+		return xerrors.Errorf("verifier %s has %s allowance left, less than %s", verifier, types.SizeStr(*left),
+			types.SizeStr(dcap))
+	}
+
+	enc, err := actors.SerializeParams(&verifreg.AddVerifiedClientParams{
+		Address:   clientAddr,
+		Allowance: dcap,
+	})
+	if err != nil {
+		return err
+	}
+
+	if proposal == nil {
+		_, err = m.messagePush(ctx, &types.Message{
+			From:       from.addr,
+			To:         builtin.VerifiedRegistryActorAddr,
+			Value:      abi.NewTokenAmount(0),
+			Method:     builtin.MethodsVerifiedRegistry.AddVerifiedClient,
+			Params:     enc,
+		}, from, 1)
+		return err
+	} else {
+		proposal.TxnID, err = m.propose(ctx, from, verifier, &multisig.ProposeParams{
+			To: builtin.VerifiedRegistryActorAddr,
+			Value: abi.NewTokenAmount(0),
+			Method: builtin.MethodsVerifiedRegistry.AddVerifiedClient,
+			Params: enc,
+		}, 1)
+		return err
+	}
+}
+
+// parseDataCap parses a number of bytes with an optional binary unit, e.g. "1024" or "32GiB".
+func parseDataCap(val string) (abi.StoragePower, error) {
+	num := strings.TrimSpace(val)
+	shift := uint(0)
+	for i, unit := range []string{"KiB", "MiB", "GiB", "TiB", "PiB"} {
+		if strings.HasSuffix(strings.ToLower(num), strings.ToLower(unit)) {
+			num = strings.TrimSpace(num[:len(num)-len(unit)])
+			shift = uint(10 * (i + 1))
+			break
+		}
+	}
+	bytes, err := strconv.ParseUint(strings.TrimSpace(strings.TrimSuffix(num, "B")), 10, 64)
+	if err != nil {
+		return big.Zero(), xerrors.Errorf("invalid DataCap %s, expected bytes or e.g. 32GiB", val)
+	}
+	return big.Lsh(big.NewIntUnsigned(bytes), shift), nil
+}
@@ -0,0 +1,86 @@
+package common
+
+import (
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-state-types/big"
+	"github.com/filecoin-project/lotus/chain/types"
+	"testing"
+)
+
+func checkDataCap(t *testing.T, e *testEnv, addr address.Address, dcap, allowance string) {
+	t.Helper()
+	info, err := e.h.GetDataCap(testCtx, addr.String())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.DataCap != dcap || info.Allowance != allowance {
+		t.Errorf("%s has DataCap %q and allowance %q, want %q and %q", addr, info.DataCap, info.Allowance, dcap,
+			allowance)
+	}
+}
+
+// TestAddVerifiedClient grants DataCap from a verifier of 10 TiB, directly and through a multisig verifier.
+func TestAddVerifiedClient(t *testing.T) {
+	e := newTestEnv(t)
+	pkA, verifier := e.newAccount(t, "10")
+	pkB, _ := e.newAccount(t, "1")
+	otherPk, _ := e.newAccount(t, "1")
+	_, client := newKey(t, types.KTSecp256k1)
+	e.node.SetVerifier(verifier, types.BigInt(big.NewInt(10<<40)))
+
+	if err := e.h.AddVerifiedClient(testCtx, pkA, client.String(), "1TiB", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkDataCap(t, e, client, "1 TiB", "")
+	checkDataCap(t, e, verifier, "", "9 TiB")
+
+	pushed := len(e.node.Pushed())
+	for _, bad := range []struct{ pk, allowance string }{
+		{pkA, "512KiB"},
+		{pkA, "10TiB"},
+		{pkA, "nope"},
+		{otherPk, "1TiB"},
+	} {
+		if err := e.h.AddVerifiedClient(testCtx, bad.pk, client.String(), bad.allowance, nil); err == nil {
+			t.Errorf("granted %s", bad.allowance)
+		}
+	}
+	if n := len(e.node.Pushed()); n != pushed {
+		t.Errorf("%d messages pushed for grants the verifier can not make", n-pushed)
+	}
+	checkDataCap(t, e, client, "1 TiB", "")
+
+	msig := e.newMultisig(t, "2", pkA, pkB)
+	msigAddr, err := address.NewFromString(msig)
+	if err != nil {
+		t.Fatal(err)
+	}
+	e.node.SetVerifier(msigAddr, types.BigInt(big.NewInt(4<<40)))
+	proposal := &Proposal{Msig: msig}
+	if err = e.h.AddVerifiedClient(testCtx, pkA, client.String(), "3TiB", proposal); err != nil {
+		t.Fatal(err)
+	}
+	checkDataCap(t, e, client, "1 TiB", "")
+	e.approve(t, pkB, msig, proposal.TxnID)
+	checkDataCap(t, e, client, "4 TiB", "")
+	checkDataCap(t, e, msigAddr, "", "1 TiB")
+}
+
+func TestParseDataCap(t *testing.T) {
+	for val, want := range map[string]int64{
+		"1024":    1024,
+		"2048B":   2048,
+		"32GiB":   32 << 30,
+		" 1 tib ": 1 << 40,
+		"1PiB":    1 << 50,
+	} {
+		if dcap, err := parseDataCap(val); err != nil || !dcap.Equals(big.NewInt(want)) {
+			t.Errorf("%q: got %s %v, want %d", val, dcap, err, want)
+		}
+	}
+	for _, val := range []string{"", "GiB", "1.5GiB", "-1", "32GB"} {
+		if _, err := parseDataCap(val); err == nil {
+			t.Errorf("DataCap %q accepted", val)
+		}
+	}
+}
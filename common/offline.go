@@ -0,0 +1,99 @@
+package common
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"fil-assistant/lib"
+	"fil-assistant/utils"
+	"fmt"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+)
+
+// PreparedError is returned by an operation whose sender was given as a bare address. Message is fully estimated,
+// with nonce and gas, and has to be signed offline with SignMessage and pushed later with Broadcast.
+type PreparedError struct {
+	Message *types.Message
+}
+
+func (e *PreparedError) Error() string {
+	return fmt.Sprintf("message from %s with nonce %d is prepared for offline signing", e.Message.From, e.Message.Nonce)
+}
+
+// SignMessage signs a JSON encoded unsigned message and returns the JSON encoded signed message. It never talks
+// to the node, so it runs on an offline machine.
+func (m *Handler) SignMessage(pk string, unsigned []byte) ([]byte, error) {
+	return SignMessage(m.wallet, pk, unsigned)
+}
+
+// SignMessage is Handler.SignMessage without a Handler, pk being an address of w or a private key when w is nil.
+func SignMessage(w *lib.Wallet, pk string, unsigned []byte) ([]byte, error) {
+	msg := new(types.Message)
+	if err := json.Unmarshal(unsigned, msg); err != nil {
+		return nil, xerrors.Errorf("invalid unsigned message: %w", err)
+	}
+
+	from, err := walletSender(w, pk)
+	if err != nil {
+		return nil, err
+	} else if from.key == nil {
+		return nil, xerrors.Errorf("no private key for %s", from.addr)
+	}
+	if msg.From.Protocol() != address.ID && msg.From != from.addr {
+		return nil, xerrors.Errorf("message is sent from %s, but the private key belongs to %s", msg.From, from.addr)
+	}
+
+	signedMsg, err := lib.SignMessage(from.signer, from.key.PrivateKey, msg)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(signedMsg)
+}
+
+// Broadcast pushes a JSON encoded signed message and waits for it like any other operation. It returns the message
+// cid and the decoded return value, e.g. the TxnID of a proposal or the address of a new multisig.
+func (m *Handler) Broadcast(ctx context.Context, signed []byte) (string, interface{}, error) {
+	signedMsg := new(types.SignedMessage)
+	if err := json.Unmarshal(signed, signedMsg); err != nil {
+		return "", nil, xerrors.Errorf("invalid signed message: %w", err)
+	}
+
+	m.process(1 / float64(3))
+	c, err := m.push(ctx, signedMsg)
+	if err != nil {
+		return "", nil, err
+	}
+
+	m.process(2 / float64(3))
+	lookup, err := m.wait(ctx, c, &signedMsg.Message)
+	if err != nil {
+		return c.String(), nil, err
+	}
+
+	res, err := m.decodeReturn(ctx, &signedMsg.Message, lookup.Receipt.Return)
+	return c.String(), res, err
+}
+
+func (m *Handler) decodeReturn(ctx context.Context, msg *types.Message, ret []byte) (interface{}, error) {
+	if len(ret) == 0 {
+		return nil, nil
+	}
+
+	code, err := m.client.StateGetActorCode(ctx, msg.To)
+	if err != nil {
+		return nil, err
+	}
+
+	val, found := utils.LookupReturn(code, msg.Method)
+	if !found {
+		return hex.EncodeToString(ret), nil
+	}
+
+	if err = val.UnmarshalCBOR(bytes.NewReader(ret)); err != nil {
+		return nil, xerrors.Errorf("failed to unmarshal return value: %w", err)
+	}
+	return val, nil
+}
@@ -2,15 +2,18 @@ package common
 
 import (
 	"context"
-	"encoding/base64"
-	"fil-assistant/utils"
+	"encoding/json"
+	"errors"
+	"fil-assistant/lib"
 	"fmt"
 	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
 	"fyne.io/fyne/v2/data/binding"
 	"fyne.io/fyne/v2/dialog"
-	"github.com/filecoin-project/go-state-types/abi"
-	"github.com/filecoin-project/lotus/chain/types"
+	"fyne.io/fyne/v2/widget"
 	"github.com/subchen/go-trylock"
+	"os"
+	"strings"
 )
 
 const (
@@ -24,6 +27,8 @@ type UI struct {
 	Process 			binding.Float
 	Handler 			*Handler
 	Locker 				trylock.TryLocker
+	keys 				[]*KeyEntry
+	addrs 				[]*AddressEntry
 }
 
 func (u *UI) Msg(level int, text string) {
@@ -39,49 +44,211 @@ func (u *UI) Msg(level int, text string) {
 	}
 }
 
+// Fail reports the error of an operation. A message prepared for offline signing is not a failure, it is written
+// to a file for the offline machine.
+func (u *UI) Fail(err error) {
+	var prepared *PreparedError
+	if !errors.As(err, &prepared) {
+		u.Msg(Warn, err.Error())
+		return
+	}
+
+	val, err := json.MarshalIndent(prepared.Message, "", "\t")
+	if err != nil {
+		u.Msg(Warn, err.Error())
+		return
+	}
+	path := fmt.Sprintf("./待签名消息_%s_%d.json", prepared.Message.From, prepared.Message.Nonce)
+	if err = os.WriteFile(path, val, 0600); err != nil {
+		u.Msg(Warn, err.Error())
+	} else {
+		u.Msg(Info, fmt.Sprintf("%s 已生成, 请离线签名后广播", path))
+	}
+}
+
 func (u *UI) Init(w fyne.Window) {
 	u.Window = w
 	u.Locker = trylock.New()
 	u.Process = binding.NewFloat()
 
-	cfg, err := utils.ReadConfig("./config.toml")
+	var err error
+	u.Handler, err = LoadHandler(context.TODO(), DefaultConfigPath, u.Process.Set)
 	if err != nil {
-		u.Msg(Error, fmt.Sprintf("read config.toml error: %s", err))
-		return
+		u.Msg(Error, err.Error())
+	}
+}
+
+func (u *UI) Close() {
+	if u.Handler != nil {
+		u.Handler.Close()
 	}
+}
+
+const manualKey = "手动输入"
 
-	var aesKey []byte
-	if len(cfg.AESKey) != 0 {
-		aesKey, err = base64.StdEncoding.DecodeString(cfg.AESKey)
-		if err != nil {
-			u.Msg(Error, fmt.Sprintf("AES key %s decode error: %s", cfg.AESKey, err))
-			return
-		} else if len(aesKey) != 32 {
-			u.Msg(Error, fmt.Sprintf("AES key length %d is invalid", len(aesKey)))
-			return
+// KeyEntry lets the operator pick an address of the wallet or paste a private key, a mnemonic or an address.
+type KeyEntry struct {
+	*fyne.Container
+	selector 			*widget.Select
+	entry 				*widget.Entry
+	addrs 				map[string]string
+}
+
+func (u *UI) NewKeyEntry() *KeyEntry {
+	k := &KeyEntry{
+		entry: widget.NewPasswordEntry(),
+	}
+	k.entry.PlaceHolder = "私钥、助记词或发送地址"
+	k.selector = widget.NewSelect(nil, func(option string) {
+		if option == manualKey {
+			k.entry.Enable()
+		} else {
+			k.entry.Disable()
 		}
+	})
+	k.Container = container.NewGridWithColumns(2, k.selector, k.entry)
+
+	u.keys = append(u.keys, k)
+	k.refresh(u.walletEntries())
+	return k
+}
+
+// Key returns the selected wallet address, or the private key or address typed by the operator.
+func (k *KeyEntry) Key() string {
+	if addr, found := k.addrs[k.selector.Selected]; found {
+		return addr
 	}
+	return strings.TrimSpace(k.entry.Text)
+}
 
-	maxFee, err := types.ParseFIL(cfg.MaxFee)
-	if err != nil {
-		u.Msg(Error, err.Error())
-		return
+func (k *KeyEntry) refresh(entries []lib.WalletEntry) {
+	options := []string{manualKey}
+	k.addrs = make(map[string]string, len(entries))
+	for _, entry := range entries {
+		option := entry.Address
+		if entry.Label != "" {
+			option = fmt.Sprintf("%s (%s)", entry.Label, entry.Address)
+		}
+		options = append(options, option)
+		k.addrs[option] = entry.Address
 	}
-	gasFeeCap, err := types.BigFromString(cfg.GasFeeCap)
-	if err != nil {
-		u.Msg(Error, err.Error())
-		return
+
+	selected := k.selector.Selected
+	k.selector.Options = options
+	if _, found := k.addrs[selected]; found {
+		k.selector.Refresh()
+	} else {
+		k.selector.SetSelected(manualKey)
 	}
+}
 
-	u.Handler, err = newHandler(context.TODO(), cfg.EndPoint, cfg.ApiToken, abi.TokenAmount(maxFee), gasFeeCap,
-		cfg.Confidence, aesKey, u.Process.Set)
+// RefreshKeys updates the wallet addresses offered by every KeyEntry.
+func (u *UI) RefreshKeys() {
+	entries := u.walletEntries()
+	for _, k := range u.keys {
+		k.refresh(entries)
+	}
+}
+
+func (u *UI) walletEntries() []lib.WalletEntry {
+	if u.Handler == nil {
+		return nil
+	}
+	entries, err := u.Handler.ListWallet()
 	if err != nil {
-		u.Msg(Error, fmt.Sprintf("initialization failed: %s", err))
+		return nil
 	}
+	return entries
 }
 
-func (u *UI) Close() {
-	if u.Handler != nil {
-		u.Handler.Close()
+// AddressEntry completes the addresses of the address book. Typing filters the drop down by label or address,
+// picking an option fills in its address.
+type AddressEntry struct {
+	*widget.SelectEntry
+	entries 			[]AddressBookEntry
+	options 			map[string]string
+	picked 				func(addr string)
+}
+
+func (u *UI) NewAddressEntry(placeHolder string) *AddressEntry {
+	a := &AddressEntry{
+		SelectEntry: widget.NewSelectEntry(nil),
+	}
+	a.PlaceHolder = placeHolder
+	a.OnChanged = a.changed
+
+	u.addrs = append(u.addrs, a)
+	a.refresh(u.bookEntries())
+	return a
+}
+
+// NewAddressPicker returns an AddressEntry adding the picked addresses as lines of target, for the entries taking
+// one address per line.
+func (u *UI) NewAddressPicker(target *widget.Entry) *AddressEntry {
+	a := u.NewAddressEntry("从地址簿添加")
+	a.picked = func(addr string) {
+		text := strings.TrimRight(target.Text, "\n")
+		if text != "" {
+			text += "\n"
+		}
+		target.SetText(text + addr)
+		a.SetText("")
 	}
+	return a
 }
+
+func (a *AddressEntry) changed(text string) {
+	addr, found := a.options[text]
+	if !found {
+		a.filter(text)
+	} else if a.picked != nil {
+		a.picked(addr)
+	} else {
+		a.SetText(addr)
+	}
+}
+
+func (a *AddressEntry) filter(text string) {
+	text = strings.ToLower(strings.TrimSpace(text))
+	options := make([]string, 0, len(a.entries))
+	for _, entry := range a.entries {
+		option := addressOption(entry)
+		if strings.Contains(strings.ToLower(option), text) || strings.Contains(entry.Address, text) {
+			options = append(options, option)
+		}
+	}
+	a.SetOptions(options)
+}
+
+func (a *AddressEntry) refresh(entries []AddressBookEntry) {
+	a.entries = entries
+	a.options = make(map[string]string, len(entries))
+	for _, entry := range entries {
+		a.options[addressOption(entry)] = entry.Address
+	}
+	a.filter(a.Text)
+}
+
+// addressOption shortens long addresses, so that a f3 address does not widen the whole form.
+func addressOption(entry AddressBookEntry) string {
+	addr := entry.Address
+	if len(addr) > 24 {
+		addr = addr[:10] + "..." + addr[len(addr)-8:]
+	}
+	return fmt.Sprintf("%s (%s)", entry.Label, addr)
+}
+
+// RefreshAddresses updates the addresses offered by every AddressEntry.
+func (u *UI) RefreshAddresses() {
+	entries := u.bookEntries()
+	for _, a := range u.addrs {
+		a.refresh(entries)
+	}
+}
+
+func (u *UI) bookEntries() []AddressBookEntry {
+	if u.Handler == nil || u.Handler.AddressBook() == nil {
+		return nil
+	}
+	return u.Handler.AddressBook().List()
+}
\ No newline at end of file
@@ -0,0 +1,189 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"golang.org/x/xerrors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// AddressBookTab edits the address book shared by both assistants, whose labels the address entries complete.
+func (u *UI) AddressBookTab() fyne.CanvasObject {
+	labelEntry := widget.NewEntry()
+	labelEntry.PlaceHolder = "标签"
+
+	tagSelect := widget.NewSelect(append([]string{""}, AddressTags...), nil)
+	tagSelect.PlaceHolder = "类型"
+
+	noteEntry := widget.NewEntry()
+	noteEntry.PlaceHolder = "备注"
+
+	// typing an address of the book loads its entry for editing
+	addrEntry := u.NewAddressEntry("地址")
+	onChanged := addrEntry.OnChanged
+	addrEntry.OnChanged = func(text string) {
+		onChanged(text)
+		if u.Handler == nil || u.Handler.AddressBook() == nil {
+			return
+		}
+		if entry, found := u.Handler.AddressBook().Get(strings.TrimSpace(addrEntry.Text)); found {
+			labelEntry.SetText(entry.Label)
+			tagSelect.SetSelected(entry.Tag)
+			noteEntry.SetText(entry.Note)
+		}
+	}
+
+	filterEntry := widget.NewEntry()
+	filterEntry.PlaceHolder = "按地址/标签/类型/备注筛选"
+
+	list := widget.NewMultiLineEntry()
+	list.PlaceHolder = "地址簿"
+	listText := binding.NewString()
+	list.Bind(listText)
+
+	book := func() *AddressBook {
+		if u.Handler == nil {
+			return nil
+		}
+		return u.Handler.AddressBook()
+	}
+
+	filtered := func() []AddressBookEntry {
+		text := strings.ToLower(strings.TrimSpace(filterEntry.Text))
+		var res []AddressBookEntry
+		for _, entry := range book().List() {
+			fields := strings.ToLower(strings.Join([]string{entry.Address, entry.Label, entry.Tag, entry.Note}, " "))
+			if strings.Contains(fields, text) {
+				res = append(res, entry)
+			}
+		}
+		return res
+	}
+
+	reload := func() {
+		if book() == nil {
+			return
+		}
+		var buf bytes.Buffer
+		for _, entry := range filtered() {
+			fmt.Fprintf(&buf, "%s    %s    %s    %s\n", entry.Address, entry.Label, entry.Tag, entry.Note)
+		}
+		listText.Set(buf.String())
+		u.RefreshAddresses()
+	}
+	filterEntry.OnChanged = func(string) { reload() }
+
+	// run wraps the address book operations with the checks every button does.
+	run := func(op func(b *AddressBook) error) func() {
+		return func() {
+			if !u.Locker.TryLock(0) {
+				u.Msg(Warn, "请稍后再试")
+				return
+			}
+			defer u.Locker.Unlock()
+
+			if book() == nil {
+				u.Msg(Error, "初始化异常")
+				return
+			}
+
+			if err := op(book()); err != nil {
+				u.Msg(Warn, err.Error())
+			}
+		}
+	}
+
+	save := widget.NewButton("保存", run(func(b *AddressBook) error {
+		if addrEntry.Text == "" || labelEntry.Text == "" {
+			return xerrors.New("输入为空")
+		}
+		err := b.Set(AddressBookEntry{
+			Address: addrEntry.Text,
+			Label:   labelEntry.Text,
+			Tag:     tagSelect.Selected,
+			Note:    noteEntry.Text,
+		})
+		if err != nil {
+			return err
+		}
+		reload()
+		return nil
+	}))
+
+	remove := widget.NewButton("删除", run(func(b *AddressBook) error {
+		addr := strings.TrimSpace(addrEntry.Text)
+		if addr == "" {
+			return xerrors.New("输入为空")
+		}
+		dialog.ShowConfirm("删除", fmt.Sprintf("确认从地址簿删除 %s ?", u.Handler.Describe(addr)), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := b.Delete(addr); err != nil {
+				u.Msg(Warn, err.Error())
+			} else {
+				addrEntry.SetText("")
+				reload()
+			}
+		}, u.Window)
+		return nil
+	}))
+
+	importFile := widget.NewButton("导入", func() {
+		dialog.ShowFileOpen(func(reader fyne.URIReadCloser, err error) {
+			if err != nil || reader == nil {
+				return
+			}
+			defer reader.Close()
+			run(func(b *AddressBook) error {
+				read := ReadAddressCSV
+				if strings.EqualFold(filepath.Ext(reader.URI().Path()), ".json") {
+					read = ReadAddressJSON
+				}
+				entries, err := read(reader)
+				if err != nil {
+					return err
+				}
+				if err = b.Import(entries); err != nil {
+					return err
+				}
+				reload()
+				u.Msg(Info, fmt.Sprintf("已导入%d个地址", len(entries)))
+				return nil
+			})()
+		}, u.Window)
+	})
+
+	export := func(path string, write func(w io.Writer, entries []AddressBookEntry) error) func() {
+		return run(func(b *AddressBook) error {
+			file, err := os.Create(path)
+			if err != nil {
+				return err
+			}
+			defer file.Close()
+			entries := filtered()
+			if err = write(file, entries); err != nil {
+				return err
+			}
+			u.Msg(Info, fmt.Sprintf("%d个地址已导出到%s", len(entries), path))
+			return nil
+		})
+	}
+	exportCSV := widget.NewButton("导出CSV", export("./地址簿.csv", WriteAddressCSV))
+	exportJSON := widget.NewButton("导出JSON", export("./地址簿.json", WriteAddressJSON))
+
+	reload()
+
+	top := container.NewGridWithColumns(2, addrEntry, labelEntry)
+	mid := container.NewGridWithColumns(2, tagSelect, noteEntry)
+	actions := container.NewGridWithColumns(5, save, remove, importFile, exportCSV, exportJSON)
+	return container.NewVBox(top, mid, actions, filterEntry, list)
+}
@@ -0,0 +1,116 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+)
+
+// CallTab calls any method of a builtin actor with the params edited as JSON. With propose the call is proposed by
+// a multisig, otherwise it is sent directly.
+func (u *UI) CallTab(propose bool) fyne.CanvasObject {
+	pkEntry := u.NewKeyEntry()
+
+	msigEntry := u.NewAddressEntry("多签账号")
+
+	toEntry := u.NewAddressEntry("目标地址")
+
+	amountEntry := widget.NewEntry()
+	amountEntry.PlaceHolder = "金额(可选)"
+
+	paramsEntry := widget.NewMultiLineEntry()
+	paramsEntry.PlaceHolder = "参数(JSON)"
+
+	var methods []ActorMethod
+	methodSelect := widget.NewSelect(nil, func(name string) {
+		for _, method := range methods {
+			if method.Name == name {
+				paramsEntry.SetText(method.Params)
+			}
+		}
+	})
+	methodSelect.PlaceHolder = "方法"
+
+	load := widget.NewButton("加载方法", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if toEntry.Text == "" {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		res, err := u.Handler.ActorMethods(context.TODO(), strings.TrimSpace(toEntry.Text))
+		if err != nil {
+			u.Msg(Warn, err.Error())
+			return
+		}
+		methods = res
+		options := make([]string, 0, len(methods))
+		for _, method := range methods {
+			options = append(options, method.Name)
+		}
+		methodSelect.Options = options
+		methodSelect.ClearSelected()
+		paramsEntry.SetText("")
+	})
+
+	submit := widget.NewButton("提交", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if pkEntry.Key() == "" || (propose && msigEntry.Text == "") || toEntry.Text == "" || methodSelect.Selected == "" {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		u.Process.Set(0)
+
+		var proposal *Proposal
+		if propose {
+			proposal = &Proposal{Msig: strings.TrimSpace(msigEntry.Text)}
+		}
+		ret, err := u.Handler.Call(context.TODO(), pkEntry.Key(), strings.TrimSpace(toEntry.Text),
+			methodSelect.Selected, paramsEntry.Text, strings.TrimSpace(amountEntry.Text), proposal)
+		if err != nil {
+			u.Fail(err)
+			return
+		}
+		if proposal != nil {
+			u.Msg(Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
+		} else if ret != nil {
+			val, _ := json.MarshalIndent(ret, "", "\t")
+			u.Msg(Info, fmt.Sprintf("执行成功, 返回值: %s", val))
+		} else {
+			u.Msg(Info, "执行成功")
+		}
+		u.Process.Set(1)
+	})
+
+	target := container.NewGridWithColumns(3, toEntry, load, methodSelect)
+	if !propose {
+		return container.NewVBox(pkEntry, target, paramsEntry, container.NewGridWithColumns(2, amountEntry, submit))
+	}
+	bottom := container.NewGridWithColumns(3, msigEntry, amountEntry, submit)
+	return container.NewVBox(pkEntry, target, paramsEntry, bottom)
+}
@@ -0,0 +1,89 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/widget"
+	"io"
+	"os"
+	"strings"
+)
+
+// HistoryTab searches and exports the messages pushed by this assistant.
+func (u *UI) HistoryTab() fyne.CanvasObject {
+	textEntry := widget.NewEntry()
+	textEntry.PlaceHolder = "地址/CID/操作/提案号"
+
+	sinceEntry := widget.NewEntry()
+	sinceEntry.PlaceHolder = "开始日期 2006-01-02"
+
+	untilEntry := widget.NewEntry()
+	untilEntry.PlaceHolder = "结束日期 2006-01-02"
+
+	result := widget.NewMultiLineEntry()
+	result.PlaceHolder = "查询结果"
+	res := binding.NewString()
+	result.Bind(res)
+
+	search := func() ([]*HistoryRecord, bool) {
+		if u.Handler == nil || u.Handler.History() == nil {
+			u.Msg(Error, "初始化异常")
+			return nil, false
+		}
+
+		q, err := NewHistoryQuery(strings.TrimSpace(textEntry.Text), strings.TrimSpace(sinceEntry.Text),
+			strings.TrimSpace(untilEntry.Text))
+		if err != nil {
+			u.Msg(Warn, err.Error())
+			return nil, false
+		}
+		recs, err := u.Handler.History().Search(q)
+		if err != nil {
+			u.Msg(Warn, err.Error())
+			return nil, false
+		}
+		return recs, true
+	}
+
+	query := widget.NewButton("查询", func() {
+		recs, ok := search()
+		if !ok {
+			return
+		}
+		var buf bytes.Buffer
+		if err := WriteHistoryJSON(&buf, recs); err != nil {
+			u.Msg(Warn, err.Error())
+		} else {
+			res.Set(buf.String())
+		}
+	})
+
+	export := func(path string, write func(w io.Writer, recs []*HistoryRecord) error) func() {
+		return func() {
+			recs, ok := search()
+			if !ok {
+				return
+			}
+			file, err := os.Create(path)
+			if err != nil {
+				u.Msg(Warn, err.Error())
+				return
+			}
+			defer file.Close()
+			if err = write(file, recs); err != nil {
+				u.Msg(Warn, err.Error())
+			} else {
+				u.Msg(Info, fmt.Sprintf("%d条记录已导出到%s", len(recs), path))
+			}
+		}
+	}
+	exportCSV := widget.NewButton("导出CSV", export("./历史记录.csv", WriteHistoryCSV))
+	exportJSON := widget.NewButton("导出JSON", export("./历史记录.json", WriteHistoryJSON))
+
+	top := container.NewGridWithColumns(3, textEntry, sinceEntry, untilEntry)
+	buttons := container.NewGridWithColumns(3, query, exportCSV, exportJSON)
+	return container.NewVBox(top, buttons, result)
+}
@@ -0,0 +1,195 @@
+package common
+
+import (
+	"bytes"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/widget"
+	"github.com/filecoin-project/lotus/chain/types"
+	"io/ioutil"
+	"strconv"
+	"strings"
+)
+
+// keysFile is where KeyGenTab exports the generated keys.
+const keysFile = "./生成的密钥.json"
+
+// KeyGenTab generates a batch of keys, shows their addresses and exports them, encrypted when a passphrase is given,
+// optionally importing them into the wallet.
+func (u *UI) KeyGenTab() fyne.CanvasObject {
+	keyType := widget.NewSelect([]string{string(types.KTSecp256k1), string(types.KTBLS)}, nil)
+	keyType.SetSelected(string(types.KTSecp256k1))
+
+	countEntry := widget.NewEntry()
+	countEntry.PlaceHolder = "数量"
+	countEntry.SetText("1")
+
+	passEntry := widget.NewPasswordEntry()
+	passEntry.PlaceHolder = "加密密码(可选, 填写则导出加密后的keystore)"
+
+	labelEntry := widget.NewEntry()
+	labelEntry.PlaceHolder = "钱包标签(可选)"
+
+	toWallet := widget.NewCheck("导入钱包", nil)
+
+	result := widget.NewMultiLineEntry()
+	result.PlaceHolder = "生成的地址"
+	res := binding.NewString()
+	result.Bind(res)
+
+	var keys []*GeneratedKey
+
+	generate := widget.NewButton("生成", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		count, err := strconv.Atoi(strings.TrimSpace(countEntry.Text))
+		if err != nil {
+			u.Msg(Warn, "数量无效")
+			return
+		}
+
+		u.Process.Set(0)
+
+		keys, err = u.Handler.GenerateKeys(keyType.Selected, count, passEntry.Text, toWallet.Checked,
+			strings.TrimSpace(labelEntry.Text))
+		if err != nil {
+			keys = nil
+			u.Fail(err)
+			return
+		}
+		addrs := make([]string, 0, len(keys))
+		for _, key := range keys {
+			addrs = append(addrs, key.Address)
+		}
+		res.Set(strings.Join(addrs, "\n"))
+		u.Msg(Info, fmt.Sprintf("已生成%d个地址, 请导出备份私钥", len(keys)))
+		u.Process.Set(1)
+	})
+
+	export := widget.NewButton("导出", func() {
+		if len(keys) == 0 {
+			u.Msg(Warn, "请先生成")
+			return
+		}
+
+		buf := new(bytes.Buffer)
+		if err := WriteKeysJSON(buf, keys); err != nil {
+			u.Fail(err)
+			return
+		}
+		if err := ioutil.WriteFile(keysFile, buf.Bytes(), 0600); err != nil {
+			u.Fail(err)
+			return
+		}
+		u.Msg(Info, fmt.Sprintf("已导出到 %s", keysFile))
+	})
+
+	options := container.NewGridWithColumns(4, keyType, countEntry, labelEntry, toWallet)
+	actions := container.NewGridWithColumns(2, generate, export)
+	return container.NewBorder(container.NewVBox(options, passEntry, actions), nil, nil, nil, result)
+}
+
+// MnemonicTab generates or takes a BIP39 phrase and shows the f1 addresses derived from it along the Filecoin path,
+// optionally importing their keys into the wallet.
+func (u *UI) MnemonicTab() fyne.CanvasObject {
+	words := widget.NewSelect([]string{"12", "24"}, nil)
+	words.SetSelected("24")
+
+	mnemonicEntry := widget.NewMultiLineEntry()
+	mnemonicEntry.PlaceHolder = "助记词, 生成或输入已有的助记词"
+	mnemonicEntry.Wrapping = fyne.TextWrapWord
+
+	startEntry := widget.NewEntry()
+	startEntry.PlaceHolder = "起始序号"
+	startEntry.SetText("0")
+
+	countEntry := widget.NewEntry()
+	countEntry.PlaceHolder = "数量"
+	countEntry.SetText("1")
+
+	labelEntry := widget.NewEntry()
+	labelEntry.PlaceHolder = "钱包标签(可选)"
+
+	toWallet := widget.NewCheck("导入钱包", nil)
+
+	result := widget.NewMultiLineEntry()
+	result.PlaceHolder = "派生的地址"
+	res := binding.NewString()
+	result.Bind(res)
+
+	generate := widget.NewButton("生成助记词", func() {
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		count, _ := strconv.Atoi(words.Selected)
+		mnemonic, err := u.Handler.GenerateMnemonic(count)
+		if err != nil {
+			u.Fail(err)
+			return
+		}
+		mnemonicEntry.SetText(mnemonic)
+		res.Set("")
+		u.Msg(Info, "请抄写并妥善保管助记词")
+	})
+
+	derive := widget.NewButton("派生地址", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if strings.TrimSpace(mnemonicEntry.Text) == "" {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+		start, err := strconv.Atoi(strings.TrimSpace(startEntry.Text))
+		if err != nil {
+			u.Msg(Warn, "起始序号无效")
+			return
+		}
+		count, err := strconv.Atoi(strings.TrimSpace(countEntry.Text))
+		if err != nil {
+			u.Msg(Warn, "数量无效")
+			return
+		}
+
+		u.Process.Set(0)
+
+		keys, err := u.Handler.DeriveKeys(mnemonicEntry.Text, start, count, toWallet.Checked,
+			strings.TrimSpace(labelEntry.Text))
+		if err != nil {
+			u.Fail(err)
+			return
+		}
+		rows := make([]string, 0, len(keys))
+		for _, key := range keys {
+			rows = append(rows, fmt.Sprintf("%s    %s", key.Path, key.Address))
+		}
+		res.Set(strings.Join(rows, "\n"))
+		u.Msg(Info, fmt.Sprintf("已派生%d个地址, 私钥处填写\"助记词 路径\"即可使用", len(keys)))
+		u.Process.Set(1)
+	})
+
+	options := container.NewGridWithColumns(4, startEntry, countEntry, labelEntry, toWallet)
+	actions := container.NewGridWithColumns(3, words, generate, derive)
+	return container.NewBorder(container.NewVBox(mnemonicEntry, options, actions), nil, nil, nil, result)
+}
@@ -0,0 +1,127 @@
+package common
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+)
+
+// MarketTab shows the storage market escrow of a provider or client and adds or withdraws collateral. With propose
+// the deposit is paid and the withdrawal sent by a multisig.
+func (u *UI) MarketTab(propose bool) fyne.CanvasObject {
+	pkEntry := u.NewKeyEntry()
+
+	addrEntry := u.NewAddressEntry("矿工号/客户地址")
+
+	msigEntry := u.NewAddressEntry("多签账号")
+
+	amountEntry := widget.NewEntry()
+	amountEntry.PlaceHolder = "金额"
+
+	all := u.NewWithdrawAllCheck(amountEntry)
+
+	result := widget.NewMultiLineEntry()
+	result.PlaceHolder = "托管余额"
+	res := binding.NewString()
+	result.Bind(res)
+
+	query := widget.NewButton("查询余额", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if addrEntry.Text == "" {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		u.Process.Set(0)
+
+		info, err := u.Handler.GetMarketBalance(context.TODO(), strings.TrimSpace(addrEntry.Text))
+		if err != nil {
+			u.Fail(err)
+			return
+		}
+		val, err := json.MarshalIndent(info, "", "\t")
+		if err != nil {
+			u.Fail(err)
+			return
+		}
+		res.Set(string(val))
+		u.Process.Set(1)
+	})
+
+	// submit checks the common inputs and runs send, reporting the result
+	submit := func(needAmount bool, success string, send func(proposal *Proposal) error) {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if pkEntry.Key() == "" || addrEntry.Text == "" || (propose && msigEntry.Text == "") ||
+			(needAmount && amountEntry.Text == "") {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		u.Process.Set(0)
+
+		var proposal *Proposal
+		if propose {
+			proposal = &Proposal{Msig: strings.TrimSpace(msigEntry.Text)}
+		}
+		if err := send(proposal); err != nil {
+			u.Fail(err)
+			return
+		}
+		if proposal != nil {
+			u.Msg(Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
+		} else {
+			u.Msg(Info, success)
+		}
+		u.Process.Set(1)
+	}
+
+	add := widget.NewButton("充值", func() {
+		submit(true, "充值成功", func(proposal *Proposal) error {
+			return u.Handler.MarketAddBalance(context.TODO(), pkEntry.Key(), strings.TrimSpace(addrEntry.Text),
+				strings.TrimSpace(amountEntry.Text), proposal)
+		})
+	})
+
+	withdraw := widget.NewButton("提现", func() {
+		submit(!all.Checked, "提现成功", func(proposal *Proposal) error {
+			amount := strings.TrimSpace(amountEntry.Text)
+			if all.Checked {
+				amount = ""
+			}
+			return u.Handler.MarketWithdraw(context.TODO(), pkEntry.Key(), strings.TrimSpace(addrEntry.Text), amount,
+				proposal)
+		})
+	})
+
+	target := container.NewGridWithColumns(2, addrEntry, query)
+	if propose {
+		target = container.NewGridWithColumns(3, msigEntry, addrEntry, query)
+	}
+	actions := container.NewGridWithColumns(4, amountEntry, all, add, withdraw)
+	return container.NewBorder(container.NewVBox(pkEntry, target, actions), nil, nil, nil, result)
+}
@@ -0,0 +1,261 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/widget"
+	"github.com/filecoin-project/lotus/chain/types"
+	"strings"
+)
+
+// PeerInfoTab changes the peer ID or the multiaddrs of a miner. With propose the change is proposed by the multisig
+// owning the miner, otherwise it is sent by the owner, worker or a control address.
+func (u *UI) PeerInfoTab(propose bool) fyne.CanvasObject {
+	pkEntry := u.NewKeyEntry()
+
+	minerEntry := u.NewAddressEntry("矿工号")
+
+	msigEntry := u.NewAddressEntry("多签账号")
+
+	peerEntry := widget.NewEntry()
+	peerEntry.PlaceHolder = "节点ID, 如12D3KooW..."
+
+	addrsEntry := widget.NewMultiLineEntry()
+	addrsEntry.PlaceHolder = "节点地址, 每行一个, 如/ip4/1.2.3.4/tcp/24001"
+
+	// submit checks the common inputs and runs change, reporting the result
+	submit := func(value string, change func(proposal *Proposal) error) {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if pkEntry.Key() == "" || minerEntry.Text == "" || (propose && msigEntry.Text == "") ||
+			strings.TrimSpace(value) == "" {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		u.Process.Set(0)
+
+		var proposal *Proposal
+		if propose {
+			proposal = &Proposal{Msig: strings.TrimSpace(msigEntry.Text)}
+		}
+		if err := change(proposal); err != nil {
+			u.Fail(err)
+			return
+		}
+		if proposal != nil {
+			u.Msg(Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
+		} else {
+			u.Msg(Info, "更换成功")
+		}
+		u.Process.Set(1)
+	}
+
+	changePeer := widget.NewButton("更换节点ID", func() {
+		submit(peerEntry.Text, func(proposal *Proposal) error {
+			return u.Handler.ChangePeerID(context.TODO(), pkEntry.Key(), strings.TrimSpace(minerEntry.Text),
+				strings.TrimSpace(peerEntry.Text), proposal)
+		})
+	})
+
+	changeAddrs := widget.NewButton("更换节点地址", func() {
+		submit(addrsEntry.Text, func(proposal *Proposal) error {
+			return u.Handler.ChangeMultiaddrs(context.TODO(), pkEntry.Key(), strings.TrimSpace(minerEntry.Text),
+				lines(addrsEntry.Text), proposal)
+		})
+	})
+
+	target := container.NewGridWithColumns(1, minerEntry)
+	if propose {
+		target = container.NewGridWithColumns(2, msigEntry, minerEntry)
+	}
+	peer := container.NewGridWithColumns(2, peerEntry, changePeer)
+	return container.NewVBox(pkEntry, target, peer, addrsEntry, changeAddrs)
+}
+
+// CreateMinerTab creates a miner through the power actor, sent by the owner key or, with propose, proposed by the
+// multisig that becomes its owner.
+func (u *UI) CreateMinerTab(propose bool) fyne.CanvasObject {
+	pkEntry := u.NewKeyEntry()
+
+	msigEntry := u.NewAddressEntry("多签账号")
+
+	ownerEntry := u.NewAddressEntry("owner地址(可选, 默认发送方或多签)")
+
+	workerEntry := u.NewAddressEntry("worker地址(可选, 默认发送方)")
+
+	sizeSelect := widget.NewSelect([]string{"2KiB", "8MiB", "512MiB", "32GiB", "64GiB"}, nil)
+	sizeSelect.SetSelected("32GiB")
+
+	peerEntry := widget.NewEntry()
+	peerEntry.PlaceHolder = "节点ID(可选), 如12D3KooW..."
+
+	addrsEntry := widget.NewMultiLineEntry()
+	addrsEntry.PlaceHolder = "节点地址(可选), 每行一个, 如/ip4/1.2.3.4/tcp/24001"
+
+	submit := widget.NewButton("创建矿工", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if pkEntry.Key() == "" || (propose && msigEntry.Text == "") {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		u.Process.Set(0)
+
+		var proposal *Proposal
+		if propose {
+			proposal = &Proposal{Msig: strings.TrimSpace(msigEntry.Text)}
+		}
+		id, robust, err := u.Handler.CreateMiner(context.TODO(), pkEntry.Key(), strings.TrimSpace(ownerEntry.Text),
+			strings.TrimSpace(workerEntry.Text), sizeSelect.Selected, strings.TrimSpace(peerEntry.Text),
+			lines(addrsEntry.Text), proposal)
+		if err != nil {
+			u.Fail(err)
+			return
+		}
+		if proposal != nil {
+			u.Msg(Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
+		} else {
+			u.Msg(Info, fmt.Sprintf("矿工已创建: %s, robust地址: %s", id, robust))
+		}
+		u.Process.Set(1)
+	})
+
+	addresses := container.NewGridWithColumns(2, ownerEntry, workerEntry)
+	if propose {
+		addresses = container.NewGridWithColumns(3, msigEntry, ownerEntry, workerEntry)
+	}
+	peer := container.NewGridWithColumns(2, sizeSelect, peerEntry)
+	return container.NewVBox(pkEntry, addresses, peer, addrsEntry, submit)
+}
+
+// lines returns the non-empty trimmed lines of text.
+func lines(text string) []string {
+	var res []string
+	for _, line := range strings.Split(text, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			res = append(res, line)
+		}
+	}
+	return res
+}
+
+// NewWithdrawAllCheck returns a check withdrawing all of the available balance, the amount is disabled while it is
+// checked.
+func (u *UI) NewWithdrawAllCheck(amount *widget.Entry) *widget.Check {
+	return widget.NewCheck("全部提现", func(checked bool) {
+		if checked {
+			amount.Disable()
+		} else {
+			amount.Enable()
+		}
+	})
+}
+
+// NewPreflightButton checks whether the withdrawal from the miner can be made, and tells why not.
+func (u *UI) NewPreflightButton(minerEntry *AddressEntry, amount *widget.Entry, all *widget.Check) *widget.Button {
+	return widget.NewButton("检查", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if minerEntry.Text == "" || (amount.Text == "" && !all.Checked) {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		value := strings.TrimSpace(amount.Text)
+		if all.Checked {
+			value = ""
+		}
+		amnt, err := u.Handler.WithdrawPreflight(context.TODO(), strings.TrimSpace(minerEntry.Text), value)
+		if err != nil {
+			u.Msg(Warn, err.Error())
+		} else {
+			u.Msg(Info, fmt.Sprintf("可提现 %s", types.FIL(amnt)))
+		}
+	})
+}
+
+// RepayDebtTab repays the fee debt of a miner, sent by its owner, worker or a control address, or proposed by the
+// multisig owning it with propose. Without an amount, what the unlocked balance of the miner lacks is sent.
+func (u *UI) RepayDebtTab(propose bool) fyne.CanvasObject {
+	pkEntry := u.NewKeyEntry()
+
+	minerEntry := u.NewAddressEntry("矿工号")
+
+	msigEntry := u.NewAddressEntry("多签账号")
+
+	amountEntry := widget.NewEntry()
+	amountEntry.PlaceHolder = "金额(可选)"
+
+	submit := widget.NewButton("还款", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if pkEntry.Key() == "" || minerEntry.Text == "" || (propose && msigEntry.Text == "") {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		u.Process.Set(0)
+
+		var proposal *Proposal
+		if propose {
+			proposal = &Proposal{Msig: strings.TrimSpace(msigEntry.Text)}
+		}
+		err := u.Handler.RepayDebt(context.TODO(), pkEntry.Key(), strings.TrimSpace(minerEntry.Text),
+			strings.TrimSpace(amountEntry.Text), proposal)
+		if err != nil {
+			u.Fail(err)
+			return
+		}
+		if proposal != nil {
+			u.Msg(Info, fmt.Sprintf("提案号已生成: %s", proposal.TxnID))
+		} else {
+			u.Msg(Info, "还款成功")
+		}
+		u.Process.Set(1)
+	})
+
+	if propose {
+		return container.NewVBox(pkEntry, container.NewGridWithColumns(3, msigEntry, minerEntry, amountEntry), submit)
+	}
+	return container.NewVBox(pkEntry, container.NewGridWithColumns(2, minerEntry, amountEntry), submit)
+}
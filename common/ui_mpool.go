@@ -0,0 +1,67 @@
+package common
+
+import (
+	"context"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"strings"
+)
+
+// MpoolTab speeds up or cancels a message stuck in the mpool, shared by both assistants.
+func (u *UI) MpoolTab() fyne.CanvasObject {
+	pkEntry := u.NewKeyEntry()
+
+	targetEntry := widget.NewEntry()
+	targetEntry.PlaceHolder = "消息CID或nonce"
+
+	feeCapEntry := widget.NewEntry()
+	feeCapEntry.PlaceHolder = "GasFeeCap(attoFIL, 可选)"
+
+	// run does the checks every button does, then replaces the message with op.
+	run := func(name string, op func(h *Handler, ctx context.Context, pk, target, feeCap string) (string, error)) {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if pkEntry.Key() == "" || targetEntry.Text == "" {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		u.Process.Set(0)
+
+		c, err := op(u.Handler, context.TODO(), pkEntry.Key(), strings.TrimSpace(targetEntry.Text),
+			strings.TrimSpace(feeCapEntry.Text))
+		if err != nil {
+			u.Fail(err)
+		} else {
+			u.Msg(Info, fmt.Sprintf("%s成功, 新消息: %s", name, c))
+			u.Process.Set(1)
+		}
+	}
+
+	replace := widget.NewButton("加速", func() {
+		run("加速", (*Handler).Replace)
+	})
+
+	cancel := widget.NewButton("取消消息", func() {
+		dialog.ShowConfirm("取消消息", "确认用一笔0金额的自转账替换该消息?", func(ok bool) {
+			if ok {
+				run("取消", (*Handler).Cancel)
+			}
+		}, u.Window)
+	})
+
+	bottom := container.NewGridWithColumns(3, feeCapEntry, replace, cancel)
+	return container.NewVBox(pkEntry, targetEntry, bottom)
+}
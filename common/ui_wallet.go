@@ -0,0 +1,198 @@
+package common
+
+import (
+	"fil-assistant/lib"
+	"fmt"
+	"fyne.io/fyne/v2"
+	"fyne.io/fyne/v2/container"
+	"fyne.io/fyne/v2/data/binding"
+	"fyne.io/fyne/v2/dialog"
+	"fyne.io/fyne/v2/widget"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+	"strings"
+)
+
+// WalletTab manages the local wallet shared by both assistants.
+func (u *UI) WalletTab() fyne.CanvasObject {
+	var entries []lib.WalletEntry
+	selected := -1
+
+	// the wallet stays small, a radio group shows every address without scrolling
+	list := widget.NewRadioGroup(nil, nil)
+	list.OnChanged = func(option string) {
+		selected = -1
+		for i, entry := range entries {
+			if option == walletOption(entry) {
+				selected = i
+			}
+		}
+	}
+
+	reload := func() {
+		entries = u.walletEntries()
+		options := make([]string, 0, len(entries))
+		for _, entry := range entries {
+			options = append(options, walletOption(entry))
+		}
+		list.Options = options
+		list.SetSelected("")
+		list.Refresh()
+		u.RefreshKeys()
+	}
+
+	state := binding.NewString()
+	status := widget.NewLabelWithData(state)
+	setState := func() {
+		if u.Handler != nil && !u.Handler.WalletLocked() {
+			state.Set("钱包已解锁")
+		} else {
+			state.Set("钱包已锁定")
+		}
+	}
+
+	passEntry := widget.NewPasswordEntry()
+	passEntry.PlaceHolder = "钱包密码"
+
+	unlock := widget.NewButton("解锁", func() {
+		if !u.Locker.TryLock(0) {
+			u.Msg(Warn, "请稍后再试")
+			return
+		}
+		defer u.Locker.Unlock()
+
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		if passEntry.Text == "" {
+			u.Msg(Warn, "输入为空")
+			return
+		}
+
+		if err := u.Handler.UnlockWallet(passEntry.Text); err != nil {
+			u.Msg(Warn, err.Error())
+		} else {
+			passEntry.SetText("")
+			setState()
+			reload()
+		}
+	})
+
+	lock := widget.NewButton("锁定", func() {
+		if u.Handler == nil {
+			u.Msg(Error, "初始化异常")
+			return
+		}
+
+		u.Handler.LockWallet()
+		setState()
+	})
+
+	pkEntry := widget.NewPasswordEntry()
+	pkEntry.PlaceHolder = "私钥"
+
+	labelEntry := widget.NewEntry()
+	labelEntry.PlaceHolder = "标签"
+
+	keyType := widget.NewSelect([]string{string(types.KTSecp256k1), string(types.KTBLS)}, nil)
+	keyType.SetSelected(string(types.KTSecp256k1))
+
+	exported := widget.NewEntry()
+	exported.PlaceHolder = "导出的私钥"
+	exportedKey := binding.NewString()
+	exported.Bind(exportedKey)
+
+	// run wraps the wallet operations with the checks every button does.
+	run := func(needSelected bool, op func() error) func() {
+		return func() {
+			if !u.Locker.TryLock(0) {
+				u.Msg(Warn, "请稍后再试")
+				return
+			}
+			defer u.Locker.Unlock()
+
+			if u.Handler == nil {
+				u.Msg(Error, "初始化异常")
+				return
+			}
+
+			if needSelected && selected < 0 {
+				u.Msg(Warn, "请先选择地址")
+				return
+			}
+
+			if err := op(); err != nil {
+				u.Msg(Warn, err.Error())
+			}
+		}
+	}
+
+	importKey := widget.NewButton("导入", run(false, func() error {
+		if pkEntry.Text == "" {
+			return xerrors.New("输入为空")
+		}
+		addr, err := u.Handler.ImportKey(strings.TrimSpace(pkEntry.Text), strings.TrimSpace(labelEntry.Text))
+		if err != nil {
+			return err
+		}
+		pkEntry.SetText("")
+		reload()
+		u.Msg(Info, fmt.Sprintf("已导入: %s", addr))
+		return nil
+	}))
+
+	generate := widget.NewButton("生成", run(false, func() error {
+		addr, err := u.Handler.GenerateKey(keyType.Selected, strings.TrimSpace(labelEntry.Text))
+		if err != nil {
+			return err
+		}
+		reload()
+		u.Msg(Info, fmt.Sprintf("已生成: %s", addr))
+		return nil
+	}))
+
+	label := widget.NewButton("设置标签", run(true, func() error {
+		if err := u.Handler.LabelKey(entries[selected].Address, strings.TrimSpace(labelEntry.Text)); err != nil {
+			return err
+		}
+		reload()
+		return nil
+	}))
+
+	export := widget.NewButton("导出", run(true, func() error {
+		pk, err := u.Handler.ExportKey(entries[selected].Address)
+		if err != nil {
+			return err
+		}
+		return exportedKey.Set(pk)
+	}))
+
+	remove := widget.NewButton("删除", run(true, func() error {
+		addr := entries[selected].Address
+		dialog.ShowConfirm("删除", fmt.Sprintf("确认从钱包删除 %s ? 请先确认私钥已备份", u.Handler.Describe(addr)), func(ok bool) {
+			if !ok {
+				return
+			}
+			if err := u.Handler.DeleteKey(addr); err != nil {
+				u.Msg(Warn, err.Error())
+			} else {
+				reload()
+			}
+		}, u.Window)
+		return nil
+	}))
+
+	setState()
+	reload()
+
+	top := container.NewGridWithColumns(4, passEntry, unlock, lock, status)
+	mid := container.NewGridWithColumns(2, pkEntry, labelEntry)
+	actions := container.NewGridWithColumns(6, keyType, generate, importKey, label, export, remove)
+	return container.NewVBox(top, mid, actions, exported, list)
+}
+
+func walletOption(entry lib.WalletEntry) string {
+	return fmt.Sprintf("%s    %s    %s", entry.Address, entry.Type, entry.Label)
+}
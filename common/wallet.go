@@ -0,0 +1,295 @@
+package common
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fil-assistant/lib"
+	"fmt"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+	"io"
+)
+
+var errNoWallet = xerrors.New("handler has no wallet")
+
+// maxGeneratedKeys bounds the keys GenerateKeys makes at once.
+const maxGeneratedKeys = 1000
+
+// SetWallet lets the operations look up the keys of wallet addresses, a nil wallet disables the lookup.
+func (m *Handler) SetWallet(w *lib.Wallet) {
+	m.wallet = w
+}
+
+func (m *Handler) UnlockWallet(passphrase string) error {
+	if m.wallet == nil {
+		return errNoWallet
+	}
+	return m.wallet.Unlock(passphrase)
+}
+
+func (m *Handler) LockWallet() {
+	if m.wallet != nil {
+		m.wallet.Lock()
+	}
+}
+
+func (m *Handler) WalletLocked() bool {
+	return m.wallet == nil || m.wallet.Locked()
+}
+
+func (m *Handler) ListWallet() ([]lib.WalletEntry, error) {
+	if m.wallet == nil {
+		return nil, nil
+	}
+	return m.wallet.List()
+}
+
+func (m *Handler) ImportKey(pk, label string) (string, error) {
+	if m.wallet == nil {
+		return "", errNoWallet
+	}
+
+	pki, err := parsePrivateKey(pk)
+	if err != nil {
+		return "", err
+	}
+
+	addr, err := m.wallet.Import(pki, label)
+	if err != nil {
+		return "", err
+	} else {
+		return addr.String(), nil
+	}
+}
+
+func (m *Handler) GenerateKey(keyType, label string) (string, error) {
+	if m.wallet == nil {
+		return "", errNoWallet
+	}
+
+	t, err := parseKeyType(keyType)
+	if err != nil {
+		return "", err
+	}
+
+	addr, err := m.wallet.Generate(t, label)
+	if err != nil {
+		return "", err
+	} else {
+		return addr.String(), nil
+	}
+}
+
+// GeneratedKey is a key made by GenerateKeys. Key is the hex encoded KeyInfo the operations accept, Keystore the
+// same key encrypted like Encrypt does.
+type GeneratedKey struct {
+	Address  string `json:"地址"`
+	Type     string `json:"类型"`
+	Key      string `json:"私钥,omitempty"`
+	Keystore string `json:"keystore,omitempty"`
+	InWallet bool   `json:"已导入钱包"`
+}
+
+// GenerateKeys makes count keys of keyType without a wallet. With passphrase the keys are returned encrypted, with
+// toWallet they are also imported into the unlocked wallet, labeled label-1, label-2... when label is set.
+func (m *Handler) GenerateKeys(keyType string, count int, passphrase string, toWallet bool,
+	label string) ([]*GeneratedKey, error) {
+	return GenerateKeys(m.wallet, keyType, count, passphrase, toWallet, label, m.process)
+}
+
+// GenerateKeys is Handler.GenerateKeys without a Handler, importing into w. process may be nil.
+func GenerateKeys(w *lib.Wallet, keyType string, count int, passphrase string, toWallet bool, label string,
+	process func(float64) error) ([]*GeneratedKey, error) {
+	t, err := parseKeyType(keyType)
+	if err != nil {
+		return nil, err
+	}
+	if count < 1 || count > maxGeneratedKeys {
+		return nil, xerrors.Errorf("key count must be between 1 and %d", maxGeneratedKeys)
+	}
+	if toWallet && w == nil {
+		return nil, errNoWallet
+	} else if toWallet && w.Locked() {
+		return nil, xerrors.New("wallet is locked")
+	}
+	if process == nil {
+		process = func(float64) error { return nil }
+	}
+
+	signer := lib.ChooseSigner(t)
+	keys := make([]*GeneratedKey, 0, count)
+	for i := 0; i < count; i++ {
+		process(float64(i) / float64(count))
+		pk, err := signer.GenPriKey()
+		if err != nil {
+			return keys, err
+		}
+		pki := &types.KeyInfo{Type: t, PrivateKey: pk}
+		addr, err := signer.ToAddress(pk)
+		if err != nil {
+			return keys, err
+		}
+
+		key := &GeneratedKey{Address: addr.String(), Type: string(t)}
+		if passphrase != "" {
+			if _, key.Keystore, err = encryptKey(pki, passphrase); err != nil {
+				return keys, err
+			}
+		} else {
+			val, err := json.Marshal(pki)
+			if err != nil {
+				return keys, err
+			}
+			key.Key = hex.EncodeToString(val)
+		}
+		if toWallet {
+			name := label
+			if label != "" && count > 1 {
+				name = fmt.Sprintf("%s-%d", label, i+1)
+			}
+			if _, err = w.Import(pki, name); err != nil {
+				return keys, err
+			}
+			key.InWallet = true
+		}
+		keys = append(keys, key)
+	}
+	process(1)
+	return keys, nil
+}
+
+// DerivedKey is a secp256k1 key derived from a mnemonic by DeriveKeys, the mnemonic followed by Path is accepted
+// wherever a private key is.
+type DerivedKey struct {
+	Path     string `json:"路径"`
+	Address  string `json:"地址"`
+	InWallet bool   `json:"已导入钱包"`
+}
+
+// GenerateMnemonic returns a new BIP39 phrase of words words to derive keys from.
+func (m *Handler) GenerateMnemonic(words int) (string, error) {
+	return GenerateMnemonic(words)
+}
+
+// GenerateMnemonic is Handler.GenerateMnemonic without a Handler.
+func GenerateMnemonic(words int) (string, error) {
+	return lib.NewMnemonic(words)
+}
+
+// DeriveKeys derives the keys start to start+count-1 of lib.FilecoinPath from a BIP39 phrase. With toWallet those
+// not in the unlocked wallet yet are imported, labeled label-index when label is set.
+func (m *Handler) DeriveKeys(mnemonic string, start, count int, toWallet bool, label string) ([]*DerivedKey, error) {
+	return DeriveKeys(m.wallet, mnemonic, start, count, toWallet, label, m.process)
+}
+
+// DeriveKeys is Handler.DeriveKeys without a Handler, importing into w. process may be nil.
+func DeriveKeys(w *lib.Wallet, mnemonic string, start, count int, toWallet bool, label string,
+	process func(float64) error) ([]*DerivedKey, error) {
+	if start < 0 || count < 1 || count > maxGeneratedKeys {
+		return nil, xerrors.Errorf("start must not be negative and count between 1 and %d", maxGeneratedKeys)
+	}
+	if toWallet && w == nil {
+		return nil, errNoWallet
+	} else if toWallet && w.Locked() {
+		return nil, xerrors.New("wallet is locked")
+	}
+	if process == nil {
+		process = func(float64) error { return nil }
+	}
+
+	signer := lib.ChooseSigner(types.KTSecp256k1)
+	keys := make([]*DerivedKey, 0, count)
+	for i := start; i < start+count; i++ {
+		process(float64(i-start) / float64(count))
+		path := lib.FilecoinPath(uint32(i))
+		pki, err := lib.MnemonicKey(mnemonic, path)
+		if err != nil {
+			return keys, err
+		}
+		addr, err := signer.ToAddress(pki.PrivateKey)
+		if err != nil {
+			return keys, err
+		}
+
+		key := &DerivedKey{Path: path, Address: addr.String()}
+		if toWallet && w.Has(addr) {
+			key.InWallet = true
+		} else if toWallet {
+			name := label
+			if label != "" {
+				name = fmt.Sprintf("%s-%d", label, i)
+			}
+			if _, err = w.Import(pki, name); err != nil {
+				return keys, err
+			}
+			key.InWallet = true
+		}
+		keys = append(keys, key)
+	}
+	process(1)
+	return keys, nil
+}
+
+// WriteKeysJSON writes keys made by GenerateKeys as an indented JSON array.
+func WriteKeysJSON(w io.Writer, keys []*GeneratedKey) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "\t")
+	return enc.Encode(keys)
+}
+
+// ExportKey returns the private key of a wallet address in the hex format the operations accept.
+func (m *Handler) ExportKey(addr string) (string, error) {
+	if m.wallet == nil {
+		return "", errNoWallet
+	}
+
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return "", err
+	}
+
+	pki, err := m.wallet.Key(a)
+	if err != nil {
+		return "", err
+	}
+
+	val, err := json.Marshal(pki)
+	if err != nil {
+		return "", err
+	} else {
+		return hex.EncodeToString(val), nil
+	}
+}
+
+func (m *Handler) DeleteKey(addr string) error {
+	if m.wallet == nil {
+		return errNoWallet
+	}
+
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return err
+	}
+	return m.wallet.Delete(a)
+}
+
+func (m *Handler) LabelKey(addr, label string) error {
+	if m.wallet == nil {
+		return errNoWallet
+	}
+
+	a, err := address.NewFromString(addr)
+	if err != nil {
+		return err
+	}
+	return m.wallet.SetLabel(a, label)
+}
+
+func parseKeyType(keyType string) (types.KeyType, error) {
+	t := types.KeyType(keyType)
+	if t != types.KTSecp256k1 && t != types.KTBLS {
+		return "", xerrors.Errorf("key type %s is not supported", keyType)
+	}
+	return t, nil
+}
@@ -0,0 +1,175 @@
+package common
+
+import (
+	"errors"
+	"fil-assistant/lib"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"path/filepath"
+	"testing"
+)
+
+func newTestWallet(t *testing.T) *lib.Wallet {
+	t.Helper()
+	w, err := lib.OpenWallet(filepath.Join(t.TempDir(), "wallet"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Unlock("pass"); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// TestGenerateKeys generates keys without a Handler, as keygen does on an offline machine.
+func TestGenerateKeys(t *testing.T) {
+	keys, err := GenerateKeys(nil, "bls", 2, "", false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(keys) != 2 || keys[0].Address == keys[1].Address {
+		t.Fatalf("got %+v, want 2 different keys", keys)
+	}
+	for _, key := range keys {
+		from, err := walletSender(nil, key.Key)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if from.addr.String() != key.Address || from.addr.Protocol() != address.BLS || key.InWallet {
+			t.Errorf("key of %s belongs to %s", key.Address, from.addr)
+		}
+	}
+
+	keys, err = GenerateKeys(nil, "secp256k1", 1, "pass", false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if keys[0].Key != "" {
+		t.Error("encrypted key also returned in clear")
+	}
+	if addr, _, err := new(Handler).Decrypt(keys[0].Keystore, "pass"); err != nil || addr != keys[0].Address {
+		t.Errorf("keystore of %s opened as %s: %v", keys[0].Address, addr, err)
+	}
+
+	w := newTestWallet(t)
+	keys, err = GenerateKeys(w, "secp256k1", 2, "", true, "cold", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := w.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	labels := make(map[string]string)
+	for _, entry := range entries {
+		labels[entry.Address] = entry.Label
+	}
+	for i, key := range keys {
+		if want := []string{"cold-1", "cold-2"}[i]; !key.InWallet || labels[key.Address] != want {
+			t.Errorf("key %s labeled %q in the wallet, want %s", key.Address, labels[key.Address], want)
+		}
+	}
+
+	for _, bad := range []struct {
+		keyType  string
+		count    int
+		toWallet bool
+	}{
+		{"ed25519", 1, false},
+		{"secp256k1", 0, false},
+		{"secp256k1", maxGeneratedKeys + 1, false},
+		{"secp256k1", 1, true},
+	} {
+		if _, err = GenerateKeys(nil, bad.keyType, bad.count, "", bad.toWallet, "", nil); err == nil {
+			t.Errorf("generated %d %s keys, into a wallet %t", bad.count, bad.keyType, bad.toWallet)
+		}
+	}
+	w.Lock()
+	if _, err = GenerateKeys(w, string(types.KTSecp256k1), 1, "", true, "", nil); err == nil {
+		t.Error("imported into a locked wallet")
+	}
+}
+
+func TestDeriveKeys(t *testing.T) {
+	mnemonic, err := GenerateMnemonic(12)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keys, err := DeriveKeys(nil, mnemonic, 3, 2, false, "", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, key := range keys {
+		from, err := walletSender(nil, mnemonic+" "+key.Path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if key.Path != lib.FilecoinPath(uint32(i+3)) || from.addr.String() != key.Address {
+			t.Errorf("key %d at %s is %s, its mnemonic opens %s", i, key.Path, key.Address, from.addr)
+		}
+	}
+
+	w := newTestWallet(t)
+	if _, err = DeriveKeys(w, mnemonic, 3, 1, true, "hd", nil); err != nil {
+		t.Fatal(err)
+	}
+	// keys already in the wallet are kept, the others imported
+	again, err := DeriveKeys(w, mnemonic, 3, 2, true, "hd", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries, err := w.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 2 || !again[0].InWallet || !again[1].InWallet {
+		t.Fatalf("%d keys in the wallet, want 2", len(entries))
+	}
+	labels := map[string]string{keys[0].Address: "hd-3", keys[1].Address: "hd-4"}
+	for _, entry := range entries {
+		if want := labels[entry.Address]; entry.Label != want {
+			t.Errorf("%s labeled %q, want %q", entry.Address, entry.Label, want)
+		}
+	}
+
+	if _, err = DeriveKeys(nil, mnemonic, -1, 1, false, "", nil); err == nil {
+		t.Error("derived from a negative index")
+	}
+	if _, err = DeriveKeys(nil, mnemonic, 0, 1, true, "", nil); err == nil {
+		t.Error("imported without a wallet")
+	}
+	if _, err = GenerateMnemonic(13); err == nil {
+		t.Error("generated a mnemonic of 13 words")
+	}
+}
+
+// TestLockedWalletSender prepares the messages of a wallet address while the wallet is locked, and signs them once
+// it is unlocked.
+func TestLockedWalletSender(t *testing.T) {
+	e := newTestEnv(t)
+	pk, from := e.newAccount(t, "10")
+	_, to := e.newAccount(t, "0")
+	w := newTestWallet(t)
+	e.h.SetWallet(w)
+	if _, err := e.h.ImportKey(pk, ""); err != nil {
+		t.Fatal(err)
+	}
+
+	e.h.LockWallet()
+	err := e.h.Send(testCtx, from.String(), to.String(), "1", nil)
+	var prepared *PreparedError
+	if !errors.As(err, &prepared) || prepared.Message.From != from {
+		t.Fatalf("got %v, want a message prepared from %s", err, from)
+	}
+	if _, err = SignMessage(w, from.String(), []byte("{}")); err == nil {
+		t.Error("signed with a locked wallet")
+	}
+
+	if err = e.h.UnlockWallet("pass"); err != nil {
+		t.Fatal(err)
+	}
+	if err = e.h.Send(testCtx, from.String(), to.String(), "1", nil); err != nil {
+		t.Fatal(err)
+	}
+	checkBalance(t, e, to, "1")
+}
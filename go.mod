@@ -12,9 +12,13 @@ require (
 	github.com/filecoin-project/lotus v1.11.0
 	github.com/filecoin-project/specs-actors/v6 v6.0.0-20210813162619-b5db2fd8407e
 	github.com/ipfs/go-cid v0.0.7
+	github.com/libp2p/go-libp2p-core v0.8.5
 	github.com/minio/blake2b-simd v0.0.0-20160723061019-3f5f724cb5b1
+	github.com/multiformats/go-multiaddr v0.3.1
 	github.com/subchen/go-trylock v1.3.0
 	github.com/supranational/blst v0.3.4
+	github.com/tyler-smith/go-bip39 v1.0.1-0.20181017060643-dbb3b84ba2ef
 	github.com/whyrusleeping/cbor-gen v0.0.0-20210303213153-67a261a1d291
+	golang.org/x/crypto v0.0.0-20210322153248-0c34fe9e7dc2
 	golang.org/x/xerrors v0.0.0-20200804184101-5ec99f83aff1
 )
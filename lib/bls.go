@@ -41,6 +41,26 @@ func (b *BlsSigner) Sign(pk, msg []byte) ([]byte, error) {
 	return new(blst.P2Affine).Sign(pri, msg, []byte(DST)).Compress(), nil
 }
 
+// Verify checks sig against the public key carried by the f3 address.
+func (b *BlsSigner) Verify(addr address.Address, msg, sig []byte) error {
+	if addr.Protocol() != address.BLS {
+		return xerrors.Errorf("%s is not a bls address", addr)
+	}
+
+	pub := new(blst.P1Affine).Uncompress(addr.Payload())
+	if pub == nil {
+		return xerrors.Errorf("bls signature invalid public key")
+	}
+	s := new(blst.P2Affine).Uncompress(sig)
+	if s == nil {
+		return xerrors.Errorf("bls signature invalid signature")
+	}
+	if !s.Verify(true, pub, true, msg, []byte(DST)) {
+		return xerrors.Errorf("bls signature verification failed")
+	}
+	return nil
+}
+
 func (b *BlsSigner) Type() crypto2.SigType {
 	return crypto2.SigTypeBLS
 }
\ No newline at end of file
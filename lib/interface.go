@@ -4,12 +4,14 @@ import (
 	"github.com/filecoin-project/go-address"
 	"github.com/filecoin-project/go-state-types/crypto"
 	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
 )
 
 type Signer interface {
 	GenPriKey() ([]byte, error)
 	ToAddress(pk []byte) (address.Address, error)
 	Sign(pk, msg []byte) ([]byte, error)
+	Verify(addr address.Address, msg, sig []byte) error
 	Type() crypto.SigType
 }
 
@@ -22,4 +24,16 @@ func ChooseSigner(t types.KeyType) Signer {
 	default:
 		panic("key type is not supported")
 	}
+}
+
+// SignerOf chooses the signer of the signature type, i.e. the first byte of a signature made by Handler.Sign.
+func SignerOf(t crypto.SigType) (Signer, error) {
+	switch t {
+	case crypto.SigTypeSecp256k1:
+		return new(Secp256Signer), nil
+	case crypto.SigTypeBLS:
+		return new(BlsSigner), nil
+	default:
+		return nil, xerrors.Errorf("signature type %d is not supported", t)
+	}
 }
\ No newline at end of file
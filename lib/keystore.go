@@ -0,0 +1,193 @@
+package lib
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/crypto/scrypt"
+	"golang.org/x/xerrors"
+)
+
+const (
+	KeystoreVersion = 1
+
+	kdfScrypt    = "scrypt"
+	cipherAESGCM = "aes-256-gcm"
+
+	scryptN      = 1 << 18
+	scryptR      = 8
+	scryptP      = 1
+	scryptKeyLen = 32
+)
+
+type ScryptParams struct {
+	N    int    `json:"n"`
+	R    int    `json:"r"`
+	P    int    `json:"p"`
+	Salt string `json:"salt"`
+}
+
+type KeystoreCrypto struct {
+	KDF        string       `json:"kdf"`
+	KDFParams  ScryptParams `json:"kdfparams"`
+	Cipher     string       `json:"cipher"`
+	Nonce      string       `json:"nonce"`
+	Ciphertext string       `json:"ciphertext"`
+}
+
+// Keystore is a private key encrypted with a key derived from a passphrase. Version, Address and Type stay in
+// clear and are authenticated together with the ciphertext, so a tampered keystore fails to decrypt.
+type Keystore struct {
+	Version int            `json:"version"`
+	Address string         `json:"address"`
+	Type    types.KeyType  `json:"type"`
+	Crypto  KeystoreCrypto `json:"crypto"`
+}
+
+func EncryptKey(ki *types.KeyInfo, passphrase string) (*Keystore, error) {
+	if passphrase == "" {
+		return nil, xerrors.New("passphrase is empty")
+	}
+
+	addr, err := ChooseSigner(ki.Type).ToAddress(ki.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	salt := make([]byte, 32)
+	if _, err = rand.Read(salt); err != nil {
+		return nil, xerrors.Errorf("keystore error generating salt: %w", err)
+	}
+
+	ks := &Keystore{
+		Version: KeystoreVersion,
+		Address: addr.String(),
+		Type:    ki.Type,
+		Crypto: KeystoreCrypto{
+			KDF: kdfScrypt,
+			KDFParams: ScryptParams{
+				N:    scryptN,
+				R:    scryptR,
+				P:    scryptP,
+				Salt: hex.EncodeToString(salt),
+			},
+			Cipher: cipherAESGCM,
+		},
+	}
+
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err = rand.Read(nonce); err != nil {
+		return nil, xerrors.Errorf("keystore error generating nonce: %w", err)
+	}
+
+	ad, err := ks.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	ks.Crypto.Nonce = hex.EncodeToString(nonce)
+	ks.Crypto.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, ki.PrivateKey, ad))
+	return ks, nil
+}
+
+func DecryptKey(ks *Keystore, passphrase string) (*types.KeyInfo, error) {
+	if ks.Version != KeystoreVersion {
+		return nil, xerrors.Errorf("unsupported keystore version %d", ks.Version)
+	}
+	if ks.Crypto.Cipher != cipherAESGCM {
+		return nil, xerrors.Errorf("unsupported keystore cipher %s", ks.Crypto.Cipher)
+	}
+
+	aead, err := ks.aead(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	nonce, err := hex.DecodeString(ks.Crypto.Nonce)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid keystore nonce: %w", err)
+	}
+	if len(nonce) != aead.NonceSize() {
+		return nil, xerrors.Errorf("invalid keystore nonce size %d", len(nonce))
+	}
+	ciphertext, err := hex.DecodeString(ks.Crypto.Ciphertext)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid keystore ciphertext: %w", err)
+	}
+
+	ad, err := ks.additionalData()
+	if err != nil {
+		return nil, err
+	}
+	pk, err := aead.Open(nil, nonce, ciphertext, ad)
+	if err != nil {
+		return nil, xerrors.New("wrong passphrase or corrupted keystore")
+	}
+
+	ki := &types.KeyInfo{
+		Type:       ks.Type,
+		PrivateKey: pk,
+	}
+	addr, err := ChooseSigner(ki.Type).ToAddress(ki.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+	if addr.String() != ks.Address {
+		return nil, xerrors.Errorf("keystore key belongs to %s instead of %s", addr, ks.Address)
+	}
+	return ki, nil
+}
+
+func ParseKeystore(val []byte) (*Keystore, error) {
+	ks := new(Keystore)
+	if err := json.Unmarshal(val, ks); err != nil {
+		return nil, xerrors.Errorf("invalid keystore: %w", err)
+	}
+	return ks, nil
+}
+
+func (ks *Keystore) aead(passphrase string) (cipher.AEAD, error) {
+	if ks.Crypto.KDF != kdfScrypt {
+		return nil, xerrors.Errorf("unsupported keystore kdf %s", ks.Crypto.KDF)
+	}
+
+	// the keystore sets the cost of scrypt, a crafted one could take all memory and CPU, so only the parameters
+	// EncryptKey writes are run
+	params := ks.Crypto.KDFParams
+	if params.N != scryptN || params.R != scryptR || params.P != scryptP {
+		return nil, xerrors.Errorf("unsupported keystore scrypt parameters n=%d r=%d p=%d", params.N, params.R, params.P)
+	}
+	salt, err := hex.DecodeString(params.Salt)
+	if err != nil {
+		return nil, xerrors.Errorf("invalid keystore salt: %w", err)
+	}
+
+	key, err := scrypt.Key([]byte(passphrase), salt, params.N, params.R, params.P, scryptKeyLen)
+	if err != nil {
+		return nil, xerrors.Errorf("keystore kdf error: %w", err)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+// additionalData binds the clear metadata to the ciphertext.
+func (ks *Keystore) additionalData() ([]byte, error) {
+	return json.Marshal(struct {
+		Version int
+		Address string
+		Type    types.KeyType
+		KDF     string
+		Params  ScryptParams
+	}{ks.Version, ks.Address, ks.Type, ks.Crypto.KDF, ks.Crypto.KDFParams})
+}
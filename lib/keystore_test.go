@@ -0,0 +1,144 @@
+package lib
+
+import (
+	"encoding/hex"
+	"github.com/filecoin-project/lotus/chain/types"
+	"strings"
+	"testing"
+)
+
+func newTestKey(t *testing.T) *types.KeyInfo {
+	t.Helper()
+	pk, err := new(Secp256Signer).GenPriKey()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return &types.KeyInfo{Type: types.KTSecp256k1, PrivateKey: pk}
+}
+
+func TestKeystoreRoundTrip(t *testing.T) {
+	ki := newTestKey(t)
+	ks, err := EncryptKey(ki, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := DecryptKey(ks, "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got.Type != ki.Type || hex.EncodeToString(got.PrivateKey) != hex.EncodeToString(ki.PrivateKey) {
+		t.Fatalf("decrypted %s %x, want %s %x", got.Type, got.PrivateKey, ki.Type, ki.PrivateKey)
+	}
+
+	if _, err = EncryptKey(ki, ""); err == nil {
+		t.Fatal("empty passphrase accepted")
+	}
+}
+
+func TestKeystoreWrongPassphrase(t *testing.T) {
+	ks, err := EncryptKey(newTestKey(t), "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = DecryptKey(ks, "other"); err == nil {
+		t.Fatal("wrong passphrase accepted")
+	}
+}
+
+func TestKeystoreTampered(t *testing.T) {
+	ks, err := EncryptKey(newTestKey(t), "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := new(Secp256Signer).ToAddress(newTestKey(t).PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for name, tamper := range map[string]func(ks *Keystore){
+		"ciphertext": func(ks *Keystore) {
+			ct, _ := hex.DecodeString(ks.Crypto.Ciphertext)
+			ct[0] ^= 1
+			ks.Crypto.Ciphertext = hex.EncodeToString(ct)
+		},
+		"address": func(ks *Keystore) { ks.Address = other.String() },
+		"type":    func(ks *Keystore) { ks.Type = types.KTBLS },
+		"salt":    func(ks *Keystore) { ks.Crypto.KDFParams.Salt = hex.EncodeToString(make([]byte, 32)) },
+	} {
+		tampered := *ks
+		tamper(&tampered)
+		if _, err = DecryptKey(&tampered, "pass"); err == nil {
+			t.Errorf("keystore with tampered %s accepted", name)
+		}
+	}
+}
+
+// TestKeystoreScryptParams checks that scrypt parameters other than the written ones are refused before deriving the
+// key, the costly ones would otherwise exhaust memory.
+func TestKeystoreScryptParams(t *testing.T) {
+	ks, err := EncryptKey(newTestKey(t), "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, params := range []ScryptParams{
+		{N: 1 << 30, R: scryptR, P: scryptP},
+		{N: scryptN, R: 1 << 20, P: scryptP},
+		{N: scryptN, R: scryptR, P: 1 << 20},
+		{N: 2, R: 1, P: 1},
+	} {
+		tampered := *ks
+		params.Salt = ks.Crypto.KDFParams.Salt
+		tampered.Crypto.KDFParams = params
+		if _, err = DecryptKey(&tampered, "pass"); err == nil || !strings.Contains(err.Error(), "scrypt parameters") {
+			t.Errorf("scrypt n=%d r=%d p=%d: %v", params.N, params.R, params.P, err)
+		}
+	}
+}
+
+// TestKeystoreAddressMismatch seals a key under the address of another one, the metadata authenticates but the
+// decrypted key must still be checked against it.
+func TestKeystoreAddressMismatch(t *testing.T) {
+	ks, err := EncryptKey(newTestKey(t), "pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	other, err := new(Secp256Signer).ToAddress(newTestKey(t).PrivateKey)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	aead, err := ks.aead("pass")
+	if err != nil {
+		t.Fatal(err)
+	}
+	nonce, _ := hex.DecodeString(ks.Crypto.Nonce)
+	pk, err := aead.Open(nil, nonce, mustHex(t, ks.Crypto.Ciphertext), mustAD(t, ks))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ks.Address = other.String()
+	ks.Crypto.Ciphertext = hex.EncodeToString(aead.Seal(nil, nonce, pk, mustAD(t, ks)))
+	if _, err = DecryptKey(ks, "pass"); err == nil {
+		t.Fatal("key of another address accepted")
+	}
+}
+
+func mustHex(t *testing.T, s string) []byte {
+	t.Helper()
+	val, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return val
+}
+
+func mustAD(t *testing.T, ks *Keystore) []byte {
+	t.Helper()
+	ad, err := ks.additionalData()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return ad
+}
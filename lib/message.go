@@ -0,0 +1,28 @@
+package lib
+
+import (
+	"github.com/filecoin-project/go-state-types/crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+)
+
+// SignMessage signs the cid of msg, it needs no connection to a node and can run on an offline machine.
+func SignMessage(signer Signer, pk []byte, msg *types.Message) (*types.SignedMessage, error) {
+	mb, err := msg.ToStorageBlock()
+	if err != nil {
+		return nil, xerrors.Errorf("SignMessage ToStorageBlock error: %w", err)
+	}
+
+	sig, err := signer.Sign(pk, mb.Cid().Bytes())
+	if err != nil {
+		return nil, xerrors.Errorf("SignMessage Sign error: %w", err)
+	}
+
+	return &types.SignedMessage{
+		Message:	*msg,
+		Signature: 	crypto.Signature{
+			Type: signer.Type(),
+			Data: sig,
+		},
+	}, nil
+}
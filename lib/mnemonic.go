@@ -0,0 +1,119 @@
+package lib
+
+import (
+	"crypto/hmac"
+	"crypto/sha512"
+	"encoding/binary"
+	"fmt"
+	"github.com/filecoin-project/go-crypto"
+	"github.com/filecoin-project/lotus/chain/types"
+	"github.com/tyler-smith/go-bip39"
+	"golang.org/x/xerrors"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const hardened = uint32(1) << 31
+
+// secp256k1N is the order of the secp256k1 curve, child keys are reduced modulo it.
+var secp256k1N, _ = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+
+// FilecoinPath is the BIP44 path of the index-th secp256k1 key of the first Filecoin account.
+func FilecoinPath(index uint32) string {
+	return fmt.Sprintf("m/44'/461'/0'/0/%d", index)
+}
+
+// NewMnemonic returns a BIP39 English phrase of words words, 12, 15, 18, 21 or 24.
+func NewMnemonic(words int) (string, error) {
+	if words < 12 || words > 24 || words%3 != 0 {
+		return "", xerrors.Errorf("mnemonic length %d is not one of 12, 15, 18, 21 or 24 words", words)
+	}
+	entropy, err := bip39.NewEntropy(words / 3 * 32)
+	if err != nil {
+		return "", err
+	}
+	return bip39.NewMnemonic(entropy)
+}
+
+// MnemonicKey derives the secp256k1 key at path, such as FilecoinPath(0), from a BIP39 phrase without passphrase.
+func MnemonicKey(mnemonic, path string) (*types.KeyInfo, error) {
+	mnemonic = strings.Join(strings.Fields(strings.ToLower(mnemonic)), " ")
+	if _, err := bip39.EntropyFromMnemonic(mnemonic); err != nil {
+		return nil, xerrors.Errorf("invalid mnemonic: %w", err)
+	}
+	indexes, err := parsePath(path)
+	if err != nil {
+		return nil, err
+	}
+
+	key, chainCode := hmacSHA512([]byte("Bitcoin seed"), bip39.NewSeed(mnemonic, ""))
+	for _, index := range indexes {
+		if key, chainCode, err = deriveChild(key, chainCode, index); err != nil {
+			return nil, err
+		}
+	}
+	return &types.KeyInfo{Type: types.KTSecp256k1, PrivateKey: key}, nil
+}
+
+// parsePath parses a BIP32 path like m/44'/461'/0'/0/0, ' or h marking hardened indexes.
+func parsePath(path string) ([]uint32, error) {
+	parts := strings.Split(strings.TrimSpace(path), "/")
+	if parts[0] != "m" {
+		return nil, xerrors.Errorf("derivation path %s does not start with m", path)
+	}
+	indexes := make([]uint32, 0, len(parts)-1)
+	for _, part := range parts[1:] {
+		offset := uint32(0)
+		if strings.HasSuffix(part, "'") || strings.HasSuffix(part, "h") {
+			offset = hardened
+			part = part[:len(part)-1]
+		}
+		index, err := strconv.ParseUint(part, 10, 31)
+		if err != nil {
+			return nil, xerrors.Errorf("invalid index %s in derivation path %s", part, path)
+		}
+		indexes = append(indexes, uint32(index)+offset)
+	}
+	return indexes, nil
+}
+
+// deriveChild is the BIP32 private parent key to private child key derivation.
+func deriveChild(key, chainCode []byte, index uint32) ([]byte, []byte, error) {
+	data := make([]byte, 0, 37)
+	if index >= hardened {
+		data = append(append(data, 0), key...)
+	} else {
+		data = append(data, compressPublicKey(crypto.PublicKey(key))...)
+	}
+	var serialized [4]byte
+	binary.BigEndian.PutUint32(serialized[:], index)
+	data = append(data, serialized[:]...)
+
+	il, childChainCode := hmacSHA512(chainCode, data)
+	tweak := new(big.Int).SetBytes(il)
+	if tweak.Cmp(secp256k1N) >= 0 {
+		return nil, nil, xerrors.Errorf("derived an invalid key at index %d", index)
+	}
+	child := tweak.Add(tweak, new(big.Int).SetBytes(key))
+	child.Mod(child, secp256k1N)
+	if child.Sign() == 0 {
+		return nil, nil, xerrors.Errorf("derived an invalid key at index %d", index)
+	}
+	return child.FillBytes(make([]byte, 32)), childChainCode, nil
+}
+
+// compressPublicKey turns a 65 bytes uncompressed public key into its 33 bytes compressed form.
+func compressPublicKey(pub []byte) []byte {
+	compressed := make([]byte, 33)
+	compressed[0] = 2 + pub[64]&1
+	copy(compressed[1:], pub[1:33])
+	return compressed
+}
+
+func hmacSHA512(key, data []byte) ([]byte, []byte) {
+	mac := hmac.New(sha512.New, key)
+	mac.Write(data)
+	sum := mac.Sum(nil)
+	return sum[:32], sum[32:]
+}
@@ -0,0 +1,78 @@
+package lib
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/go-crypto"
+	"testing"
+)
+
+// TestDeriveChild checks the private key and chain code of BIP32 test vector 1 at m/0'/1/2'/2/1000000000.
+func TestDeriveChild(t *testing.T) {
+	indexes, err := parsePath("m/0'/1/2h/2/1000000000")
+	if err != nil {
+		t.Fatal(err)
+	}
+	key, chainCode := hmacSHA512([]byte("Bitcoin seed"), mustHex(t, "000102030405060708090a0b0c0d0e0f"))
+	for _, index := range indexes {
+		if key, chainCode, err = deriveChild(key, chainCode, index); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := hex.EncodeToString(key); got != "471b76e389e528d6de6d816857e012c5455051cad6660850e58372a6c3e6e7c8" {
+		t.Errorf("key %s", got)
+	}
+	if got := hex.EncodeToString(chainCode); got != "c783e67b921d2beb8f6b389cc646d7263b4145701dadd2161548a8b078e65e9e" {
+		t.Errorf("chain code %s", got)
+	}
+}
+
+// TestMnemonicKey uses the mnemonic of the tests of Zondax's filecoin-signing-tools, whose key at m/44'/461'/0/0/0
+// they publish.
+func TestMnemonicKey(t *testing.T) {
+	mnemonic := "equip will roof matter pink blind book anxiety banner elbow sun young"
+	for _, m := range []string{mnemonic, " Equip will  ROOF matter pink blind book anxiety banner elbow sun young\n"} {
+		ki, err := MnemonicKey(m, "m/44'/461'/0/0/0")
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := base64.StdEncoding.EncodeToString(ki.PrivateKey); got != "8VcW07ADswS4BV2cxi5rnIadVsyTDDhY1NfDH19T8Uo=" {
+			t.Fatalf("key %s", got)
+		}
+		addr, err := address.NewSecp256k1Address(crypto.PublicKey(ki.PrivateKey))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if addr.String() != "f1d2xrzcslx7xlbbylc5c3d5lvandqw4iwl6epxba" {
+			t.Errorf("address %s", addr)
+		}
+	}
+
+	bad := "abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon abandon"
+	if _, err := MnemonicKey(bad, FilecoinPath(0)); err == nil {
+		t.Error("mnemonic with a bad checksum accepted")
+	}
+}
+
+func TestParsePath(t *testing.T) {
+	indexes, err := parsePath(FilecoinPath(3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []uint32{hardened + 44, hardened + 461, hardened, 0, 3}
+	if len(indexes) != len(want) {
+		t.Fatalf("got %v, want %v", indexes, want)
+	}
+	for i := range want {
+		if indexes[i] != want[i] {
+			t.Fatalf("got %v, want %v", indexes, want)
+		}
+	}
+
+	for _, path := range []string{"m/", "x/0", "m/2147483648", "m/2147483648'", "m/0''", "m/-1", ""} {
+		if _, err := parsePath(path); err == nil {
+			t.Errorf("path %q accepted", path)
+		}
+	}
+}
@@ -38,6 +38,27 @@ func (s *Secp256Signer) Sign(pk, msg []byte) ([]byte, error) {
 	}
 }
 
+// Verify recovers the public key from sig and checks that it hashes to the f1 address.
+func (s *Secp256Signer) Verify(addr address.Address, msg, sig []byte) error {
+	if addr.Protocol() != address.SECP256K1 {
+		return xerrors.Errorf("%s is not a secp256k1 address", addr)
+	}
+
+	b2sum := blake2b.Sum256(msg)
+	pubKey, err := crypto.EcRecover(b2sum[:], sig)
+	if err != nil {
+		return xerrors.Errorf("secp256k1 signature recovery error: %w", err)
+	}
+	maybeAddr, err := address.NewSecp256k1Address(pubKey)
+	if err != nil {
+		return err
+	}
+	if maybeAddr != addr {
+		return xerrors.Errorf("signature is made by %s instead of %s", maybeAddr, addr)
+	}
+	return nil
+}
+
 func (s *Secp256Signer) Type() crypto2.SigType {
 	return crypto2.SigTypeSecp256k1
 }
\ No newline at end of file
@@ -0,0 +1,258 @@
+package lib
+
+import (
+	"encoding/json"
+	"github.com/filecoin-project/go-address"
+	"github.com/filecoin-project/lotus/chain/types"
+	"golang.org/x/xerrors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+const labelsFile = "labels.json"
+
+type WalletEntry struct {
+	Address string
+	Type    types.KeyType
+	Label   string
+}
+
+// Wallet keeps one keystore file per address in a directory, together with a labels file. It is unlocked once per
+// session with a passphrase which protects every key it stores.
+type Wallet struct {
+	dir        string
+	passphrase string
+	keys       map[string]*types.KeyInfo
+	lk         sync.Mutex
+}
+
+func OpenWallet(dir string) (*Wallet, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, xerrors.Errorf("open wallet %s error: %w", dir, err)
+	}
+	return &Wallet{
+		dir:  dir,
+		keys: make(map[string]*types.KeyInfo),
+	}, nil
+}
+
+// Unlock checks passphrase against every stored key, so that the keys of a wallet all share one passphrase. An empty
+// wallet accepts any passphrase for its future keys.
+func (w *Wallet) Unlock(passphrase string) error {
+	if passphrase == "" {
+		return xerrors.New("passphrase is empty")
+	}
+
+	entries, err := w.List()
+	if err != nil {
+		return err
+	}
+
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	keys := make(map[string]*types.KeyInfo)
+	for _, entry := range entries {
+		ks, err := w.readKeystore(entry.Address)
+		if err != nil {
+			return err
+		}
+		ki, err := DecryptKey(ks, passphrase)
+		if err != nil {
+			return xerrors.Errorf("unlock %s error: %w", entry.Address, err)
+		}
+		keys[ks.Address] = ki
+	}
+
+	w.passphrase = passphrase
+	w.keys = keys
+	return nil
+}
+
+func (w *Wallet) Lock() {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	w.passphrase = ""
+	w.keys = make(map[string]*types.KeyInfo)
+}
+
+func (w *Wallet) Locked() bool {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	return w.passphrase == ""
+}
+
+// List works on a locked wallet too, addresses and key types are stored in clear.
+func (w *Wallet) List() ([]WalletEntry, error) {
+	files, err := filepath.Glob(filepath.Join(w.dir, "*.key"))
+	if err != nil {
+		return nil, err
+	}
+
+	labels, err := w.readLabels()
+	if err != nil {
+		return nil, err
+	}
+
+	entries := make([]WalletEntry, 0, len(files))
+	for _, file := range files {
+		ks, err := w.readKeystore(strings.TrimSuffix(filepath.Base(file), ".key"))
+		if err != nil {
+			return nil, err
+		}
+		entries = append(entries, WalletEntry{
+			Address: ks.Address,
+			Type:    ks.Type,
+			Label:   labels[ks.Address],
+		})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		return entries[i].Address < entries[j].Address
+	})
+	return entries, nil
+}
+
+func (w *Wallet) Has(addr address.Address) bool {
+	_, err := os.Stat(w.keyPath(addr.String()))
+	return err == nil
+}
+
+func (w *Wallet) Import(ki *types.KeyInfo, label string) (address.Address, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.passphrase == "" {
+		return address.Undef, xerrors.New("wallet is locked")
+	}
+
+	addr, err := ChooseSigner(ki.Type).ToAddress(ki.PrivateKey)
+	if err != nil {
+		return address.Undef, err
+	}
+	if w.Has(addr) {
+		return address.Undef, xerrors.Errorf("%s is already in the wallet", addr)
+	}
+	ks, err := EncryptKey(ki, w.passphrase)
+	if err != nil {
+		return address.Undef, err
+	}
+
+	val, err := json.Marshal(ks)
+	if err != nil {
+		return address.Undef, err
+	}
+	if err = ioutil.WriteFile(w.keyPath(ks.Address), val, 0600); err != nil {
+		return address.Undef, err
+	}
+	w.keys[ks.Address] = ki
+
+	if label != "" {
+		if err = w.setLabel(ks.Address, label); err != nil {
+			return address.Undef, err
+		}
+	}
+	return addr, nil
+}
+
+func (w *Wallet) Generate(t types.KeyType, label string) (address.Address, error) {
+	pk, err := ChooseSigner(t).GenPriKey()
+	if err != nil {
+		return address.Undef, err
+	}
+	return w.Import(&types.KeyInfo{Type: t, PrivateKey: pk}, label)
+}
+
+// Key returns the private key of addr, decrypting its keystore on first use.
+func (w *Wallet) Key(addr address.Address) (*types.KeyInfo, error) {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.passphrase == "" {
+		return nil, xerrors.New("wallet is locked")
+	}
+	if ki, found := w.keys[addr.String()]; found {
+		return ki, nil
+	}
+
+	ks, err := w.readKeystore(addr.String())
+	if err != nil {
+		return nil, err
+	}
+	ki, err := DecryptKey(ks, w.passphrase)
+	if err != nil {
+		return nil, err
+	}
+	w.keys[addr.String()] = ki
+	return ki, nil
+}
+
+func (w *Wallet) Delete(addr address.Address) error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if w.passphrase == "" {
+		return xerrors.New("wallet is locked")
+	}
+	if err := os.Remove(w.keyPath(addr.String())); err != nil {
+		return err
+	}
+	delete(w.keys, addr.String())
+	return w.setLabel(addr.String(), "")
+}
+
+func (w *Wallet) SetLabel(addr address.Address, label string) error {
+	w.lk.Lock()
+	defer w.lk.Unlock()
+
+	if !w.Has(addr) {
+		return xerrors.Errorf("%s is not in the wallet", addr)
+	}
+	return w.setLabel(addr.String(), label)
+}
+
+func (w *Wallet) setLabel(addr, label string) error {
+	labels, err := w.readLabels()
+	if err != nil {
+		return err
+	}
+	if label == "" {
+		delete(labels, addr)
+	} else {
+		labels[addr] = label
+	}
+
+	val, err := json.MarshalIndent(labels, "", "\t")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(filepath.Join(w.dir, labelsFile), val, 0600)
+}
+
+func (w *Wallet) readLabels() (map[string]string, error) {
+	labels := make(map[string]string)
+	val, err := ioutil.ReadFile(filepath.Join(w.dir, labelsFile))
+	if os.IsNotExist(err) {
+		return labels, nil
+	} else if err != nil {
+		return nil, err
+	}
+	return labels, json.Unmarshal(val, &labels)
+}
+
+func (w *Wallet) readKeystore(addr string) (*Keystore, error) {
+	val, err := ioutil.ReadFile(w.keyPath(addr))
+	if err != nil {
+		return nil, xerrors.Errorf("read keystore of %s error: %w", addr, err)
+	}
+	return ParseKeystore(val)
+}
+
+func (w *Wallet) keyPath(addr string) string {
+	return filepath.Join(w.dir, addr+".key")
+}
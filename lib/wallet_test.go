@@ -0,0 +1,80 @@
+package lib
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"strings"
+	"testing"
+)
+
+func newTestWallet(t *testing.T) *Wallet {
+	t.Helper()
+	w, err := OpenWallet(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Unlock("pass"); err != nil {
+		t.Fatal(err)
+	}
+	return w
+}
+
+// TestWalletUnlock checks the passphrase against every key, a key written under another passphrase keeps the
+// wallet locked.
+func TestWalletUnlock(t *testing.T) {
+	w := newTestWallet(t)
+	for i := 0; i < 2; i++ {
+		if _, err := w.Import(newTestKey(t), ""); err != nil {
+			t.Fatal(err)
+		}
+	}
+	w.Lock()
+	if err := w.Unlock("other"); err == nil || !w.Locked() {
+		t.Fatal("unlocked with a wrong passphrase")
+	}
+
+	ks, err := EncryptKey(newTestKey(t), "other")
+	if err != nil {
+		t.Fatal(err)
+	}
+	val, err := json.Marshal(ks)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = ioutil.WriteFile(w.keyPath(ks.Address), val, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Unlock("pass"); err == nil || !strings.Contains(err.Error(), ks.Address) || !w.Locked() {
+		t.Fatalf("unlocked a wallet with the key of %s under another passphrase: %v", ks.Address, err)
+	}
+}
+
+func TestWalletImport(t *testing.T) {
+	w := newTestWallet(t)
+	ki := newTestKey(t)
+	addr, err := w.Import(ki, "hot")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Import(ki, ""); err == nil || !strings.Contains(err.Error(), "already in the wallet") {
+		t.Errorf("imported %s twice: %v", addr, err)
+	}
+	entries, err := w.List()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Address != addr.String() || entries[0].Label != "hot" {
+		t.Errorf("entries %+v, want %s labeled hot", entries, addr)
+	}
+
+	w.Lock()
+	if _, err = w.Import(newTestKey(t), ""); err == nil {
+		t.Error("imported into a locked wallet")
+	}
+	if err = w.Unlock("pass"); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := w.Key(addr); err != nil || string(got.PrivateKey) != string(ki.PrivateKey) {
+		t.Errorf("key of %s: %v", addr, err)
+	}
+}
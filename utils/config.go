@@ -11,6 +11,9 @@ type Config struct {
 	MaxFee 		string
 	GasFeeCap	string
 	Confidence  uint64
+	WalletPath 	string
+	HistoryPath string
+	AddressBookPath string
 }
 
 func ReadConfig(path string) (*Config, error) {
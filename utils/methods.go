@@ -8,12 +8,16 @@ import (
 	cbg "github.com/whyrusleeping/cbor-gen"
 	"reflect"
 	"runtime"
+	"sort"
+	"strconv"
 	"strings"
 )
 
 type MethodMeta struct {
 	Name 	string
 	Params 	cbg.CBORUnmarshaler
+	// Return is nil when the method returns nothing.
+	Return 	cbg.CBORUnmarshaler
 }
 
 var MethodsMap = map[cid.Cid]map[abi.MethodNum]MethodMeta{}
@@ -37,11 +41,63 @@ func init() {
 
 			ev := reflect.ValueOf(export)
 			fnName := runtime.FuncForPC(ev.Pointer()).Name()
+			ret, _ := reflect.New(ev.Type().Out(0).Elem()).Interface().(cbg.CBORUnmarshaler)
+			if _, empty := ret.(*abi.EmptyValue); empty {
+				ret = nil
+			}
 			methods[abi.MethodNum(number)] = MethodMeta{
 				Name:   strings.TrimSuffix(fnName[strings.LastIndexByte(fnName, '.')+1:], "-fm"),
 				Params: reflect.New(ev.Type().In(1).Elem()).Interface().(cbg.CBORUnmarshaler),
+				Return: ret,
 			}
 		}
 		MethodsMap[actor.Code()] = methods
 	}
 }
+
+// LookupMethod returns the name of the method and a new value of its params type, which, unlike the Params of
+// MethodsMap, can be unmarshalled into without being shared.
+func LookupMethod(code cid.Cid, num abi.MethodNum) (string, cbg.CBORUnmarshaler, bool) {
+	meta, found := MethodsMap[code][num]
+	if !found {
+		return "", nil, false
+	}
+	return meta.Name, reflect.New(reflect.TypeOf(meta.Params).Elem()).Interface().(cbg.CBORUnmarshaler), true
+}
+
+// Methods returns the numbers of the methods of the actor code in ascending order.
+func Methods(code cid.Cid) []abi.MethodNum {
+	nums := make([]abi.MethodNum, 0, len(MethodsMap[code]))
+	for num := range MethodsMap[code] {
+		nums = append(nums, num)
+	}
+	sort.Slice(nums, func(i, j int) bool {
+		return nums[i] < nums[j]
+	})
+	return nums
+}
+
+// LookupMethodByName is LookupMethod by the name of the method, or by its number in decimal.
+func LookupMethodByName(code cid.Cid, name string) (abi.MethodNum, cbg.CBORUnmarshaler, bool) {
+	if num, err := strconv.ParseUint(name, 10, 64); err == nil {
+		_, params, found := LookupMethod(code, abi.MethodNum(num))
+		return abi.MethodNum(num), params, found
+	}
+	for num, meta := range MethodsMap[code] {
+		if meta.Name == name {
+			_, params, _ := LookupMethod(code, num)
+			return num, params, true
+		}
+	}
+	return 0, nil, false
+}
+
+// LookupReturn returns a new value of the return type of the method, false when the method is unknown or returns
+// nothing.
+func LookupReturn(code cid.Cid, num abi.MethodNum) (cbg.CBORUnmarshaler, bool) {
+	meta, found := MethodsMap[code][num]
+	if !found || meta.Return == nil {
+		return nil, false
+	}
+	return reflect.New(reflect.TypeOf(meta.Return).Elem()).Interface().(cbg.CBORUnmarshaler), true
+}
\ No newline at end of file